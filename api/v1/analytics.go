@@ -0,0 +1,17 @@
+package v1
+
+// AnalyticsPoint 每日统计数据点
+type AnalyticsPoint struct {
+	Date           string `json:"date"`            // 日期，格式 2006-01-02
+	Views          int64  `json:"views"`           // 浏览量
+	Downloads      int64  `json:"downloads"`       // 下载量
+	UniqueVisitors int64  `json:"unique_visitors"` // 独立访客数(HyperLogLog 估计值)
+}
+
+// AnalyticsSeriesResponse 每日统计时间序列响应
+type AnalyticsSeriesResponse struct {
+	BookId uint              `json:"book_id"` // 图书ID，0 表示全站
+	From   string            `json:"from"`    // 起始日期(含)
+	To     string            `json:"to"`      // 结束日期(含)
+	Points []*AnalyticsPoint `json:"points"`  // 按日期升序的数据点，无数据的日期补零
+}
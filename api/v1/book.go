@@ -55,21 +55,27 @@ type GetBookResponse struct {
 	Tag         string    `json:"tag"`           // 标签
 	HotValue    int64     `json:"hot_value"`     // 热度值
 	CreatedAt   time.Time `json:"created_at"`    // 创建时间
+	UpdatedAt   time.Time `json:"updated_at"`    // 更新时间
 	Downloads   int64     `json:"downloads"`     // 下载量
+	RatingScore float64   `json:"rating_score"`  // 贝叶斯加权评分
+	RatingCount int64     `json:"rating_count"`  // 评分数
+	Version     uint      `json:"version"`       // 版本号，与 ETag 中的版本一致
 }
 
 // BookItem 图书列表项
 type BookItem struct {
-	Id        uint      `json:"id"`         // 图书ID
-	Title     string    `json:"title"`      // 书名
-	Author    string    `json:"author"`     // 作者
-	Cover     string    `json:"cover"`      // 封面图片URL
-	Intro     string    `json:"intro"`      // 简介
-	Sort      string    `json:"sort"`       // 分类
-	Type      string    `json:"type"`       // 类型
-	Tag       string    `json:"tag"`        // 标签
-	HotValue  int64     `json:"hot_value"`  // 热度值
-	CreatedAt time.Time `json:"created_at"` // 创建时间
+	Id          uint      `json:"id"`           // 图书ID
+	Title       string    `json:"title"`        // 书名
+	Author      string    `json:"author"`       // 作者
+	Cover       string    `json:"cover"`        // 封面图片URL
+	Intro       string    `json:"intro"`        // 简介
+	Sort        string    `json:"sort"`         // 分类
+	Type        string    `json:"type"`         // 类型
+	Tag         string    `json:"tag"`          // 标签
+	HotValue    int64     `json:"hot_value"`    // 热度值
+	RatingScore float64   `json:"rating_score"` // 贝叶斯加权评分
+	RatingCount int64     `json:"rating_count"` // 评分数
+	CreatedAt   time.Time `json:"created_at"`   // 创建时间
 }
 
 type ListBooksRequest struct {
@@ -77,7 +83,7 @@ type ListBooksRequest struct {
 	Author   string `json:"author,omitempty"` // 作者，可选，支持模糊查询
 	Tag      string `json:"tag,omitempty"`    // 标签，可选，支持模糊查询
 	Sort     string `json:"sort,omitempty"`   // 分类，可选，支持模糊查询
-	Type     string `json:"type,omitempty"`   // 类型，可选，支持模糊查询
+	Type     string `json:"type,omitempty"`   // 排序方式，可选：latest 最新，hotest 最热，rating 评分最高
 	Page     int    `json:"page"`             // 页码
 	PageSize int    `json:"page_size"`        // 每页数量
 }
@@ -92,6 +98,11 @@ type GetAllSortsResponse struct {
 	Sorts []string `json:"sorts"`
 }
 
+// DownloadBookResponse 下载图书响应
+type DownloadBookResponse struct {
+	FileURL string `json:"file_url"` // 文件URL
+}
+
 // QuickSearchRequest 快速搜索请求
 type QuickSearchRequest struct {
 	Keyword string `json:"keyword" binding:"required"` // 搜索关键字(可匹配书名、作者、标签)
@@ -3,10 +3,20 @@ package v1
 import "time"
 
 type CreateBookRatingRequest struct {
-	BookId       uint   `json:"book_id" binding:"required"`
-	RatingTypeId uint   `json:"rating_type_id" binding:"required"`
-	Comment      string `json:"comment"`
-	IP           string `json:"ip"`
+	BookId       uint      `json:"book_id" binding:"required"`
+	RatingTypeId uint      `json:"rating_type_id" binding:"required"`
+	Comment      string    `json:"comment"`
+	IP           string    `json:"ip"`
+	UserId       string    `json:"-"` // 登录用户ID，由 handler 填充
+	VisitorId    string    `json:"-"` // 匿名访客ID，由 handler 填充
+	VisitorSince time.Time `json:"-"` // 访客 Cookie 的签发时间，由 handler 填充
+}
+
+// CreateBookRatingResponse 创建书籍评分响应
+type CreateBookRatingResponse struct {
+	Id      uint   `json:"id"`      // 评分ID
+	Updated bool   `json:"updated"` // 是否为更新已有评分
+	Status  string `json:"status"`  // 评论审核状态，pending 表示评论需审核后才会公开展示
 }
 
 type UpdateBookRatingRequest struct {
@@ -20,20 +30,50 @@ type BookRatingResponse struct {
 	RatingTypeId uint      `json:"rating_type_id"`
 	Comment      string    `json:"comment"`
 	IP           string    `json:"ip"`
+	Status       string    `json:"status"` // 审核状态(pending/approved/rejected)
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-type ListBookRatingsResponse struct {
-	Total int64                 `json:"total"`
-	Items []*BookRatingResponse `json:"items"`
+// 评论列表排序方式
+const (
+	ReviewOrderNewest = "newest" // 最新
+	ReviewOrderLiked  = "liked"  // 点赞最多
+)
+
+// ListBookReviewsRequest 书籍评论列表请求
+type ListBookReviewsRequest struct {
+	Order        string `form:"order"`          // 排序方式(newest/liked)，默认 newest
+	RatingTypeId uint   `form:"rating_type_id"` // 评分类型ID，可选
+	Cursor       string `form:"cursor"`         // 上一页返回的游标，首页留空
+	Limit        int    `form:"limit"`          // 每页数量，默认 20，最大 50
+}
+
+// BookReviewItem 公开的书籍评论
+type BookReviewItem struct {
+	Id           uint      `json:"id"`
+	BookId       uint      `json:"book_id"`
+	RatingTypeId uint      `json:"rating_type_id"`
+	Comment      string    `json:"comment"`
+	IP           string    `json:"ip"` // 打码后的IP，如 192.168.*.*
+	LikeCount    int64     `json:"like_count"`
+	ReplyCount   int64     `json:"reply_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ListBookReviewsResponse 书籍评论列表响应
+type ListBookReviewsResponse struct {
+	Items      []*BookReviewItem `json:"items"`
+	NextCursor string            `json:"next_cursor"` // 下一页游标，为空表示没有更多数据
 }
 
 // BookRatingStats 书籍评分统计
 type BookRatingStats struct {
-	BookId       uint                   `json:"book_id"`       // 书籍ID
-	TotalRatings int64                  `json:"total_ratings"` // 总评分数
-	RatingTypes  []*RatingTypeWithCount `json:"rating_types"`  // 各类型评分统计
+	BookId        uint                   `json:"book_id"`        // 书籍ID
+	TotalRatings  int64                  `json:"total_ratings"`  // 总评分数
+	AverageLevel  float64                `json:"average_level"`  // 按评分等级加权的平均等级
+	BayesianScore float64                `json:"bayesian_score"` // 以先验平均等级修正后的贝叶斯评分，无评分时为 0
+	RatingTypes   []*RatingTypeWithCount `json:"rating_types"`   // 各类型评分统计
 }
 
 // RatingTypeWithCount 评分类型及数量
@@ -50,3 +90,33 @@ type RatingTypeWithCount struct {
 type GetBookRatingResponse struct {
 	Stats *BookRatingStats `json:"stats"`
 }
+
+// GetRatingTrendRequest 评分趋势查询参数
+type GetRatingTrendRequest struct {
+	Interval string `form:"interval" binding:"omitempty,oneof=day week month"` // 分桶粒度，默认 day
+	From     string `form:"from"`                                              // 起始日期(含)，格式 2006-01-02
+	To       string `form:"to"`                                                // 结束日期(含)，默认今天
+}
+
+// RatingTrendPoint 一个时间桶内的评分数
+type RatingTrendPoint struct {
+	Start  string             `json:"start"`  // 桶的起始日期，周从周一开始，月从 1 日开始
+	Total  int64              `json:"total"`  // 桶内评分总数
+	Counts []*RatingTypeCount `json:"counts"` // 各评分类型的评分数，顺序与 rating_types 一致
+}
+
+// RatingTypeCount 评分类型的评分数
+type RatingTypeCount struct {
+	RatingTypeId uint  `json:"rating_type_id"`
+	Count        int64 `json:"count"`
+}
+
+// GetRatingTrendResponse 评分趋势响应
+type GetRatingTrendResponse struct {
+	BookId      uint                  `json:"book_id"`
+	Interval    string                `json:"interval"`
+	From        string                `json:"from"`         // 第一个桶的起始日期
+	To          string                `json:"to"`           // 结束日期(含)
+	RatingTypes []*RatingTypeResponse `json:"rating_types"` // 参与统计的评分类型
+	Points      []*RatingTrendPoint   `json:"points"`       // 按时间升序的数据点，无评分的桶补零
+}
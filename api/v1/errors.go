@@ -12,5 +12,56 @@ var (
 	ErrEmailAlreadyUse = newError(1001, "The email is already in use.")
 
 	// user errors
-	ErrForbidden = newError(1002, "Forbidden")
+	ErrForbidden          = newError(1002, "Forbidden")
+	ErrUsernameAlreadyUse = newError(1003, "The username is already in use.")
+	ErrInvalidCredentials = newError(1004, "Incorrect username/email or password.")
+	ErrInvalidUsername    = newError(1005, "The username may only contain letters, digits and underscores.")
+	ErrLoginLocked        = newError(1006, "Too many failed login attempts, please try again later.")
+	ErrUserBanned         = newError(1007, "Your account has been banned.")
+	ErrCannotBanUser      = newError(1008, "This user cannot be banned.")
+	ErrUserNotBanned      = newError(1009, "The user is not banned.")
+
+	// token errors
+	ErrInvalidRefreshToken = newError(1101, "The refresh token is invalid or expired, please log in again.")
+
+	// email verification and password reset errors
+	ErrInvalidUserToken     = newError(1201, "The link is invalid or has expired.")
+	ErrMailTooFrequent      = newError(1202, "Emails are sent too frequently, please try again later.")
+	ErrEmailAlreadyVerified = newError(1203, "The email has already been verified.")
+
+	// two-factor authentication errors
+	ErrInvalidTwoFactorCode    = newError(1301, "The verification code is incorrect.")
+	ErrTwoFactorNotSetup       = newError(1302, "Two-factor authentication has not been set up.")
+	ErrTwoFactorAlreadyEnabled = newError(1303, "Two-factor authentication is already enabled.")
+	ErrTwoFactorRequired       = newError(1304, "Two-factor authentication is required for your role and cannot be disabled.")
+	ErrInvalidLoginChallenge   = newError(1305, "The login session has expired, please log in again.")
+
+	// role and permission errors
+	ErrRoleExists              = newError(1401, "A role with this name or code already exists.")
+	ErrRoleInUse               = newError(1402, "The role is still assigned to users.")
+	ErrBuiltinRole             = newError(1403, "The administrator role cannot be deleted.")
+	ErrPermissionExists        = newError(1404, "A permission with this code already exists.")
+	ErrPermissionHasChildren   = newError(1405, "The permission has child permissions, delete them first.")
+	ErrInvalidPermissionParent = newError(1406, "The parent permission does not exist or cannot have children.")
+	ErrPermissionNotHeld       = newError(1407, "You can only grant roles and permissions you hold yourself.")
+	ErrAdminRoleRequired       = newError(1408, "Only administrators can manage the administrator role.")
+	ErrLastAdmin               = newError(1409, "At least one administrator must remain.")
+
+	// book errors
+	ErrPreconditionRequired = newError(2001, "If-Match header is required")
+	ErrBookVersionConflict  = newError(2002, "The book has been modified by someone else, please reload and retry.")
+
+	// rating errors
+	ErrRatingLimitExceeded = newError(3001, "Too many ratings for this book from your network.")
+	ErrCommentRejected     = newError(3002, "The comment contains prohibited content.")
+	ErrRatingDuplicate     = newError(3003, "You have already rated this book.")
+	ErrLikeLimitExceeded   = newError(3004, "Too many likes from your network, please try again later.")
+
+	// rating type errors
+	ErrRatingLevelExists           = newError(3101, "A rating type with this level already exists.")
+	ErrRatingTypeMigrationRequired = newError(3102, "The rating type is in use, specify migrate_to to move existing ratings.")
+
+	// report errors
+	ErrReportDuplicate     = newError(4001, "You have already reported this.")
+	ErrReportLimitExceeded = newError(4002, "Too many reports from your network, please try again later.")
 )
@@ -0,0 +1,44 @@
+package v1
+
+import "time"
+
+// ListReviewQueueRequest 评论审核队列请求
+type ListReviewQueueRequest struct {
+	Status   string `form:"status"`    // 审核状态(pending/approved/rejected)，默认 pending
+	Page     int    `form:"page"`      // 页码，默认 1
+	PageSize int    `form:"page_size"` // 每页数量，默认 20，最大 100
+}
+
+type ListReviewQueueResponse struct {
+	Total int64                 `json:"total"`
+	Items []*BookRatingResponse `json:"items"`
+}
+
+// ModerateReviewsRequest 批量审核评论请求
+type ModerateReviewsRequest struct {
+	Ids    []uint `json:"ids" binding:"required,min=1,max=100"`              // 评分ID列表
+	Status string `json:"status" binding:"required,oneof=approved rejected"` // 审核结果
+}
+
+type ModerateReviewsResponse struct {
+	Updated int64 `json:"updated"` // 实际修改的条数
+}
+
+// AddSensitiveWordsRequest 批量添加敏感词请求，已存在的词会更新处理方式
+type AddSensitiveWordsRequest struct {
+	Words  []string `json:"words" binding:"required,min=1,max=1000"`
+	Action string   `json:"action" binding:"required,oneof=mask review reject"` // 命中后的处理方式：mask 打码，review 人工审核，reject 拒绝
+}
+
+type SensitiveWordResponse struct {
+	Id        uint      `json:"id"`
+	Word      string    `json:"word"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ListSensitiveWordsResponse struct {
+	Total int64                    `json:"total"`
+	Items []*SensitiveWordResponse `json:"items"`
+}
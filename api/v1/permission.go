@@ -0,0 +1,49 @@
+package v1
+
+import "time"
+
+type CreatePermissionRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Code        string `json:"code" binding:"required,max=50"`
+	Type        string `json:"type" binding:"required,oneof=menu button api"`
+	ParentId    uint   `json:"parent_id"` // 0 表示顶级
+	Path        string `json:"path" binding:"max=200"`
+	Description string `json:"description" binding:"max=200"`
+}
+
+// UpdatePermissionRequest 更新权限，权限编码创建后不可修改
+type UpdatePermissionRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Type        string `json:"type" binding:"required,oneof=menu button api"`
+	ParentId    uint   `json:"parent_id"`
+	Path        string `json:"path" binding:"max=200"`
+	Description string `json:"description" binding:"max=200"`
+}
+
+type CreatePermissionResponse struct {
+	Id uint `json:"id"`
+}
+
+type PermissionResponse struct {
+	Id          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Code        string    `json:"code"`
+	Type        string    `json:"type"`
+	ParentId    uint      `json:"parent_id"`
+	Path        string    `json:"path"`
+	Method      string    `json:"method,omitempty"`
+	Stale       bool      `json:"stale"` // 自动注册的接口权限对应的路由已不存在
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PermissionNode 权限树的节点
+type PermissionNode struct {
+	*PermissionResponse
+	Children []*PermissionNode `json:"children"`
+}
+
+type PermissionTreeResponse struct {
+	Items []*PermissionNode `json:"items"`
+}
@@ -0,0 +1,37 @@
+package v1
+
+import "time"
+
+// 榜单类型
+const (
+	RankingBoardHot       = "hot"       // 热度榜
+	RankingBoardDownloads = "downloads" // 下载榜
+	RankingBoardRating    = "rating"    // 好评榜
+	RankingBoardNewest    = "newest"    // 新书榜
+)
+
+// 榜单周期
+const (
+	RankingPeriodDaily   = "daily"   // 日榜
+	RankingPeriodWeekly  = "weekly"  // 周榜
+	RankingPeriodMonthly = "monthly" // 月榜
+	RankingPeriodAll     = "all"     // 总榜
+)
+
+// RankingItem 榜单条目
+type RankingItem struct {
+	Rank      int       `json:"rank"`       // 名次
+	Id        uint      `json:"id"`         // 图书ID
+	Title     string    `json:"title"`      // 书名
+	Author    string    `json:"author"`     // 作者
+	Cover     string    `json:"cover"`      // 封面图片URL
+	Score     float64   `json:"score"`      // 榜单分值(浏览量/下载量/平均评分等级)
+	CreatedAt time.Time `json:"created_at"` // 创建时间
+}
+
+// GetRankingResponse 获取榜单响应
+type GetRankingResponse struct {
+	Board  string         `json:"board"`  // 榜单类型
+	Period string         `json:"period"` // 榜单周期
+	Items  []*RankingItem `json:"items"`  // 榜单列表
+}
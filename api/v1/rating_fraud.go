@@ -0,0 +1,41 @@
+package v1
+
+import "time"
+
+// ListRatingFlagsRequest 可疑评分列表请求
+type ListRatingFlagsRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending cleared removed"` // 复核状态，默认 pending
+	Page     int    `form:"page"`                                                     // 页码，默认 1
+	PageSize int    `form:"page_size"`                                                // 每页数量，默认 20，最大 100
+}
+
+// RatingFlagItem 可疑评分
+type RatingFlagItem struct {
+	Id              uint      `json:"id"`
+	RatingId        uint      `json:"rating_id"`
+	BookId          uint      `json:"book_id"`
+	RatingTypeId    uint      `json:"rating_type_id"`
+	Comment         string    `json:"comment"`
+	IP              string    `json:"ip"`
+	UserId          string    `json:"user_id"`
+	VisitorId       string    `json:"visitor_id"`
+	Reasons         []string  `json:"reasons"` // 命中的规则(subnet/duplicate_comment/burst/new_visitor)
+	Status          string    `json:"status"`
+	Operator        string    `json:"operator"`
+	RatingCreatedAt time.Time `json:"rating_created_at"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type ListRatingFlagsResponse struct {
+	Total int64             `json:"total"`
+	Items []*RatingFlagItem `json:"items"`
+}
+
+// ReviewRatingFlagsRequest 批量复核可疑评分请求，只处理待复核的记录
+type ReviewRatingFlagsRequest struct {
+	Ids []uint `json:"ids" binding:"required,min=1,max=500"` // 可疑评分ID列表
+}
+
+type ReviewRatingFlagsResponse struct {
+	Updated int64 `json:"updated"` // 实际处理的条数
+}
@@ -3,24 +3,40 @@ package v1
 import "time"
 
 type CreateRatingTypeRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Level       int    `json:"level" binding:"required"`
+	Name         string `json:"name" binding:"required,max=32"`
+	Description  string `json:"description" binding:"max=255"`
+	Level        int    `json:"level" binding:"required,min=1"`
+	DisplayOrder int    `json:"display_order"`
 }
 
 type UpdateRatingTypeRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Level       int    `json:"level"`
+	Name         string `json:"name" binding:"required,max=32"`
+	Description  string `json:"description" binding:"max=255"`
+	Level        int    `json:"level" binding:"required,min=1"`
+	DisplayOrder int    `json:"display_order"`
+}
+
+// DeleteRatingTypeRequest 删除评分类型，已有评分时必须指定迁移到的评分类型
+type DeleteRatingTypeRequest struct {
+	MigrateTo uint `form:"migrate_to"`
+}
+
+type CreateRatingTypeResponse struct {
+	Id uint `json:"id"`
+}
+
+type DeleteRatingTypeResponse struct {
+	Migrated int64 `json:"migrated"` // 迁移的评分数
 }
 
 type RatingTypeResponse struct {
-	Id          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Level       int       `json:"level"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Id           uint      `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Level        int       `json:"level"`
+	DisplayOrder int       `json:"display_order"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 type ListRatingTypesResponse struct {
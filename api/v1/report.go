@@ -0,0 +1,75 @@
+package v1
+
+import "time"
+
+// CreateReportRequest 举报请求
+type CreateReportRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=book review chapter"`                       // 举报对象类型
+	TargetId   uint   `json:"target_id" binding:"required"`                                                   // 举报对象ID
+	Reason     string `json:"reason" binding:"required,oneof=spam abuse broken_file copyright illegal other"` // 举报原因：spam 垃圾广告，abuse 辱骂攻击，broken_file 文件损坏，copyright 侵权，illegal 违法违规，other 其他
+	Detail     string `json:"detail" binding:"max=1000"`                                                      // 补充说明
+	IP         string `json:"-"`                                                                              // 由 handler 填充
+	UserId     string `json:"-"`                                                                              // 登录用户ID，由 handler 填充
+	VisitorId  string `json:"-"`                                                                              // 匿名访客ID，由 handler 填充
+}
+
+type CreateReportResponse struct {
+	Id uint `json:"id"` // 举报ID
+}
+
+// ListReportTargetsRequest 举报处理列表请求
+type ListReportTargetsRequest struct {
+	Status     string `form:"status"`      // 举报状态(open/resolved/dismissed)，默认 open
+	TargetType string `form:"target_type"` // 举报对象类型，可选
+	Page       int    `form:"page"`        // 页码，默认 1
+	PageSize   int    `form:"page_size"`   // 每页数量，默认 20，最大 100
+}
+
+// ReportTargetItem 按举报对象汇总的举报
+type ReportTargetItem struct {
+	TargetType string    `json:"target_type"`
+	TargetId   uint      `json:"target_id"`
+	Reports    int64     `json:"reports"`   // 举报数
+	LatestAt   time.Time `json:"latest_at"` // 最近一次举报时间
+}
+
+type ListReportTargetsResponse struct {
+	Total int64               `json:"total"`
+	Items []*ReportTargetItem `json:"items"`
+}
+
+type ReportItem struct {
+	Id        uint      `json:"id"`
+	Reporter  string    `json:"reporter"`
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail"`
+	IP        string    `json:"ip"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReportAuditItem struct {
+	Id        uint      `json:"id"`
+	Action    string    `json:"action"`   // auto_hide 自动隐藏，remove 下架，dismiss 驳回
+	Operator  string    `json:"operator"` // 操作的管理员ID，系统自动处理时为空
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetReportTargetResponse 某个举报对象的全部举报和处理记录
+type GetReportTargetResponse struct {
+	TargetType string             `json:"target_type"`
+	TargetId   uint               `json:"target_id"`
+	Reports    []*ReportItem      `json:"reports"`
+	Audits     []*ReportAuditItem `json:"audits"`
+}
+
+// ResolveReportsRequest 处理某个对象的全部待处理举报
+type ResolveReportsRequest struct {
+	Action string `json:"action" binding:"required,oneof=remove dismiss"` // remove 举报成立并下架对象，dismiss 驳回举报并恢复对象
+	Note   string `json:"note" binding:"max=1000"`                        // 处理说明
+}
+
+type ResolveReportsResponse struct {
+	Resolved int64 `json:"resolved"` // 处理的举报数
+}
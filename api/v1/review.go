@@ -0,0 +1,62 @@
+package v1
+
+import "time"
+
+// CreateReviewReplyRequest 回复评论请求
+type CreateReviewReplyRequest struct {
+	ParentId  uint   `json:"parent_id"`                           // 被回复的回复ID，直接回复评论时为 0
+	Comment   string `json:"comment" binding:"required,max=1000"` // 回复内容
+	IP        string `json:"-"`                                   // 由 handler 填充
+	UserId    string `json:"-"`                                   // 登录用户ID，由 handler 填充
+	VisitorId string `json:"-"`                                   // 匿名访客ID，由 handler 填充
+}
+
+type CreateReviewReplyResponse struct {
+	Id     uint   `json:"id"`     // 回复ID
+	Status string `json:"status"` // 审核状态，pending 表示需审核后才会公开展示
+}
+
+// ReviewLikeRequest 点赞/取消点赞请求，字段均由 handler 填充
+type ReviewLikeRequest struct {
+	IP        string // 点赞者IP
+	UserId    string // 登录用户ID
+	VisitorId string // 匿名访客ID
+}
+
+// ReviewLikeResponse 点赞/取消点赞响应
+type ReviewLikeResponse struct {
+	Liked     bool  `json:"liked"`      // 当前是否已点赞
+	LikeCount int64 `json:"like_count"` // 评论的点赞数
+}
+
+// ReviewReplyItem 公开的评论回复，二级回复放在所属一级回复的 replies 中
+type ReviewReplyItem struct {
+	Id        uint               `json:"id"`
+	ParentId  uint               `json:"parent_id"`
+	Comment   string             `json:"comment"`
+	IP        string             `json:"ip"` // 打码后的IP
+	CreatedAt time.Time          `json:"created_at"`
+	Replies   []*ReviewReplyItem `json:"replies,omitempty"`
+}
+
+// GetReviewThreadResponse 评论及其回复
+type GetReviewThreadResponse struct {
+	Review  *BookReviewItem    `json:"review"`
+	Replies []*ReviewReplyItem `json:"replies"`
+}
+
+// ReviewReplyResponse 审核队列中的回复
+type ReviewReplyResponse struct {
+	Id        uint      `json:"id"`
+	RatingId  uint      `json:"rating_id"`
+	ParentId  uint      `json:"parent_id"`
+	Comment   string    `json:"comment"`
+	IP        string    `json:"ip"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListReplyQueueResponse struct {
+	Total int64                  `json:"total"`
+	Items []*ReviewReplyResponse `json:"items"`
+}
@@ -0,0 +1,62 @@
+package v1
+
+import "time"
+
+type CreateRoleRequest struct {
+	Name          string `json:"name" binding:"required,max=50"`
+	Code          string `json:"code" binding:"required,max=50"`
+	Description   string `json:"description" binding:"max=200"`
+	PermissionIds []uint `json:"permission_ids"` // 创建时同时授予的权限
+}
+
+// UpdateRoleRequest 更新角色，角色编码创建后不可修改
+type UpdateRoleRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description" binding:"max=200"`
+}
+
+type CreateRoleResponse struct {
+	Id uint `json:"id"`
+}
+
+type RoleResponse struct {
+	Id            uint      `json:"id"`
+	Name          string    `json:"name"`
+	Code          string    `json:"code"`
+	Description   string    `json:"description"`
+	UserCount     int64     `json:"user_count"`               // 拥有该角色的用户数
+	PermissionIds []uint    `json:"permission_ids,omitempty"` // 仅角色详情返回
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type ListRolesResponse struct {
+	Total int64           `json:"total"`
+	Items []*RoleResponse `json:"items"`
+}
+
+// RolePermissionsRequest 批量授予或收回角色的权限
+type RolePermissionsRequest struct {
+	PermissionIds []uint `json:"permission_ids" binding:"required,min=1,max=500"`
+}
+
+// SetRolePermissionsRequest 用给定的权限替换角色现有的权限，空列表表示清空
+type SetRolePermissionsRequest struct {
+	PermissionIds []uint `json:"permission_ids" binding:"required,max=500"`
+}
+
+// UserRolesRequest 批量给用户授予或收回角色，每个用户都会授予或收回每个角色
+type UserRolesRequest struct {
+	UserIds []string `json:"user_ids" binding:"required,min=1,max=100"`
+	RoleIds []uint   `json:"role_ids" binding:"required,min=1,max=20"`
+}
+
+type UserRolesResponse struct {
+	UserId string          `json:"user_id"`
+	Roles  []*RoleResponse `json:"roles"`
+}
+
+// BatchUpdateResponse 批量授予或收回的结果
+type BatchUpdateResponse struct {
+	Affected int64 `json:"affected"` // 实际新增或删除的关联数，已存在或不存在的关联不计入
+}
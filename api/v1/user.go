@@ -1,31 +1,166 @@
 package v1
 
+import "time"
+
 type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email" example:"1234@gmail.com"`
-	Password string `json:"password" binding:"required" example:"123456"`
+	Username string `json:"username" binding:"required,min=3,max=32" example:"alan"` // 用户名，只能包含字母、数字和下划线
+	Nickname string `json:"nickname" binding:"required,max=32" example:"Alan"`
+	Email    string `json:"email" binding:"required,email,max=128" example:"1234@gmail.com"`
+	Password string `json:"password" binding:"required,min=6,max=72" example:"123456"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email" example:"1234@gmail.com"`
+	Account  string `json:"account" binding:"required" example:"alan"` // 用户名或邮箱
 	Password string `json:"password" binding:"required" example:"123456"`
+	IP       string `json:"-"` // 客户端IP，由服务端填充
 }
 type LoginResponseData struct {
-	AccessToken string `json:"accessToken"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"` // 刷新令牌，只能使用一次，刷新后返回新的刷新令牌
+	ExpiresIn    int64  `json:"expiresIn"`    // 访问令牌有效期(秒)
+
+	// 需要两步验证时不返回令牌，使用 ChallengeToken 调用 /login/2fa 完成登录
+	TwoFactorRequired      bool     `json:"twoFactorRequired,omitempty"`
+	TwoFactorSetupRequired bool     `json:"twoFactorSetupRequired,omitempty"` // 所在角色要求两步验证但尚未启用，需先调用 /login/2fa/setup 绑定验证器
+	ChallengeToken         string   `json:"challengeToken,omitempty"`
+	RecoveryCodes          []string `json:"recoveryCodes,omitempty"` // 登录时启用两步验证后返回的恢复码，只展示一次
 }
 type LoginResponse struct {
 	Response
 	Data LoginResponseData
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type LogoutRequest struct {
+	All bool `json:"all"` // 为 true 时注销全部设备上的会话
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"` // 验证邮件链接中的 token 参数
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email,max=128" example:"1234@gmail.com"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"` // 重置密码邮件链接中的 token 参数
+	Password string `json:"password" binding:"required,min=6,max=72" example:"123456"`
+}
+
 type UpdateProfileRequest struct {
-	Nickname string `json:"nickname" example:"alan"`
-	Email    string `json:"email" binding:"required,email" example:"1234@gmail.com"`
+	Nickname string `json:"nickname" binding:"required,max=32" example:"alan"`
+	Email    string `json:"email" binding:"required,email,max=128" example:"1234@gmail.com"`
+	Avatar   string `json:"avatar" binding:"omitempty,url,max=255"` // 头像地址
+	Intro    string `json:"intro" binding:"max=500"`                // 个人简介
 }
 type GetProfileResponseData struct {
-	UserId   string `json:"userId"`
-	Nickname string `json:"nickname" example:"alan"`
+	UserId           string    `json:"userId"`
+	Username         string    `json:"username" example:"alan"`
+	Nickname         string    `json:"nickname" example:"alan"`
+	Email            string    `json:"email" example:"1234@gmail.com"`
+	EmailVerified    bool      `json:"emailVerified"`
+	TwoFactorEnabled bool      `json:"twoFactorEnabled"`
+	Avatar           string    `json:"avatar"`
+	Intro            string    `json:"intro"`
+	CreatedAt        time.Time `json:"createdAt"`
 }
 type GetProfileResponse struct {
 	Response
 	Data GetProfileResponseData
 }
+
+// ListLoginAttemptsRequest 登录记录查询请求
+type ListLoginAttemptsRequest struct {
+	Account  string `form:"account"` // 登录时填写的用户名或邮箱
+	UserId   string `form:"user_id"`
+	IP       string `form:"ip"`
+	Result   string `form:"result" binding:"omitempty,oneof=success invalid_credentials locked"`
+	Page     int    `form:"page"`      // 页码，默认 1
+	PageSize int    `form:"page_size"` // 每页数量，默认 20，最大 100
+}
+
+type LoginAttemptItem struct {
+	Id        uint      `json:"id"`
+	Account   string    `json:"account"`
+	UserId    string    `json:"user_id"`
+	IP        string    `json:"ip"`
+	Result    string    `json:"result"` // success/invalid_credentials/locked
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListLoginAttemptsResponse struct {
+	Total int64               `json:"total"`
+	Items []*LoginAttemptItem `json:"items"`
+}
+
+// BanUserRequest 封禁用户请求，ExpiresAt 为空表示永久封禁
+type BanUserRequest struct {
+	Reason    string     `json:"reason" binding:"required,max=200"`
+	ExpiresAt *time.Time `json:"expires_at"` // 封禁截止时间，必须晚于当前时间
+}
+
+type UnbanUserRequest struct {
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+// ListUserBansRequest 封禁记录查询请求
+type ListUserBansRequest struct {
+	UserId     string `form:"user_id"`     // 被封禁的用户
+	OperatorId string `form:"operator_id"` // 操作人
+	Action     string `form:"action" binding:"omitempty,oneof=ban unban"`
+	Page       int    `form:"page"`      // 页码，默认 1
+	PageSize   int    `form:"page_size"` // 每页数量，默认 20，最大 100
+}
+
+type UserBanItem struct {
+	Id         uint       `json:"id"`
+	UserId     string     `json:"user_id"`
+	Action     string     `json:"action"` // ban/unban
+	Reason     string     `json:"reason"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	OperatorId string     `json:"operator_id"` // 封禁到期自动解封时为空
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ListUserBansResponse struct {
+	Total int64          `json:"total"`
+	Items []*UserBanItem `json:"items"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`          // Base32 编码的密钥，无法扫码时手动输入
+	ProvisioningURI string `json:"provisioningUri"` // otpauth:// 地址，用于生成二维码
+}
+
+type EnableTwoFactorRequest struct {
+	Code string `json:"code" binding:"required,len=6" example:"123456"` // 验证器生成的验证码
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // 验证码或恢复码
+	IP       string `json:"-"`                       // 客户端IP，由服务端填充
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"` // 验证码或恢复码
+	IP   string `json:"-"`                       // 客户端IP，由服务端填充
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"` // 每个恢复码只能使用一次，只展示一次
+}
+
+type LoginTwoFactorSetupRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"` // 验证码或恢复码
+	IP             string `json:"-"`                       // 客户端IP，由服务端填充
+}
@@ -19,7 +19,7 @@ import (
 
 func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), error) {
 	db := repository.NewDB(viperViper, logger)
-	migrate := server.NewMigrate(db, logger)
+	migrate := server.NewMigrate(db, viperViper, logger)
 	appApp := newApp(migrate)
 	return appApp, func() {
 	}, nil
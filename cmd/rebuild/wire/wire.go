@@ -0,0 +1,58 @@
+//go:build wireinject
+// +build wireinject
+
+package wire
+
+import (
+	"novel-site-backend/internal/repository"
+	"novel-site-backend/internal/server"
+	"novel-site-backend/internal/service"
+	"novel-site-backend/pkg/app"
+	"novel-site-backend/pkg/jwt"
+	"novel-site-backend/pkg/log"
+	"novel-site-backend/pkg/sid"
+	"github.com/google/wire"
+	"github.com/spf13/viper"
+)
+
+var repositorySet = wire.NewSet(
+	repository.NewDB,
+	repository.NewRepository,
+	repository.NewTransaction,
+	repository.NewBookRepository,
+	repository.NewBookRatingRepository,
+	repository.NewRatingTypeRepository,
+	repository.NewBookRatingStatRepository,
+	repository.NewSensitiveWordRepository,
+)
+
+var serviceSet = wire.NewSet(
+	service.NewService,
+	service.NewBookRatingService,
+	service.NewModerationService,
+)
+
+var serverSet = wire.NewSet(
+	server.NewRebuild,
+)
+
+// build App
+func newApp(
+	rebuild *server.Rebuild,
+) *app.App {
+	return app.NewApp(
+		app.WithServer(rebuild),
+		app.WithName("demo-rebuild"),
+	)
+}
+
+func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
+	panic(wire.Build(
+		repositorySet,
+		serviceSet,
+		serverSet,
+		sid.NewSid,
+		jwt.NewJwt,
+		newApp,
+	))
+}
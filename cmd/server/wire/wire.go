@@ -11,6 +11,7 @@ import (
 	"novel-site-backend/pkg/app"
 	"novel-site-backend/pkg/jwt"
 	"novel-site-backend/pkg/log"
+	"novel-site-backend/pkg/mailer"
 	"novel-site-backend/pkg/server/http"
 	"novel-site-backend/pkg/sid"
 
@@ -27,14 +28,44 @@ var repositorySet = wire.NewSet(
 	repository.NewRatingTypeRepository,
 	repository.NewBookRatingRepository,
 	repository.NewBookRepository,
+	repository.NewBookCounter,
+	repository.NewBookStatRepository,
+	repository.NewBookRatingStatRepository,
+	repository.NewSensitiveWordRepository,
+	repository.NewReviewRepository,
+	repository.NewReportRepository,
+	repository.NewRatingFlagRepository,
+	repository.NewTokenRepository,
+	repository.NewLoginAttemptRepository,
+	repository.NewTwoFactorRepository,
+	repository.NewRoleRepository,
+	repository.NewPermissionRepository,
+	repository.NewRolePermissionRepository,
+	repository.NewUserRoleRepository,
+	repository.NewUserBanRepository,
 )
 
 var serviceSet = wire.NewSet(
 	service.NewService,
 	service.NewUserService,
+	service.NewTokenService,
+	service.NewLoginGuardService,
+	service.NewTwoFactorService,
+	service.NewPermissionService,
+	service.NewRoleService,
+	service.NewRolePermissionService,
+	service.NewUserRoleService,
+	service.NewUserBanService,
 	service.NewRatingTypeService,
 	service.NewBookRatingService,
 	service.NewBookService,
+	service.NewRankingService,
+	service.NewAnalyticsService,
+	service.NewModerationService,
+	service.NewReviewService,
+	service.NewReportService,
+	service.NewRatingFraudService,
+	service.NewMailQueue,
 )
 
 var handlerSet = wire.NewSet(
@@ -43,21 +74,38 @@ var handlerSet = wire.NewSet(
 	handler.NewRatingTypeHandler,
 	handler.NewBookRatingHandler,
 	handler.NewBookHandler,
+	handler.NewRankingHandler,
+	handler.NewAnalyticsHandler,
+	handler.NewModerationHandler,
+	handler.NewReviewHandler,
+	handler.NewReportHandler,
+	handler.NewRatingFraudHandler,
+	handler.NewLoginAttemptHandler,
+	handler.NewTwoFactorHandler,
+	handler.NewRoleHandler,
+	handler.NewPermissionHandler,
+	handler.NewRolePermissionHandler,
+	handler.NewUserRoleHandler,
+	handler.NewUserBanHandler,
 )
 
 var serverSet = wire.NewSet(
 	server.NewHTTPServer,
 	server.NewJob,
+	server.NewCounterFlusher,
+	server.NewMailWorker,
 )
 
 // build App
 func newApp(
 	httpServer *http.Server,
 	job *server.Job,
+	counterFlusher *server.CounterFlusher,
+	mailWorker *server.MailWorker,
 	// task *server.Task,
 ) *app.App {
 	return app.NewApp(
-		app.WithServer(httpServer, job),
+		app.WithServer(httpServer, job, counterFlusher, mailWorker),
 		app.WithName("novel-site-backend"),
 	)
 }
@@ -70,6 +118,7 @@ func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
 		serverSet,
 		sid.NewSid,
 		jwt.NewJwt,
+		mailer.NewMailer,
 		newApp,
 	))
 }
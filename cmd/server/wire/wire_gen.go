@@ -16,6 +16,7 @@ import (
 	"novel-site-backend/pkg/app"
 	"novel-site-backend/pkg/jwt"
 	"novel-site-backend/pkg/log"
+	"novel-site-backend/pkg/mailer"
 	"novel-site-backend/pkg/server/http"
 	"novel-site-backend/pkg/sid"
 )
@@ -31,39 +32,87 @@ func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), err
 	sidSid := sid.NewSid()
 	serviceService := service.NewService(transaction, logger, sidSid, jwtJWT)
 	userRepository := repository.NewUserRepository(repositoryRepository)
-	userService := service.NewUserService(serviceService, userRepository)
-	userHandler := handler.NewUserHandler(handlerHandler, userService)
+	tokenRepository := repository.NewTokenRepository(repositoryRepository)
+	tokenService := service.NewTokenService(serviceService, viperViper, tokenRepository, userRepository)
+	permissionRepository := repository.NewPermissionRepository(repositoryRepository)
+	roleRepository := repository.NewRoleRepository(repositoryRepository)
+	rolePermissionRepository := repository.NewRolePermissionRepository(repositoryRepository)
+	permissionService := service.NewPermissionService(serviceService, viperViper, permissionRepository, roleRepository, rolePermissionRepository)
+	mailerMailer := mailer.NewMailer(viperViper, logger)
+	mailQueue := service.NewMailQueue(logger, viperViper)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(repositoryRepository)
+	loginGuardService := service.NewLoginGuardService(serviceService, viperViper, loginAttemptRepository)
+	twoFactorRepository := repository.NewTwoFactorRepository(repositoryRepository)
+	twoFactorService := service.NewTwoFactorService(serviceService, viperViper, userRepository, tokenRepository, twoFactorRepository, tokenService, loginGuardService)
+	userService := service.NewUserService(serviceService, viperViper, userRepository, tokenRepository, tokenService, loginGuardService, twoFactorService, mailerMailer, mailQueue)
+	userHandler := handler.NewUserHandler(handlerHandler, userService, tokenService)
 	bookRepository := repository.NewBookRepository(repositoryRepository)
-	bookService := service.NewBookService(serviceService, bookRepository)
+	bookCounter := repository.NewBookCounter(viperViper)
+	bookStatRepository := repository.NewBookStatRepository(repositoryRepository)
+	bookService := service.NewBookService(serviceService, bookRepository, bookStatRepository, bookCounter)
 	bookHandler := handler.NewBookHandler(handlerHandler, bookService)
 	bookRatingRepository := repository.NewBookRatingRepository(repositoryRepository)
 	ratingTypeRepository := repository.NewRatingTypeRepository(repositoryRepository)
-	bookRatingService := service.NewBookRatingService(serviceService, bookRatingRepository, ratingTypeRepository)
+	bookRatingStatRepository := repository.NewBookRatingStatRepository(repositoryRepository)
+	sensitiveWordRepository := repository.NewSensitiveWordRepository(repositoryRepository)
+	moderationService := service.NewModerationService(serviceService, viperViper, bookRatingRepository, sensitiveWordRepository)
+	bookRatingService := service.NewBookRatingService(serviceService, viperViper, bookRepository, bookRatingRepository, bookRatingStatRepository, ratingTypeRepository, moderationService)
 	bookRatingHandler := handler.NewBookRatingHandler(handlerHandler, bookRatingService)
-	ratingTypeService := service.NewRatingTypeService(serviceService, ratingTypeRepository)
+	ratingTypeService := service.NewRatingTypeService(serviceService, ratingTypeRepository, bookRatingRepository, bookRatingStatRepository, bookRatingService)
 	ratingTypeHandler := handler.NewRatingTypeHandler(handlerHandler, ratingTypeService)
-	httpServer := server.NewHTTPServer(logger, viperViper, jwtJWT, userHandler, bookHandler, bookRatingHandler, ratingTypeHandler)
+	rankingService := service.NewRankingService(serviceService, viperViper, bookRepository, bookStatRepository, bookRatingRepository)
+	rankingHandler := handler.NewRankingHandler(handlerHandler, rankingService)
+	analyticsService := service.NewAnalyticsService(serviceService, bookRepository, bookStatRepository)
+	analyticsHandler := handler.NewAnalyticsHandler(handlerHandler, analyticsService)
+	moderationHandler := handler.NewModerationHandler(handlerHandler, moderationService, bookRatingService)
+	reviewRepository := repository.NewReviewRepository(repositoryRepository)
+	reviewService := service.NewReviewService(serviceService, viperViper, bookRatingRepository, reviewRepository, moderationService)
+	reviewHandler := handler.NewReviewHandler(handlerHandler, reviewService)
+	reportRepository := repository.NewReportRepository(repositoryRepository)
+	reportService := service.NewReportService(serviceService, viperViper, reportRepository, bookRepository, bookRatingRepository, bookRatingService)
+	reportHandler := handler.NewReportHandler(handlerHandler, reportService)
+	ratingFlagRepository := repository.NewRatingFlagRepository(repositoryRepository)
+	ratingFraudService := service.NewRatingFraudService(serviceService, viperViper, bookRatingRepository, bookRatingStatRepository, ratingFlagRepository, bookRatingService)
+	ratingFraudHandler := handler.NewRatingFraudHandler(handlerHandler, ratingFraudService)
+	loginAttemptHandler := handler.NewLoginAttemptHandler(handlerHandler, loginGuardService)
+	twoFactorHandler := handler.NewTwoFactorHandler(handlerHandler, twoFactorService)
+	userRoleRepository := repository.NewUserRoleRepository(repositoryRepository)
+	roleService := service.NewRoleService(serviceService, roleRepository, permissionRepository, rolePermissionRepository, userRoleRepository, permissionService)
+	roleHandler := handler.NewRoleHandler(handlerHandler, roleService)
+	permissionHandler := handler.NewPermissionHandler(handlerHandler, permissionService)
+	rolePermissionService := service.NewRolePermissionService(serviceService, roleRepository, permissionRepository, rolePermissionRepository, permissionService)
+	rolePermissionHandler := handler.NewRolePermissionHandler(handlerHandler, rolePermissionService)
+	userRoleService := service.NewUserRoleService(serviceService, userRepository, roleRepository, userRoleRepository, permissionService)
+	userRoleHandler := handler.NewUserRoleHandler(handlerHandler, userRoleService)
+	userBanRepository := repository.NewUserBanRepository(repositoryRepository)
+	userBanService := service.NewUserBanService(serviceService, userRepository, userBanRepository, tokenService, permissionService)
+	userBanHandler := handler.NewUserBanHandler(handlerHandler, userBanService)
+	httpServer := server.NewHTTPServer(logger, viperViper, jwtJWT, tokenService, permissionService, userHandler, bookHandler, bookRatingHandler, ratingTypeHandler, rankingHandler, analyticsHandler, moderationHandler, reviewHandler, reportHandler, ratingFraudHandler, loginAttemptHandler, twoFactorHandler, roleHandler, permissionHandler, rolePermissionHandler, userRoleHandler, userBanHandler)
 	job := server.NewJob(logger)
-	appApp := newApp(httpServer, job)
+	counterFlusher := server.NewCounterFlusher(logger, viperViper, bookService)
+	mailWorker := server.NewMailWorker(logger, mailQueue)
+	appApp := newApp(httpServer, job, counterFlusher, mailWorker)
 	return appApp, func() {
 	}, nil
 }
 
 // wire.go:
 
-var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewRatingTypeRepository, repository.NewBookRatingRepository, repository.NewBookRepository)
+var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewRatingTypeRepository, repository.NewBookRatingRepository, repository.NewBookRepository, repository.NewBookCounter, repository.NewBookStatRepository, repository.NewBookRatingStatRepository, repository.NewSensitiveWordRepository, repository.NewReviewRepository, repository.NewReportRepository, repository.NewRatingFlagRepository, repository.NewTokenRepository, repository.NewLoginAttemptRepository, repository.NewTwoFactorRepository, repository.NewRoleRepository, repository.NewPermissionRepository, repository.NewRolePermissionRepository, repository.NewUserRoleRepository, repository.NewUserBanRepository)
 
-var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewRatingTypeService, service.NewBookRatingService, service.NewBookService)
+var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewTokenService, service.NewLoginGuardService, service.NewTwoFactorService, service.NewPermissionService, service.NewRoleService, service.NewRolePermissionService, service.NewUserRoleService, service.NewUserBanService, service.NewRatingTypeService, service.NewBookRatingService, service.NewBookService, service.NewRankingService, service.NewAnalyticsService, service.NewModerationService, service.NewReviewService, service.NewReportService, service.NewRatingFraudService, service.NewMailQueue)
 
-var handlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewRatingTypeHandler, handler.NewBookRatingHandler, handler.NewBookHandler)
+var handlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewRatingTypeHandler, handler.NewBookRatingHandler, handler.NewBookHandler, handler.NewRankingHandler, handler.NewAnalyticsHandler, handler.NewModerationHandler, handler.NewReviewHandler, handler.NewReportHandler, handler.NewRatingFraudHandler, handler.NewLoginAttemptHandler, handler.NewTwoFactorHandler, handler.NewRoleHandler, handler.NewPermissionHandler, handler.NewRolePermissionHandler, handler.NewUserRoleHandler, handler.NewUserBanHandler)
 
-var serverSet = wire.NewSet(server.NewHTTPServer, server.NewJob)
+var serverSet = wire.NewSet(server.NewHTTPServer, server.NewJob, server.NewCounterFlusher, server.NewMailWorker)
 
 // build App
 func newApp(
 	httpServer *http.Server,
 	job *server.Job,
+	counterFlusher *server.CounterFlusher,
+	mailWorker *server.MailWorker,
 
 ) *app.App {
-	return app.NewApp(app.WithServer(httpServer, job), app.WithName("novel-site-backend"))
+	return app.NewApp(app.WithServer(httpServer, job, counterFlusher, mailWorker), app.WithName("novel-site-backend"))
 }
@@ -4,13 +4,49 @@
 package wire
 
 import (
+	"novel-site-backend/internal/repository"
 	"novel-site-backend/internal/server"
+	"novel-site-backend/internal/service"
 	"novel-site-backend/pkg/app"
+	"novel-site-backend/pkg/jwt"
 	"novel-site-backend/pkg/log"
+	"novel-site-backend/pkg/sid"
 	"github.com/google/wire"
 	"github.com/spf13/viper"
 )
 
+var repositorySet = wire.NewSet(
+	repository.NewDB,
+	repository.NewRepository,
+	repository.NewTransaction,
+	repository.NewBookRepository,
+	repository.NewBookStatRepository,
+	repository.NewBookRatingRepository,
+	repository.NewRatingTypeRepository,
+	repository.NewBookRatingStatRepository,
+	repository.NewSensitiveWordRepository,
+	repository.NewRatingFlagRepository,
+	repository.NewTokenRepository,
+	repository.NewLoginAttemptRepository,
+	repository.NewUserRepository,
+	repository.NewUserBanRepository,
+	repository.NewPermissionRepository,
+	repository.NewRoleRepository,
+	repository.NewRolePermissionRepository,
+)
+
+var serviceSet = wire.NewSet(
+	service.NewService,
+	service.NewRankingService,
+	service.NewBookRatingService,
+	service.NewModerationService,
+	service.NewRatingFraudService,
+	service.NewTokenService,
+	service.NewLoginGuardService,
+	service.NewUserBanService,
+	service.NewPermissionService,
+)
+
 var serverSet = wire.NewSet(
 	server.NewTask,
 )
@@ -27,7 +63,11 @@ func newApp(
 
 func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
 	panic(wire.Build(
+		repositorySet,
+		serviceSet,
 		serverSet,
+		sid.NewSid,
+		jwt.NewJwt,
 		newApp,
 	))
 }
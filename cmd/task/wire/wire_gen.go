@@ -7,9 +7,13 @@
 package wire
 
 import (
+	"novel-site-backend/internal/repository"
 	"novel-site-backend/internal/server"
+	"novel-site-backend/internal/service"
 	"novel-site-backend/pkg/app"
+	"novel-site-backend/pkg/jwt"
 	"novel-site-backend/pkg/log"
+	"novel-site-backend/pkg/sid"
 	"github.com/google/wire"
 	"github.com/spf13/viper"
 )
@@ -17,7 +21,35 @@ import (
 // Injectors from wire.go:
 
 func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), error) {
-	task := server.NewTask(logger)
+	db := repository.NewDB(viperViper, logger)
+	repositoryRepository := repository.NewRepository(logger, db)
+	transaction := repository.NewTransaction(repositoryRepository)
+	sidSid := sid.NewSid()
+	jwtJWT := jwt.NewJwt(viperViper)
+	serviceService := service.NewService(transaction, logger, sidSid, jwtJWT)
+	bookRepository := repository.NewBookRepository(repositoryRepository)
+	bookStatRepository := repository.NewBookStatRepository(repositoryRepository)
+	bookRatingRepository := repository.NewBookRatingRepository(repositoryRepository)
+	rankingService := service.NewRankingService(serviceService, viperViper, bookRepository, bookStatRepository, bookRatingRepository)
+	ratingTypeRepository := repository.NewRatingTypeRepository(repositoryRepository)
+	bookRatingStatRepository := repository.NewBookRatingStatRepository(repositoryRepository)
+	sensitiveWordRepository := repository.NewSensitiveWordRepository(repositoryRepository)
+	moderationService := service.NewModerationService(serviceService, viperViper, bookRatingRepository, sensitiveWordRepository)
+	bookRatingService := service.NewBookRatingService(serviceService, viperViper, bookRepository, bookRatingRepository, bookRatingStatRepository, ratingTypeRepository, moderationService)
+	ratingFlagRepository := repository.NewRatingFlagRepository(repositoryRepository)
+	ratingFraudService := service.NewRatingFraudService(serviceService, viperViper, bookRatingRepository, bookRatingStatRepository, ratingFlagRepository, bookRatingService)
+	tokenRepository := repository.NewTokenRepository(repositoryRepository)
+	userRepository := repository.NewUserRepository(repositoryRepository)
+	tokenService := service.NewTokenService(serviceService, viperViper, tokenRepository, userRepository)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(repositoryRepository)
+	loginGuardService := service.NewLoginGuardService(serviceService, viperViper, loginAttemptRepository)
+	userBanRepository := repository.NewUserBanRepository(repositoryRepository)
+	permissionRepository := repository.NewPermissionRepository(repositoryRepository)
+	roleRepository := repository.NewRoleRepository(repositoryRepository)
+	rolePermissionRepository := repository.NewRolePermissionRepository(repositoryRepository)
+	permissionService := service.NewPermissionService(serviceService, viperViper, permissionRepository, roleRepository, rolePermissionRepository)
+	userBanService := service.NewUserBanService(serviceService, userRepository, userBanRepository, tokenService, permissionService)
+	task := server.NewTask(logger, viperViper, rankingService, bookRatingService, ratingFraudService, tokenService, loginGuardService, userBanService)
 	appApp := newApp(task)
 	return appApp, func() {
 	}, nil
@@ -25,6 +57,10 @@ func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), err
 
 // wire.go:
 
+var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewBookRepository, repository.NewBookStatRepository, repository.NewBookRatingRepository, repository.NewRatingTypeRepository, repository.NewBookRatingStatRepository, repository.NewSensitiveWordRepository, repository.NewRatingFlagRepository, repository.NewTokenRepository, repository.NewLoginAttemptRepository, repository.NewUserRepository, repository.NewUserBanRepository, repository.NewPermissionRepository, repository.NewRoleRepository, repository.NewRolePermissionRepository)
+
+var serviceSet = wire.NewSet(service.NewService, service.NewRankingService, service.NewBookRatingService, service.NewModerationService, service.NewRatingFraudService, service.NewTokenService, service.NewLoginGuardService, service.NewUserBanService, service.NewPermissionService)
+
 var serverSet = wire.NewSet(server.NewTask)
 
 // build App
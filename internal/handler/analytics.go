@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AnalyticsHandler struct {
+	*Handler
+	analyticsService service.AnalyticsService
+}
+
+func NewAnalyticsHandler(handler *Handler, analyticsService service.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		Handler:          handler,
+		analyticsService: analyticsService,
+	}
+}
+
+// GetBookAnalytics godoc
+// @Summary 获取图书每日统计
+// @Tags 统计模块
+// @Accept json
+// @Produce json,text/csv
+// @Security Bearer
+// @Param id path int true "书籍ID"
+// @Param from query string false "起始日期(含)，格式 2006-01-02，默认 to 前 29 天"
+// @Param to query string false "结束日期(含)，格式 2006-01-02，默认今天"
+// @Param format query string false "输出格式(json/csv)，默认 json"
+// @Success 200 {object} v1.AnalyticsSeriesResponse
+// @Router /admin/analytics/books/{id} [get]
+func (h *AnalyticsHandler) GetBookAnalytics(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	series, err := h.analyticsService.GetBookSeries(ctx, uint(id), ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		h.handleAnalyticsError(ctx, err)
+		return
+	}
+
+	h.writeSeries(ctx, series, fmt.Sprintf("book-%d", id))
+}
+
+// GetSiteAnalytics godoc
+// @Summary 获取全站每日统计
+// @Tags 统计模块
+// @Accept json
+// @Produce json,text/csv
+// @Security Bearer
+// @Param from query string false "起始日期(含)，格式 2006-01-02，默认 to 前 29 天"
+// @Param to query string false "结束日期(含)，格式 2006-01-02，默认今天"
+// @Param format query string false "输出格式(json/csv)，默认 json"
+// @Success 200 {object} v1.AnalyticsSeriesResponse
+// @Router /admin/analytics/site [get]
+func (h *AnalyticsHandler) GetSiteAnalytics(ctx *gin.Context) {
+	series, err := h.analyticsService.GetSiteSeries(ctx, ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		h.handleAnalyticsError(ctx, err)
+		return
+	}
+
+	h.writeSeries(ctx, series, "site")
+}
+
+func (h *AnalyticsHandler) handleAnalyticsError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, v1.ErrBadRequest):
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+	case errors.Is(err, v1.ErrNotFound):
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+	default:
+		h.logger.WithContext(ctx).Error("analyticsService error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+	}
+}
+
+// writeSeries 按 format 参数输出 JSON 或 CSV
+func (h *AnalyticsHandler) writeSeries(ctx *gin.Context, series *v1.AnalyticsSeriesResponse, name string) {
+	if ctx.Query("format") != "csv" {
+		v1.HandleSuccess(ctx, series)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s-%s.csv", name, series.From, series.To)
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Status(http.StatusOK)
+
+	w := csv.NewWriter(ctx.Writer)
+	_ = w.Write([]string{"date", "views", "downloads", "unique_visitors"})
+	for _, point := range series.Points {
+		_ = w.Write([]string{
+			point.Date,
+			strconv.FormatInt(point.Views, 10),
+			strconv.FormatInt(point.Downloads, 10),
+			strconv.FormatInt(point.UniqueVisitors, 10),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		h.logger.WithContext(ctx).Error("write analytics csv error", zap.Error(err))
+	}
+}
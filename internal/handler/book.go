@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/middleware"
 	"novel-site-backend/internal/service"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -52,8 +56,11 @@ func (h *BookHandler) CreateBook(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "书籍ID"
+// @Param If-Match header string true "GetBook 返回的 ETag，格式为 \"v<version>-<hash>\""
 // @Param request body v1.UpdateBookRequest true "params"
 // @Success 200 {object} v1.Response
+// @Failure 412 {object} v1.Response
+// @Failure 428 {object} v1.Response
 // @Router /books/{id} [put]
 func (h *BookHandler) UpdateBook(ctx *gin.Context) {
 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
@@ -62,14 +69,20 @@ func (h *BookHandler) UpdateBook(ctx *gin.Context) {
 		return
 	}
 
+	version, ok := parseIfMatch(ctx)
+	if !ok {
+		v1.HandleError(ctx, http.StatusPreconditionRequired, v1.ErrPreconditionRequired, nil)
+		return
+	}
+
 	req := new(v1.UpdateBookRequest)
 	if err := ctx.ShouldBindJSON(req); err != nil {
 		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
 		return
 	}
 
-	if err := h.bookService.UpdateBook(ctx, uint(id), req); err != nil {
-		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+	if err := h.bookService.UpdateBook(ctx, uint(id), version, req); err != nil {
+		handleBookWriteError(ctx, err)
 		return
 	}
 
@@ -82,7 +95,10 @@ func (h *BookHandler) UpdateBook(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "书籍ID"
+// @Param If-Match header string true "GetBook 返回的 ETag，格式为 \"v<version>-<hash>\""
 // @Success 200 {object} v1.Response
+// @Failure 412 {object} v1.Response
+// @Failure 428 {object} v1.Response
 // @Router /books/{id} [delete]
 func (h *BookHandler) DeleteBook(ctx *gin.Context) {
 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
@@ -91,8 +107,14 @@ func (h *BookHandler) DeleteBook(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.bookService.DeleteBook(ctx, uint(id)); err != nil {
-		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+	version, ok := parseIfMatch(ctx)
+	if !ok {
+		v1.HandleError(ctx, http.StatusPreconditionRequired, v1.ErrPreconditionRequired, nil)
+		return
+	}
+
+	if err := h.bookService.DeleteBook(ctx, uint(id), version); err != nil {
+		handleBookWriteError(ctx, err)
 		return
 	}
 
@@ -114,15 +136,51 @@ func (h *BookHandler) GetBook(ctx *gin.Context) {
 		return
 	}
 
-	book, err := h.bookService.GetBook(ctx, uint(id))
+	book, err := h.bookService.GetBook(ctx, uint(id), middleware.GetClientIP(ctx))
 	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+			return
+		}
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
 	}
 
+	// 热度、下载量和评分变化时版本号不变，ETag 由缓存中间件按响应内容计算并带上版本号前缀
+	middleware.SetETagPrefix(ctx, fmt.Sprintf("v%d-", book.Version))
 	v1.HandleSuccess(ctx, book)
 }
 
+// DownloadBook godoc
+// @Summary 下载书籍
+// @Description 记录一次下载并返回文件地址
+// @Tags 书籍模块
+// @Accept json
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Success 200 {object} v1.DownloadBookResponse
+// @Router /books/{id}/download [post]
+func (h *BookHandler) DownloadBook(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.bookService.DownloadBook(ctx, uint(id), middleware.GetClientIP(ctx))
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("bookService.DownloadBook error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
 // ListBooks godoc
 // @Summary 获取书籍列表
 // @Tags 书籍模块
@@ -200,3 +258,36 @@ func (h *BookHandler) QuickSearch(ctx *gin.Context) {
 
 	v1.HandleSuccess(ctx, result)
 }
+
+// parseIfMatch 从 If-Match 请求头中解析图书版本号，格式为 GetBook 返回的 "v<version>-<hash>"
+func parseIfMatch(ctx *gin.Context) (uint, bool) {
+	etag := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, false
+	}
+	value := etag[1 : len(etag)-1]
+	if !strings.HasPrefix(value, "v") {
+		return 0, false
+	}
+	value, _, ok := strings.Cut(value[1:], "-")
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(version), true
+}
+
+// handleBookWriteError 将图书写操作的错误映射为对应的 HTTP 状态码
+func handleBookWriteError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, v1.ErrBookVersionConflict):
+		v1.HandleError(ctx, http.StatusPreconditionFailed, err, nil)
+	case errors.Is(err, v1.ErrNotFound):
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+	default:
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/service"
@@ -29,8 +30,12 @@ func NewBookRatingHandler(handler *Handler, bookRatingService service.BookRating
 // @Tags 书籍评分模块
 // @Accept json
 // @Produce json
+// @Description 每个评分者对同一本书只保留一条评分，重复提交会更新已有评分
 // @Param request body v1.CreateBookRatingRequest true "params"
-// @Success 200 {object} v1.Response
+// @Success 200 {object} v1.CreateBookRatingResponse
+// @Failure 409 {object} v1.Response
+// @Failure 422 {object} v1.Response
+// @Failure 429 {object} v1.Response
 // @Router /book-ratings [post]
 func (h *BookRatingHandler) CreateBookRating(ctx *gin.Context) {
 	req := new(v1.CreateBookRatingRequest)
@@ -39,16 +44,32 @@ func (h *BookRatingHandler) CreateBookRating(ctx *gin.Context) {
 		return
 	}
 
-	// 从中间件获取IP
+	// 从中间件获取IP和评分者身份，登录用户优先使用用户ID
 	req.IP = middleware.GetClientIP(ctx)
+	req.UserId = GetUserIdFromCtx(ctx)
+	req.VisitorId = middleware.GetVisitorId(ctx)
+	req.VisitorSince = middleware.GetVisitorIssuedAt(ctx)
 
-	if err := h.bookRatingService.CreateBookRating(ctx, req); err != nil {
+	resp, err := h.bookRatingService.CreateBookRating(ctx, req)
+	if err != nil {
+		if errors.Is(err, v1.ErrRatingLimitExceeded) {
+			v1.HandleError(ctx, http.StatusTooManyRequests, err, nil)
+			return
+		}
+		if errors.Is(err, v1.ErrCommentRejected) {
+			v1.HandleError(ctx, http.StatusUnprocessableEntity, err, nil)
+			return
+		}
+		if errors.Is(err, v1.ErrRatingDuplicate) {
+			v1.HandleError(ctx, http.StatusConflict, err, nil)
+			return
+		}
 		h.logger.WithContext(ctx).Error("bookRatingService.CreateBookRating error", zap.Error(err))
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	v1.HandleSuccess(ctx, nil)
+	v1.HandleSuccess(ctx, resp)
 }
 
 // UpdateBookRating godoc
@@ -74,6 +95,14 @@ func (h *BookRatingHandler) UpdateBookRating(ctx *gin.Context) {
 	}
 
 	if err := h.bookRatingService.UpdateBookRating(ctx, uint(id), req); err != nil {
+		if errors.Is(err, v1.ErrCommentRejected) {
+			v1.HandleError(ctx, http.StatusUnprocessableEntity, err, nil)
+			return
+		}
+		if errors.Is(err, v1.ErrNotFound) {
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+			return
+		}
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
 	}
@@ -97,6 +126,10 @@ func (h *BookRatingHandler) DeleteBookRating(ctx *gin.Context) {
 	}
 
 	if err := h.bookRatingService.DeleteBookRating(ctx, uint(id)); err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+			return
+		}
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
 	}
@@ -128,31 +161,84 @@ func (h *BookRatingHandler) GetBookRating(ctx *gin.Context) {
 	v1.HandleSuccess(ctx, stats)
 }
 
-// ListBookRatings godoc
-// @Summary 获取书籍评分列表
+// ListBookReviews godoc
+// @Summary 获取书籍评论列表
 // @Tags 书籍评分模块
 // @Accept json
 // @Produce json
-// @Param book_id path int true "书籍ID"
-// @Param page query int false "页码"
-// @Param page_size query int false "每页数量"
-// @Success 200 {object} v1.ListBookRatingsResponse
-// @Router /books/{book_id}/ratings [get]
-func (h *BookRatingHandler) ListBookRatings(ctx *gin.Context) {
-	bookId, err := strconv.ParseUint(ctx.Param("book_id"), 10, 32)
+// @Description 只返回审核通过的评论，IP 打码展示，使用游标分页
+// @Param id path int true "书籍ID"
+// @Param order query string false "排序方式(newest/liked)，默认 newest"
+// @Param rating_type_id query int false "评分类型ID"
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param limit query int false "每页数量，默认 20，最大 50"
+// @Success 200 {object} v1.ListBookReviewsResponse
+// @Router /books/{id}/reviews [get]
+func (h *BookRatingHandler) ListBookReviews(ctx *gin.Context) {
+	bookId, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
 	if err != nil {
 		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
 		return
 	}
 
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	req := new(v1.ListBookReviewsRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
 
-	ratings, err := h.bookRatingService.ListBookRatings(ctx, uint(bookId), page, pageSize)
+	reviews, err := h.bookRatingService.ListBookReviews(ctx, uint(bookId), req)
 	if err != nil {
+		if errors.Is(err, v1.ErrBadRequest) {
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("bookRatingService.ListBookReviews error", zap.Error(err))
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	v1.HandleSuccess(ctx, ratings)
+	v1.HandleSuccess(ctx, reviews)
+}
+
+// GetRatingTrend godoc
+// @Summary 获取书籍评分趋势
+// @Tags 书籍评分模块
+// @Accept json
+// @Produce json
+// @Description 按天/周/月统计各评分类型的评分数，无评分的时间段补零
+// @Param id path int true "书籍ID"
+// @Param interval query string false "分桶粒度(day/week/month)，默认 day"
+// @Param from query string false "起始日期(含)，默认最近 30 天/12 周/12 个月"
+// @Param to query string false "结束日期(含)，默认今天"
+// @Success 200 {object} v1.GetRatingTrendResponse
+// @Router /books/{id}/rating-trend [get]
+func (h *BookRatingHandler) GetRatingTrend(ctx *gin.Context) {
+	bookId, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.GetRatingTrendRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	trend, err := h.bookRatingService.GetRatingTrend(ctx, uint(bookId), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, v1.ErrBadRequest):
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		case errors.Is(err, v1.ErrNotFound):
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+		default:
+			h.logger.WithContext(ctx).Error("bookRatingService.GetRatingTrend error", zap.Error(err))
+			v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		}
+		return
+	}
+
+	v1.HandleSuccess(ctx, trend)
 }
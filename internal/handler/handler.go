@@ -24,3 +24,10 @@ func GetUserIdFromCtx(ctx *gin.Context) string {
 	}
 	return v.(*jwt.MyCustomClaims).UserId
 }
+func GetClaimsFromCtx(ctx *gin.Context) *jwt.MyCustomClaims {
+	v, exists := ctx.Get("claims")
+	if !exists {
+		return nil
+	}
+	return v.(*jwt.MyCustomClaims)
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type LoginAttemptHandler struct {
+	*Handler
+	loginGuardService service.LoginGuardService
+}
+
+func NewLoginAttemptHandler(handler *Handler, loginGuardService service.LoginGuardService) *LoginAttemptHandler {
+	return &LoginAttemptHandler{
+		Handler:           handler,
+		loginGuardService: loginGuardService,
+	}
+}
+
+// ListLoginAttempts godoc
+// @Summary 获取登录记录
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param account query string false "登录时填写的用户名或邮箱"
+// @Param user_id query string false "用户ID"
+// @Param ip query string false "客户端IP"
+// @Param result query string false "结果(success/invalid_credentials/locked)"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} v1.ListLoginAttemptsResponse
+// @Router /admin/login-attempts [get]
+func (h *LoginAttemptHandler) ListLoginAttempts(ctx *gin.Context) {
+	req := new(v1.ListLoginAttemptsRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.loginGuardService.ListLoginAttempts(ctx, req)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("loginGuardService.ListLoginAttempts error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
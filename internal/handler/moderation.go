@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ModerationHandler struct {
+	*Handler
+	moderationService service.ModerationService
+	bookRatingService service.BookRatingService
+}
+
+func NewModerationHandler(
+	handler *Handler,
+	moderationService service.ModerationService,
+	bookRatingService service.BookRatingService,
+) *ModerationHandler {
+	return &ModerationHandler{
+		Handler:           handler,
+		moderationService: moderationService,
+		bookRatingService: bookRatingService,
+	}
+}
+
+// ListReviewQueue godoc
+// @Summary 获取评论审核队列
+// @Tags 评论审核模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param status query string false "审核状态(pending/approved/rejected)，默认 pending"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} v1.ListReviewQueueResponse
+// @Router /admin/reviews [get]
+func (h *ModerationHandler) ListReviewQueue(ctx *gin.Context) {
+	req := new(v1.ListReviewQueueRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.moderationService.ListReviewQueue(ctx, req)
+	if err != nil {
+		if errors.Is(err, v1.ErrBadRequest) {
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("moderationService.ListReviewQueue error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// ModerateReviews godoc
+// @Summary 批量审核评论
+// @Tags 评论审核模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.ModerateReviewsRequest true "params"
+// @Success 200 {object} v1.ModerateReviewsResponse
+// @Router /admin/reviews/moderate [post]
+func (h *ModerationHandler) ModerateReviews(ctx *gin.Context) {
+	req := new(v1.ModerateReviewsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.bookRatingService.ModerateReviews(ctx, req)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("bookRatingService.ModerateReviews error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// ListSensitiveWords godoc
+// @Summary 获取敏感词列表
+// @Tags 评论审核模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} v1.ListSensitiveWordsResponse
+// @Router /admin/sensitive-words [get]
+func (h *ModerationHandler) ListSensitiveWords(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "50"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	resp, err := h.moderationService.ListSensitiveWords(ctx, page, pageSize)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("moderationService.ListSensitiveWords error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// AddSensitiveWords godoc
+// @Summary 批量添加敏感词
+// @Tags 评论审核模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Description 已存在的词会更新处理方式，保存后词库立即生效
+// @Param request body v1.AddSensitiveWordsRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/sensitive-words [post]
+func (h *ModerationHandler) AddSensitiveWords(ctx *gin.Context) {
+	req := new(v1.AddSensitiveWordsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.moderationService.AddSensitiveWords(ctx, req); err != nil {
+		if errors.Is(err, v1.ErrBadRequest) {
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("moderationService.AddSensitiveWords error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
+
+// DeleteSensitiveWord godoc
+// @Summary 删除敏感词
+// @Tags 评论审核模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "敏感词ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/sensitive-words/{id} [delete]
+func (h *ModerationHandler) DeleteSensitiveWord(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.moderationService.DeleteSensitiveWord(ctx, uint(id)); err != nil {
+		h.logger.WithContext(ctx).Error("moderationService.DeleteSensitiveWord error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
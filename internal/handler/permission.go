@@ -1,8 +1,12 @@
 package handler
 
 import (
-	"github.com/gin-gonic/gin"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
 )
 
 type PermissionHandler struct {
@@ -11,15 +15,137 @@ type PermissionHandler struct {
 }
 
 func NewPermissionHandler(
-    handler *Handler,
-    permissionService service.PermissionService,
+	handler *Handler,
+	permissionService service.PermissionService,
 ) *PermissionHandler {
 	return &PermissionHandler{
-		Handler:      handler,
+		Handler:           handler,
 		permissionService: permissionService,
 	}
 }
 
+// GetPermissionTree godoc
+// @Summary 获取权限树
+// @Description 按父级组装全部权限，父级不存在的权限作为顶级
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.PermissionTreeResponse
+// @Router /admin/permissions [get]
+func (h *PermissionHandler) GetPermissionTree(ctx *gin.Context) {
+	resp, err := h.permissionService.GetPermissionTree(ctx)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "permissionService.GetPermissionTree error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// GetPermission godoc
+// @Summary 获取权限详情
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "权限ID"
+// @Success 200 {object} v1.PermissionResponse
+// @Router /admin/permissions/{id} [get]
 func (h *PermissionHandler) GetPermission(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.permissionService.GetPermission(ctx, uint(id))
+	if err != nil {
+		handleRBACError(ctx, h.logger, "permissionService.GetPermission error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// CreatePermission godoc
+// @Summary 创建权限
+// @Description api 类型的权限只能作为叶子节点；编码与已删除的权限相同时恢复该权限
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.CreatePermissionRequest true "params"
+// @Success 200 {object} v1.CreatePermissionResponse
+// @Router /admin/permissions [post]
+func (h *PermissionHandler) CreatePermission(ctx *gin.Context) {
+	req := new(v1.CreatePermissionRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.permissionService.CreatePermission(ctx, req)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "permissionService.CreatePermission error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// UpdatePermission godoc
+// @Summary 更新权限
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "权限ID"
+// @Param request body v1.UpdatePermissionRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/permissions/{id} [put]
+func (h *PermissionHandler) UpdatePermission(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.UpdatePermissionRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.permissionService.UpdatePermission(ctx, uint(id), req); err != nil {
+		handleRBACError(ctx, h.logger, "permissionService.UpdatePermission error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
+
+// DeletePermission godoc
+// @Summary 删除权限
+// @Description 同时从所有角色收回该权限，有子级的权限不能删除
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "权限ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/permissions/{id} [delete]
+func (h *PermissionHandler) DeletePermission(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.permissionService.DeletePermission(ctx, uint(id)); err != nil {
+		handleRBACError(ctx, h.logger, "permissionService.DeletePermission error", err)
+		return
+	}
 
+	v1.HandleSuccess(ctx, nil)
 }
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RankingHandler struct {
+	*Handler
+	rankingService service.RankingService
+}
+
+func NewRankingHandler(handler *Handler, rankingService service.RankingService) *RankingHandler {
+	return &RankingHandler{
+		Handler:        handler,
+		rankingService: rankingService,
+	}
+}
+
+// GetRanking godoc
+// @Summary 获取榜单
+// @Tags 榜单模块
+// @Accept json
+// @Produce json
+// @Param board path string true "榜单类型(hot/downloads/rating/newest)"
+// @Param period query string false "榜单周期(daily/weekly/monthly/all)，默认 weekly"
+// @Param limit query int false "数量，默认 20，最大 100"
+// @Success 200 {object} v1.GetRankingResponse
+// @Router /rankings/{board} [get]
+func (h *RankingHandler) GetRanking(ctx *gin.Context) {
+	period := ctx.DefaultQuery("period", v1.RankingPeriodWeekly)
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	ranking, err := h.rankingService.GetRanking(ctx, ctx.Param("board"), period, limit)
+	if err != nil {
+		if errors.Is(err, v1.ErrBadRequest) {
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("rankingService.GetRanking error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, ranking)
+}
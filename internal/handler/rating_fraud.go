@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RatingFraudHandler struct {
+	*Handler
+	ratingFraudService service.RatingFraudService
+}
+
+func NewRatingFraudHandler(handler *Handler, ratingFraudService service.RatingFraudService) *RatingFraudHandler {
+	return &RatingFraudHandler{
+		Handler:            handler,
+		ratingFraudService: ratingFraudService,
+	}
+}
+
+// ListRatingFlags godoc
+// @Summary 获取可疑评分列表
+// @Tags 评分反作弊模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param status query string false "复核状态(pending/cleared/removed)，默认 pending"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} v1.ListRatingFlagsResponse
+// @Router /admin/rating-flags [get]
+func (h *RatingFraudHandler) ListRatingFlags(ctx *gin.Context) {
+	req := new(v1.ListRatingFlagsRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.ratingFraudService.ListRatingFlags(ctx, req)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("ratingFraudService.ListRatingFlags error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// RemoveFlaggedRatings godoc
+// @Summary 批量删除可疑评分
+// @Tags 评分反作弊模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.ReviewRatingFlagsRequest true "params"
+// @Success 200 {object} v1.ReviewRatingFlagsResponse
+// @Router /admin/rating-flags/remove [post]
+func (h *RatingFraudHandler) RemoveFlaggedRatings(ctx *gin.Context) {
+	req := new(v1.ReviewRatingFlagsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.ratingFraudService.RemoveFlaggedRatings(ctx, GetUserIdFromCtx(ctx), req)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("ratingFraudService.RemoveFlaggedRatings error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// ClearRatingFlags godoc
+// @Summary 批量恢复误判的可疑评分
+// @Tags 评分反作弊模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.ReviewRatingFlagsRequest true "params"
+// @Success 200 {object} v1.ReviewRatingFlagsResponse
+// @Router /admin/rating-flags/clear [post]
+func (h *RatingFraudHandler) ClearRatingFlags(ctx *gin.Context) {
+	req := new(v1.ReviewRatingFlagsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.ratingFraudService.ClearRatingFlags(ctx, GetUserIdFromCtx(ctx), req)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("ratingFraudService.ClearRatingFlags error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
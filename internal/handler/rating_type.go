@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/middleware"
 	"novel-site-backend/internal/service"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type RatingTypeHandler struct {
@@ -26,77 +29,112 @@ func NewRatingTypeHandler(handler *Handler, ratingTypeService service.RatingType
 // @Tags 评分类型模块
 // @Accept json
 // @Produce json
+// @Security Bearer
 // @Param request body v1.CreateRatingTypeRequest true "params"
-// @Success 200 {object} v1.Response
-// @Router /rating-types [post]
-// func (h *RatingTypeHandler) CreateRatingType(ctx *gin.Context) {
-// 	req := new(v1.CreateRatingTypeRequest)
-// 	if err := ctx.ShouldBindJSON(req); err != nil {
-// 		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
-// 		return
-// 	}
-
-// 	if err := h.ratingTypeService.CreateRatingType(ctx, req); err != nil {
-// 		h.logger.WithContext(ctx).Error("ratingTypeService.CreateRatingType error", zap.Error(err))
-// 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
-// 		return
-// 	}
-
-// 	v1.HandleSuccess(ctx, nil)
-// }
+// @Success 200 {object} v1.CreateRatingTypeResponse
+// @Router /admin/rating-types [post]
+func (h *RatingTypeHandler) CreateRatingType(ctx *gin.Context) {
+	req := new(v1.CreateRatingTypeRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.ratingTypeService.CreateRatingType(ctx, req)
+	if err != nil {
+		if errors.Is(err, v1.ErrRatingLevelExists) {
+			v1.HandleError(ctx, http.StatusConflict, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("ratingTypeService.CreateRatingType error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
 
 // UpdateRatingType godoc
 // @Summary 更新评分类型
 // @Tags 评分类型模块
 // @Accept json
 // @Produce json
+// @Security Bearer
 // @Param id path int true "评分类型ID"
 // @Param request body v1.UpdateRatingTypeRequest true "params"
 // @Success 200 {object} v1.Response
-// @Router /rating-types/{id} [put]
-// func (h *RatingTypeHandler) UpdateRatingType(ctx *gin.Context) {
-// 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-// 	if err != nil {
-// 		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
-// 		return
-// 	}
-
-// 	req := new(v1.UpdateRatingTypeRequest)
-// 	if err := ctx.ShouldBindJSON(req); err != nil {
-// 		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
-// 		return
-// 	}
-
-// 	if err := h.ratingTypeService.UpdateRatingType(ctx, uint(id), req); err != nil {
-// 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
-// 		return
-// 	}
-
-// 	v1.HandleSuccess(ctx, nil)
-// }
+// @Router /admin/rating-types/{id} [put]
+func (h *RatingTypeHandler) UpdateRatingType(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.UpdateRatingTypeRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.ratingTypeService.UpdateRatingType(ctx, uint(id), req); err != nil {
+		switch {
+		case errors.Is(err, v1.ErrNotFound):
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+		case errors.Is(err, v1.ErrRatingLevelExists):
+			v1.HandleError(ctx, http.StatusConflict, err, nil)
+		default:
+			h.logger.WithContext(ctx).Error("ratingTypeService.UpdateRatingType error", zap.Error(err))
+			v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		}
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
 
 // DeleteRatingType godoc
 // @Summary 删除评分类型
+// @Description 已有评分时必须通过 migrate_to 指定迁移到的评分类型，迁移和删除在同一事务内完成
 // @Tags 评分类型模块
 // @Accept json
 // @Produce json
+// @Security Bearer
 // @Param id path int true "评分类型ID"
-// @Success 200 {object} v1.Response
-// @Router /rating-types/{id} [delete]
-// func (h *RatingTypeHandler) DeleteRatingType(ctx *gin.Context) {
-// 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-// 	if err != nil {
-// 		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
-// 		return
-// 	}
-
-// 	if err := h.ratingTypeService.DeleteRatingType(ctx, uint(id)); err != nil {
-// 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
-// 		return
-// 	}
-
-// 	v1.HandleSuccess(ctx, nil)
-// }
+// @Param migrate_to query int false "迁移到的评分类型ID"
+// @Success 200 {object} v1.DeleteRatingTypeResponse
+// @Router /admin/rating-types/{id} [delete]
+func (h *RatingTypeHandler) DeleteRatingType(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.DeleteRatingTypeRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.ratingTypeService.DeleteRatingType(ctx, uint(id), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, v1.ErrNotFound):
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+		case errors.Is(err, v1.ErrBadRequest):
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		case errors.Is(err, v1.ErrRatingTypeMigrationRequired):
+			v1.HandleError(ctx, http.StatusConflict, err, nil)
+		default:
+			h.logger.WithContext(ctx).Error("ratingTypeService.DeleteRatingType error", zap.Error(err))
+			v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		}
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
 
 // GetRatingType godoc
 // @Summary 获取评分类型详情
@@ -106,21 +144,26 @@ func NewRatingTypeHandler(handler *Handler, ratingTypeService service.RatingType
 // @Param id path int true "评分类型ID"
 // @Success 200 {object} v1.RatingTypeResponse
 // @Router /rating-types/{id} [get]
-// func (h *RatingTypeHandler) GetRatingType(ctx *gin.Context) {
-// 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
-// 	if err != nil {
-// 		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
-// 		return
-// 	}
-
-// 	ratingType, err := h.ratingTypeService.GetRatingType(ctx, uint(id))
-// 	if err != nil {
-// 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
-// 		return
-// 	}
-
-// 	v1.HandleSuccess(ctx, ratingType)
-// }
+func (h *RatingTypeHandler) GetRatingType(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	ratingType, err := h.ratingTypeService.GetRatingType(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+			return
+		}
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	middleware.SetLastModified(ctx, ratingType.UpdatedAt)
+	v1.HandleSuccess(ctx, ratingType)
+}
 
 // ListRatingTypes godoc
 // @Summary 获取评分类型列表
@@ -141,5 +184,6 @@ func (h *RatingTypeHandler) ListRatingTypes(ctx *gin.Context) {
 		return
 	}
 
+	// 删除评分类型不会更新其余类型的修改时间，列表不设置 Last-Modified，只依赖按响应内容计算的 ETag
 	v1.HandleSuccess(ctx, ratingTypes)
 }
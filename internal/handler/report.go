@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/middleware"
+	"novel-site-backend/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ReportHandler struct {
+	*Handler
+	reportService service.ReportService
+}
+
+func NewReportHandler(handler *Handler, reportService service.ReportService) *ReportHandler {
+	return &ReportHandler{
+		Handler:       handler,
+		reportService: reportService,
+	}
+}
+
+// CreateReport godoc
+// @Summary 举报
+// @Tags 举报模块
+// @Accept json
+// @Produce json
+// @Description 举报图书、评论或章节，同一举报人对同一对象只能举报一次
+// @Param request body v1.CreateReportRequest true "params"
+// @Success 200 {object} v1.CreateReportResponse
+// @Failure 404 {object} v1.Response
+// @Failure 409 {object} v1.Response
+// @Failure 429 {object} v1.Response
+// @Router /reports [post]
+func (h *ReportHandler) CreateReport(ctx *gin.Context) {
+	req := new(v1.CreateReportRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+	req.IP = middleware.GetClientIP(ctx)
+	req.UserId = GetUserIdFromCtx(ctx)
+	req.VisitorId = middleware.GetVisitorId(ctx)
+
+	resp, err := h.reportService.CreateReport(ctx, req)
+	if err != nil {
+		h.handleReportError(ctx, "reportService.CreateReport", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// ListReportTargets godoc
+// @Summary 获取举报处理列表
+// @Tags 举报模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Description 按举报对象汇总，举报数多的排在前面
+// @Param status query string false "举报状态(open/resolved/dismissed)，默认 open"
+// @Param target_type query string false "举报对象类型(book/review/chapter)"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} v1.ListReportTargetsResponse
+// @Router /admin/reports [get]
+func (h *ReportHandler) ListReportTargets(ctx *gin.Context) {
+	req := new(v1.ListReportTargetsRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.reportService.ListReportTargets(ctx, req)
+	if err != nil {
+		h.handleReportError(ctx, "reportService.ListReportTargets", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// GetReportTarget godoc
+// @Summary 获取举报对象的举报和处理记录
+// @Tags 举报模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param target_type path string true "举报对象类型(book/review/chapter)"
+// @Param target_id path int true "举报对象ID"
+// @Success 200 {object} v1.GetReportTargetResponse
+// @Router /admin/reports/{target_type}/{target_id} [get]
+func (h *ReportHandler) GetReportTarget(ctx *gin.Context) {
+	targetType, targetId, ok := parseReportTarget(ctx)
+	if !ok {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.reportService.GetReportTarget(ctx, targetType, targetId)
+	if err != nil {
+		h.handleReportError(ctx, "reportService.GetReportTarget", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// ResolveReports godoc
+// @Summary 处理举报
+// @Tags 举报模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Description 一次处理某个对象的全部待处理举报
+// @Param target_type path string true "举报对象类型(book/review/chapter)"
+// @Param target_id path int true "举报对象ID"
+// @Param request body v1.ResolveReportsRequest true "params"
+// @Success 200 {object} v1.ResolveReportsResponse
+// @Router /admin/reports/{target_type}/{target_id}/resolve [post]
+func (h *ReportHandler) ResolveReports(ctx *gin.Context) {
+	targetType, targetId, ok := parseReportTarget(ctx)
+	if !ok {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.ResolveReportsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.reportService.ResolveReports(ctx, targetType, targetId, GetUserIdFromCtx(ctx), req)
+	if err != nil {
+		h.handleReportError(ctx, "reportService.ResolveReports", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+func parseReportTarget(ctx *gin.Context) (string, uint, bool) {
+	targetType := ctx.Param("target_type")
+	targetId, err := strconv.ParseUint(ctx.Param("target_id"), 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	return targetType, uint(targetId), true
+}
+
+func (h *ReportHandler) handleReportError(ctx *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, v1.ErrBadRequest):
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+	case errors.Is(err, v1.ErrNotFound):
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+	case errors.Is(err, v1.ErrReportDuplicate):
+		v1.HandleError(ctx, http.StatusConflict, err, nil)
+	case errors.Is(err, v1.ErrReportLimitExceeded):
+		v1.HandleError(ctx, http.StatusTooManyRequests, err, nil)
+	default:
+		h.logger.WithContext(ctx).Error(op+" error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+	}
+}
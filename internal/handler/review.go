@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/middleware"
+	"novel-site-backend/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ReviewHandler struct {
+	*Handler
+	reviewService service.ReviewService
+}
+
+func NewReviewHandler(handler *Handler, reviewService service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{
+		Handler:       handler,
+		reviewService: reviewService,
+	}
+}
+
+// CreateReply godoc
+// @Summary 回复评论
+// @Tags 评论互动模块
+// @Accept json
+// @Produce json
+// @Description 回复最多两层，回复二级回复时挂到其所属的一级回复下
+// @Param id path int true "评论(评分)ID"
+// @Param request body v1.CreateReviewReplyRequest true "params"
+// @Success 200 {object} v1.CreateReviewReplyResponse
+// @Failure 404 {object} v1.Response
+// @Failure 422 {object} v1.Response
+// @Router /reviews/{id}/replies [post]
+func (h *ReviewHandler) CreateReply(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.CreateReviewReplyRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+	req.IP = middleware.GetClientIP(ctx)
+	req.UserId = GetUserIdFromCtx(ctx)
+	req.VisitorId = middleware.GetVisitorId(ctx)
+
+	resp, err := h.reviewService.CreateReply(ctx, uint(id), req)
+	if err != nil {
+		h.handleReviewError(ctx, "reviewService.CreateReply", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// Like godoc
+// @Summary 点赞评论
+// @Tags 评论互动模块
+// @Accept json
+// @Produce json
+// @Description 每个登录用户或匿名访客对同一条评论只计一次点赞，同一IP的点赞数有上限
+// @Param id path int true "评论(评分)ID"
+// @Success 200 {object} v1.ReviewLikeResponse
+// @Failure 429 {object} v1.Response
+// @Router /reviews/{id}/like [post]
+func (h *ReviewHandler) Like(ctx *gin.Context) {
+	h.toggleLike(ctx, true)
+}
+
+// Unlike godoc
+// @Summary 取消点赞评论
+// @Tags 评论互动模块
+// @Accept json
+// @Produce json
+// @Param id path int true "评论(评分)ID"
+// @Success 200 {object} v1.ReviewLikeResponse
+// @Router /reviews/{id}/like [delete]
+func (h *ReviewHandler) Unlike(ctx *gin.Context) {
+	h.toggleLike(ctx, false)
+}
+
+func (h *ReviewHandler) toggleLike(ctx *gin.Context, like bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := &v1.ReviewLikeRequest{
+		IP:        middleware.GetClientIP(ctx),
+		UserId:    GetUserIdFromCtx(ctx),
+		VisitorId: middleware.GetVisitorId(ctx),
+	}
+
+	var resp *v1.ReviewLikeResponse
+	if like {
+		resp, err = h.reviewService.Like(ctx, uint(id), req)
+	} else {
+		resp, err = h.reviewService.Unlike(ctx, uint(id), req)
+	}
+	if err != nil {
+		h.handleReviewError(ctx, "reviewService.toggleLike", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// GetThread godoc
+// @Summary 获取评论及回复
+// @Tags 评论互动模块
+// @Accept json
+// @Produce json
+// @Param id path int true "评论(评分)ID"
+// @Success 200 {object} v1.GetReviewThreadResponse
+// @Router /reviews/{id} [get]
+func (h *ReviewHandler) GetThread(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.reviewService.GetThread(ctx, uint(id))
+	if err != nil {
+		h.handleReviewError(ctx, "reviewService.GetThread", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// ListReplyQueue godoc
+// @Summary 获取回复审核队列
+// @Tags 评论审核模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param status query string false "审核状态(pending/approved/rejected)，默认 pending"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} v1.ListReplyQueueResponse
+// @Router /admin/replies [get]
+func (h *ReviewHandler) ListReplyQueue(ctx *gin.Context) {
+	req := new(v1.ListReviewQueueRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.reviewService.ListReplyQueue(ctx, req)
+	if err != nil {
+		h.handleReviewError(ctx, "reviewService.ListReplyQueue", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// ModerateReplies godoc
+// @Summary 批量审核回复
+// @Tags 评论审核模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.ModerateReviewsRequest true "params"
+// @Success 200 {object} v1.ModerateReviewsResponse
+// @Router /admin/replies/moderate [post]
+func (h *ReviewHandler) ModerateReplies(ctx *gin.Context) {
+	req := new(v1.ModerateReviewsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.reviewService.ModerateReplies(ctx, req)
+	if err != nil {
+		h.handleReviewError(ctx, "reviewService.ModerateReplies", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+func (h *ReviewHandler) handleReviewError(ctx *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, v1.ErrBadRequest):
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+	case errors.Is(err, v1.ErrNotFound):
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+	case errors.Is(err, v1.ErrCommentRejected):
+		v1.HandleError(ctx, http.StatusUnprocessableEntity, err, nil)
+	case errors.Is(err, v1.ErrLikeLimitExceeded):
+		v1.HandleError(ctx, http.StatusTooManyRequests, err, nil)
+	default:
+		h.logger.WithContext(ctx).Error(op+" error", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+	}
+}
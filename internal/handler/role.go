@@ -1,8 +1,15 @@
 package handler
 
 import (
-	"github.com/gin-gonic/gin"
+	"errors"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/service"
+	"novel-site-backend/pkg/log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type RoleHandler struct {
@@ -11,15 +18,160 @@ type RoleHandler struct {
 }
 
 func NewRoleHandler(
-    handler *Handler,
-    roleService service.RoleService,
+	handler *Handler,
+	roleService service.RoleService,
 ) *RoleHandler {
 	return &RoleHandler{
-		Handler:      handler,
+		Handler:     handler,
 		roleService: roleService,
 	}
 }
 
+// ListRoles godoc
+// @Summary 获取角色列表
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} v1.ListRolesResponse
+// @Router /admin/roles [get]
+func (h *RoleHandler) ListRoles(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	resp, err := h.roleService.ListRoles(ctx, page, pageSize)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "roleService.ListRoles error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// GetRole godoc
+// @Summary 获取角色详情
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "角色ID"
+// @Success 200 {object} v1.RoleResponse
+// @Router /admin/roles/{id} [get]
 func (h *RoleHandler) GetRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.roleService.GetRole(ctx, uint(id))
+	if err != nil {
+		handleRBACError(ctx, h.logger, "roleService.GetRole error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// CreateRole godoc
+// @Summary 创建角色
+// @Description 编码与已删除的角色相同时恢复该角色；非管理员只能授予自己拥有的权限
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.CreateRoleRequest true "params"
+// @Success 200 {object} v1.CreateRoleResponse
+// @Router /admin/roles [post]
+func (h *RoleHandler) CreateRole(ctx *gin.Context) {
+	req := new(v1.CreateRoleRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.roleService.CreateRole(ctx, GetUserIdFromCtx(ctx), req)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "roleService.CreateRole error", err)
+		return
+	}
 
+	v1.HandleSuccess(ctx, resp)
+}
+
+// UpdateRole godoc
+// @Summary 更新角色
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "角色ID"
+// @Param request body v1.UpdateRoleRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/roles/{id} [put]
+func (h *RoleHandler) UpdateRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.UpdateRoleRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.roleService.UpdateRole(ctx, uint(id), req); err != nil {
+		handleRBACError(ctx, h.logger, "roleService.UpdateRole error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
+
+// DeleteRole godoc
+// @Summary 删除角色
+// @Description 仍有用户的角色和管理员角色不能删除
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "角色ID"
+// @Success 200 {object} v1.Response
+// @Router /admin/roles/{id} [delete]
+func (h *RoleHandler) DeleteRole(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.roleService.DeleteRole(ctx, uint(id)); err != nil {
+		handleRBACError(ctx, h.logger, "roleService.DeleteRole error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
+
+// handleRBACError 角色和权限管理接口共用的错误处理
+func handleRBACError(ctx *gin.Context, logger *log.Logger, msg string, err error) {
+	switch {
+	case errors.Is(err, v1.ErrBadRequest), errors.Is(err, v1.ErrInvalidPermissionParent):
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+	case errors.Is(err, v1.ErrNotFound):
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+	case errors.Is(err, v1.ErrBuiltinRole), errors.Is(err, v1.ErrPermissionNotHeld), errors.Is(err, v1.ErrAdminRoleRequired):
+		v1.HandleError(ctx, http.StatusForbidden, err, nil)
+	case errors.Is(err, v1.ErrRoleExists), errors.Is(err, v1.ErrRoleInUse),
+		errors.Is(err, v1.ErrPermissionExists), errors.Is(err, v1.ErrPermissionHasChildren),
+		errors.Is(err, v1.ErrLastAdmin):
+		v1.HandleError(ctx, http.StatusConflict, err, nil)
+	default:
+		logger.WithContext(ctx).Error(msg, zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+	}
 }
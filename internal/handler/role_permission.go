@@ -1,8 +1,12 @@
 package handler
 
 import (
-	"github.com/gin-gonic/gin"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
 )
 
 type RolePermissionHandler struct {
@@ -11,15 +15,110 @@ type RolePermissionHandler struct {
 }
 
 func NewRolePermissionHandler(
-    handler *Handler,
-    rolePermissionService service.RolePermissionService,
+	handler *Handler,
+	rolePermissionService service.RolePermissionService,
 ) *RolePermissionHandler {
 	return &RolePermissionHandler{
-		Handler:      handler,
+		Handler:               handler,
 		rolePermissionService: rolePermissionService,
 	}
 }
 
-func (h *RolePermissionHandler) GetRolePermission(ctx *gin.Context) {
+// GrantPermissions godoc
+// @Summary 给角色授予权限
+// @Description 批量授予，角色已有的权限跳过；只有管理员可以修改管理员角色，其他人只能授予自己拥有的权限
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "角色ID"
+// @Param request body v1.RolePermissionsRequest true "params"
+// @Success 200 {object} v1.BatchUpdateResponse
+// @Router /admin/roles/{id}/permissions [post]
+func (h *RolePermissionHandler) GrantPermissions(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.RolePermissionsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.rolePermissionService.GrantPermissions(ctx, GetUserIdFromCtx(ctx), uint(id), req)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "rolePermissionService.GrantPermissions error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// RevokePermissions godoc
+// @Summary 收回角色的权限
+// @Description 批量收回，角色没有的权限跳过；只有管理员可以修改管理员角色
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "角色ID"
+// @Param request body v1.RolePermissionsRequest true "params"
+// @Success 200 {object} v1.BatchUpdateResponse
+// @Router /admin/roles/{id}/permissions/revoke [post]
+func (h *RolePermissionHandler) RevokePermissions(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.RolePermissionsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.rolePermissionService.RevokePermissions(ctx, GetUserIdFromCtx(ctx), uint(id), req)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "rolePermissionService.RevokePermissions error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// SetPermissions godoc
+// @Summary 设置角色的权限
+// @Description 用给定的权限替换角色现有的权限，空列表表示清空；只有管理员可以修改管理员角色，其他人新增的权限必须是自己拥有的
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "角色ID"
+// @Param request body v1.SetRolePermissionsRequest true "params"
+// @Success 200 {object} v1.BatchUpdateResponse
+// @Router /admin/roles/{id}/permissions [put]
+func (h *RolePermissionHandler) SetPermissions(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req := new(v1.SetRolePermissionsRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.rolePermissionService.SetPermissions(ctx, GetUserIdFromCtx(ctx), uint(id), req)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "rolePermissionService.SetPermissions error", err)
+		return
+	}
 
+	v1.HandleSuccess(ctx, resp)
 }
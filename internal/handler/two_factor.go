@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/middleware"
+	"novel-site-backend/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TwoFactorHandler struct {
+	*Handler
+	twoFactorService service.TwoFactorService
+}
+
+func NewTwoFactorHandler(handler *Handler, twoFactorService service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		Handler:          handler,
+		twoFactorService: twoFactorService,
+	}
+}
+
+// Setup 生成 TOTP 密钥和二维码地址，确认验证码后才会启用
+func (h *TwoFactorHandler) Setup(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	resp, err := h.twoFactorService.Setup(ctx, userId)
+	if err != nil {
+		h.handleError(ctx, "生成两步验证密钥失败", userId, err)
+		return
+	}
+	v1.HandleSuccess(ctx, resp)
+}
+
+// Enable 校验验证码后启用两步验证，返回恢复码
+func (h *TwoFactorHandler) Enable(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+	var req v1.EnableTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.twoFactorService.Enable(ctx, userId, &req)
+	if err != nil {
+		h.handleError(ctx, "启用两步验证失败", userId, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("启用两步验证", zap.String("用户ID", userId))
+	v1.HandleSuccess(ctx, resp)
+}
+
+// Disable 校验密码和验证码后关闭两步验证
+func (h *TwoFactorHandler) Disable(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+	var req v1.DisableTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req.IP = middleware.GetClientIP(ctx)
+	if err := h.twoFactorService.Disable(ctx, userId, &req); err != nil {
+		h.handleError(ctx, "关闭两步验证失败", userId, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("关闭两步验证", zap.String("用户ID", userId))
+	v1.HandleSuccess(ctx, nil)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，原有恢复码作废
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+	var req v1.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req.IP = middleware.GetClientIP(ctx)
+	resp, err := h.twoFactorService.RegenerateRecoveryCodes(ctx, userId, &req)
+	if err != nil {
+		h.handleError(ctx, "重新生成恢复码失败", userId, err)
+		return
+	}
+	v1.HandleSuccess(ctx, resp)
+}
+
+// LoginSetup 登录时为必须启用两步验证的用户生成 TOTP 密钥
+func (h *TwoFactorHandler) LoginSetup(ctx *gin.Context) {
+	var req v1.LoginTwoFactorSetupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.twoFactorService.SetupLogin(ctx, &req)
+	if err != nil {
+		h.handleError(ctx, "生成两步验证密钥失败", "", err)
+		return
+	}
+	v1.HandleSuccess(ctx, resp)
+}
+
+// LoginVerify 登录第二步，校验验证码或恢复码后签发令牌
+func (h *TwoFactorHandler) LoginVerify(ctx *gin.Context) {
+	var req v1.LoginTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	req.IP = middleware.GetClientIP(ctx)
+	data, err := h.twoFactorService.CompleteLogin(ctx, &req)
+	if err != nil {
+		if handleBannedError(ctx, err) {
+			return
+		}
+		h.handleError(ctx, "两步验证登录失败", "", err)
+		return
+	}
+	v1.HandleSuccess(ctx, data)
+}
+
+func (h *TwoFactorHandler) handleError(ctx *gin.Context, msg, userId string, err error) {
+	var locked *service.LoginLockedError
+	if errors.As(err, &locked) {
+		seconds := int64(math.Ceil(locked.RetryAfter.Seconds()))
+		ctx.Header("Retry-After", strconv.FormatInt(seconds, 10))
+		v1.HandleError(ctx, http.StatusTooManyRequests, v1.ErrLoginLocked, map[string]interface{}{"retryAfter": seconds})
+		return
+	}
+	switch {
+	case errors.Is(err, v1.ErrNotFound):
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+	case errors.Is(err, v1.ErrInvalidTwoFactorCode), errors.Is(err, v1.ErrInvalidCredentials),
+		errors.Is(err, v1.ErrInvalidLoginChallenge):
+		v1.HandleError(ctx, http.StatusUnauthorized, err, nil)
+	case errors.Is(err, v1.ErrTwoFactorNotSetup), errors.Is(err, v1.ErrTwoFactorAlreadyEnabled):
+		v1.HandleError(ctx, http.StatusConflict, err, nil)
+	case errors.Is(err, v1.ErrTwoFactorRequired):
+		v1.HandleError(ctx, http.StatusForbidden, err, nil)
+	default:
+		h.logger.WithContext(ctx).Error(msg, zap.String("用户ID", userId), zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+	}
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/middleware"
 	"novel-site-backend/internal/service"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -11,13 +15,15 @@ import (
 
 type UserHandler struct {
 	*Handler
-	userService service.UserService
+	userService  service.UserService
+	tokenService service.TokenService
 }
 
-func NewUserHandler(handler *Handler, userService service.UserService) *UserHandler {
+func NewUserHandler(handler *Handler, userService service.UserService, tokenService service.TokenService) *UserHandler {
 	return &UserHandler{
-		Handler:     handler,
-		userService: userService,
+		Handler:      handler,
+		userService:  userService,
+		tokenService: tokenService,
 	}
 }
 
@@ -34,14 +40,23 @@ func (h *UserHandler) Register(ctx *gin.Context) {
 	}
 
 	if err := h.userService.Register(ctx, req); err != nil {
-		h.logger.WithContext(ctx).Error("用户注册失败",
-			zap.String("邮箱", req.Email),
-			zap.Error(err))
-		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		switch {
+		case errors.Is(err, v1.ErrInvalidUsername):
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		case errors.Is(err, v1.ErrUsernameAlreadyUse), errors.Is(err, v1.ErrEmailAlreadyUse):
+			v1.HandleError(ctx, http.StatusConflict, err, nil)
+		default:
+			h.logger.WithContext(ctx).Error("用户注册失败",
+				zap.String("用户名", req.Username),
+				zap.String("邮箱", req.Email),
+				zap.Error(err))
+			v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		}
 		return
 	}
 
 	h.logger.WithContext(ctx).Info("用户注册成功",
+		zap.String("用户名", req.Username),
 		zap.String("邮箱", req.Email))
 	v1.HandleSuccess(ctx, nil)
 }
@@ -49,7 +64,7 @@ func (h *UserHandler) Register(ctx *gin.Context) {
 // Login 处理用户登录请求
 // 1. 验证登录参数
 // 2. 调用service层验证用户身份并生成token
-// 3. 返回token
+// 3. 返回访问令牌和刷新令牌
 func (h *UserHandler) Login(ctx *gin.Context) {
 	var req v1.LoginRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -58,18 +73,102 @@ func (h *UserHandler) Login(ctx *gin.Context) {
 		return
 	}
 
-	token, err := h.userService.Login(ctx, &req)
+	req.IP = middleware.GetClientIP(ctx)
+	data, err := h.userService.Login(ctx, &req)
 	if err != nil {
+		var locked *service.LoginLockedError
+		if errors.As(err, &locked) {
+			h.logger.WithContext(ctx).Warn("登录尝试被限制",
+				zap.String("账号", req.Account),
+				zap.String("IP", req.IP))
+			seconds := int64(math.Ceil(locked.RetryAfter.Seconds()))
+			ctx.Header("Retry-After", strconv.FormatInt(seconds, 10))
+			v1.HandleError(ctx, http.StatusTooManyRequests, v1.ErrLoginLocked, map[string]interface{}{"retryAfter": seconds})
+			return
+		}
+		if handleBannedError(ctx, err) {
+			h.logger.WithContext(ctx).Warn("被封禁的用户尝试登录",
+				zap.String("账号", req.Account))
+			return
+		}
+		if errors.Is(err, v1.ErrInvalidCredentials) {
+			h.logger.WithContext(ctx).Warn("用户名或密码错误",
+				zap.String("账号", req.Account))
+			v1.HandleError(ctx, http.StatusUnauthorized, err, nil)
+			return
+		}
 		h.logger.WithContext(ctx).Error("用户登录失败",
-			zap.String("邮箱", req.Email),
+			zap.String("账号", req.Account),
 			zap.Error(err))
-		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
 		return
 	}
 
 	h.logger.WithContext(ctx).Info("用户登录成功",
-		zap.String("邮箱", req.Email))
-	v1.HandleSuccess(ctx, v1.LoginResponseData{AccessToken: token})
+		zap.String("账号", req.Account))
+	v1.HandleSuccess(ctx, data)
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌
+// 刷新令牌只能使用一次，重复使用会导致该次登录签发的全部令牌失效
+func (h *UserHandler) RefreshToken(ctx *gin.Context) {
+	var req v1.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.WithContext(ctx).Warn("刷新令牌参数无效", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.tokenService.Refresh(ctx, &req)
+	if err != nil {
+		if errors.Is(err, v1.ErrInvalidRefreshToken) {
+			v1.HandleError(ctx, http.StatusUnauthorized, err, nil)
+			return
+		}
+		if handleBannedError(ctx, err) {
+			return
+		}
+		h.logger.WithContext(ctx).Error("刷新令牌失败", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, data)
+}
+
+// Logout 注销当前会话，访问令牌和刷新令牌立即失效
+func (h *UserHandler) Logout(ctx *gin.Context) {
+	claims := GetClaimsFromCtx(ctx)
+	if claims == nil {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	var req v1.LogoutRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			h.logger.WithContext(ctx).Warn("注销参数无效", zap.Error(err))
+			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+			return
+		}
+	}
+
+	if err := h.tokenService.Logout(ctx, claims, &req); err != nil {
+		if errors.Is(err, v1.ErrForbidden) {
+			v1.HandleError(ctx, http.StatusForbidden, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("注销失败",
+			zap.String("用户ID", claims.UserId),
+			zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		return
+	}
+
+	h.logger.WithContext(ctx).Info("用户注销成功",
+		zap.String("用户ID", claims.UserId),
+		zap.Bool("全部会话", req.All))
+	v1.HandleSuccess(ctx, nil)
 }
 
 // GetProfile 获取用户信息
@@ -86,10 +185,14 @@ func (h *UserHandler) GetProfile(ctx *gin.Context) {
 
 	user, err := h.userService.GetProfile(ctx, userId)
 	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+			return
+		}
 		h.logger.WithContext(ctx).Error("获取用户信息失败",
 			zap.String("用户ID", userId),
 			zap.Error(err))
-		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
 		return
 	}
 
@@ -118,6 +221,14 @@ func (h *UserHandler) UpdateProfile(ctx *gin.Context) {
 	}
 
 	if err := h.userService.UpdateProfile(ctx, userId, &req); err != nil {
+		switch {
+		case errors.Is(err, v1.ErrNotFound):
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+			return
+		case errors.Is(err, v1.ErrEmailAlreadyUse):
+			v1.HandleError(ctx, http.StatusConflict, err, nil)
+			return
+		}
 		h.logger.WithContext(ctx).Error("更新用户信息失败",
 			zap.String("用户ID", userId),
 			zap.Error(err))
@@ -129,3 +240,88 @@ func (h *UserHandler) UpdateProfile(ctx *gin.Context) {
 		zap.String("用户ID", userId))
 	v1.HandleSuccess(ctx, nil)
 }
+
+// SendVerificationEmail 重新发送邮箱验证邮件
+func (h *UserHandler) SendVerificationEmail(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	if err := h.userService.SendVerificationEmail(ctx, userId); err != nil {
+		switch {
+		case errors.Is(err, v1.ErrNotFound):
+			v1.HandleError(ctx, http.StatusNotFound, err, nil)
+		case errors.Is(err, v1.ErrEmailAlreadyVerified):
+			v1.HandleError(ctx, http.StatusConflict, err, nil)
+		case errors.Is(err, v1.ErrMailTooFrequent):
+			v1.HandleError(ctx, http.StatusTooManyRequests, err, nil)
+		default:
+			h.logger.WithContext(ctx).Error("发送验证邮件失败",
+				zap.String("用户ID", userId),
+				zap.Error(err))
+			v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		}
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
+
+// VerifyEmail 使用验证邮件中的令牌完成邮箱验证
+func (h *UserHandler) VerifyEmail(ctx *gin.Context) {
+	var req v1.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.WithContext(ctx).Warn("邮箱验证参数无效", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.VerifyEmail(ctx, &req); err != nil {
+		if errors.Is(err, v1.ErrInvalidUserToken) {
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("邮箱验证失败", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
+
+// ForgotPassword 发送重置密码邮件，无论邮箱是否已注册都返回成功
+func (h *UserHandler) ForgotPassword(ctx *gin.Context) {
+	var req v1.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.WithContext(ctx).Warn("找回密码参数无效", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	h.userService.ForgotPassword(ctx, &req)
+	v1.HandleSuccess(ctx, nil)
+}
+
+// ResetPassword 使用重置密码邮件中的令牌设置新密码
+func (h *UserHandler) ResetPassword(ctx *gin.Context) {
+	var req v1.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.WithContext(ctx).Warn("重置密码参数无效", zap.Error(err))
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userService.ResetPassword(ctx, &req); err != nil {
+		if errors.Is(err, v1.ErrInvalidUserToken) {
+			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+			return
+		}
+		h.logger.WithContext(ctx).Error("重置密码失败", zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
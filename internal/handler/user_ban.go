@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type UserBanHandler struct {
+	*Handler
+	userBanService service.UserBanService
+}
+
+func NewUserBanHandler(
+	handler *Handler,
+	userBanService service.UserBanService,
+) *UserBanHandler {
+	return &UserBanHandler{
+		Handler:        handler,
+		userBanService: userBanService,
+	}
+}
+
+// BanUser godoc
+// @Summary 封禁用户
+// @Description 封禁后用户无法登录，已签发的令牌立即失效；不填截止时间表示永久封禁，不能封禁自己和管理员
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param user_id path string true "用户ID"
+// @Param request body v1.BanUserRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{user_id}/ban [post]
+func (h *UserBanHandler) BanUser(ctx *gin.Context) {
+	req := new(v1.BanUserRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.userBanService.BanUser(ctx, GetUserIdFromCtx(ctx), ctx.Param("user_id"), req); err != nil {
+		h.handleError(ctx, "封禁用户失败", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
+
+// UnbanUser godoc
+// @Summary 解封用户
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param user_id path string true "用户ID"
+// @Param request body v1.UnbanUserRequest true "params"
+// @Success 200 {object} v1.Response
+// @Router /admin/users/{user_id}/unban [post]
+func (h *UserBanHandler) UnbanUser(ctx *gin.Context) {
+	req := new(v1.UnbanUserRequest)
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(req); err != nil {
+			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+			return
+		}
+	}
+
+	if err := h.userBanService.UnbanUser(ctx, GetUserIdFromCtx(ctx), ctx.Param("user_id"), req); err != nil {
+		h.handleError(ctx, "解封用户失败", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, nil)
+}
+
+// ListUserBans godoc
+// @Summary 获取封禁记录
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param user_id query string false "被封禁的用户ID"
+// @Param operator_id query string false "操作人ID"
+// @Param action query string false "ban/unban"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} v1.ListUserBansResponse
+// @Router /admin/user-bans [get]
+func (h *UserBanHandler) ListUserBans(ctx *gin.Context) {
+	req := new(v1.ListUserBansRequest)
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.userBanService.ListUserBans(ctx, req)
+	if err != nil {
+		h.handleError(ctx, "获取封禁记录失败", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+func (h *UserBanHandler) handleError(ctx *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, v1.ErrBadRequest):
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+	case errors.Is(err, v1.ErrNotFound):
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+	case errors.Is(err, v1.ErrCannotBanUser):
+		v1.HandleError(ctx, http.StatusForbidden, err, nil)
+	case errors.Is(err, v1.ErrUserNotBanned):
+		v1.HandleError(ctx, http.StatusConflict, err, nil)
+	default:
+		h.logger.WithContext(ctx).Error(msg, zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+	}
+}
+
+// handleBannedError 用户被封禁时返回 403 和封禁原因、截止时间，返回是否已处理
+func handleBannedError(ctx *gin.Context, err error) bool {
+	var banned *service.UserBannedError
+	if !errors.As(err, &banned) {
+		return false
+	}
+	v1.HandleError(ctx, http.StatusForbidden, v1.ErrUserBanned, map[string]interface{}{
+		"reason":      banned.Reason,
+		"bannedUntil": banned.Until,
+	})
+	return true
+}
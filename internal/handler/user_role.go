@@ -1,8 +1,11 @@
 package handler
 
 import (
-	"github.com/gin-gonic/gin"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
 )
 
 type UserRoleHandler struct {
@@ -11,15 +14,82 @@ type UserRoleHandler struct {
 }
 
 func NewUserRoleHandler(
-    handler *Handler,
-    userRoleService service.UserRoleService,
+	handler *Handler,
+	userRoleService service.UserRoleService,
 ) *UserRoleHandler {
 	return &UserRoleHandler{
-		Handler:      handler,
+		Handler:         handler,
 		userRoleService: userRoleService,
 	}
 }
 
-func (h *UserRoleHandler) GetUserRole(ctx *gin.Context) {
+// GetUserRoles godoc
+// @Summary 获取用户的角色
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param user_id path string true "用户ID"
+// @Success 200 {object} v1.UserRolesResponse
+// @Router /admin/users/{user_id}/roles [get]
+func (h *UserRoleHandler) GetUserRoles(ctx *gin.Context) {
+	resp, err := h.userRoleService.GetUserRoles(ctx, ctx.Param("user_id"))
+	if err != nil {
+		handleRBACError(ctx, h.logger, "userRoleService.GetUserRoles error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// GrantRoles godoc
+// @Summary 批量授予用户角色
+// @Description 每个用户都会授予每个角色，已有的跳过；只有管理员可以授予管理员角色，其他人只能授予权限都是自己拥有的角色
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.UserRolesRequest true "params"
+// @Success 200 {object} v1.BatchUpdateResponse
+// @Router /admin/user-roles [post]
+func (h *UserRoleHandler) GrantRoles(ctx *gin.Context) {
+	req := new(v1.UserRolesRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.userRoleService.GrantRoles(ctx, GetUserIdFromCtx(ctx), req)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "userRoleService.GrantRoles error", err)
+		return
+	}
+
+	v1.HandleSuccess(ctx, resp)
+}
+
+// RevokeRoles godoc
+// @Summary 批量收回用户角色
+// @Description 每个用户都会收回每个角色，没有的跳过；只有管理员可以收回管理员角色，且至少保留一个管理员
+// @Tags 权限模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.UserRolesRequest true "params"
+// @Success 200 {object} v1.BatchUpdateResponse
+// @Router /admin/user-roles/revoke [post]
+func (h *UserRoleHandler) RevokeRoles(ctx *gin.Context) {
+	req := new(v1.UserRolesRequest)
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.userRoleService.RevokeRoles(ctx, GetUserIdFromCtx(ctx), req)
+	if err != nil {
+		handleRBACError(ctx, h.logger, "userRoleService.RevokeRoles error", err)
+		return
+	}
 
+	v1.HandleSuccess(ctx, resp)
 }
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPCacheMiddleware 为只读接口提供条件请求支持
+// 1. 缓冲响应体，handler 未设置 ETag 时按响应内容计算，可通过 SetETagPrefix 在哈希前加上版本等前缀
+// 2. 命中 If-None-Match / If-Modified-Since 时返回 304
+// 3. cacheControl 非空时写入 Cache-Control，供 CDN 缓存
+func HTTPCacheMiddleware(cacheControl string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+			ctx.Next()
+			return
+		}
+
+		origin := ctx.Writer
+		cw := &cacheWriter{ResponseWriter: origin, body: bytes.NewBuffer(nil), status: http.StatusOK}
+		ctx.Writer = cw
+		ctx.Next()
+		ctx.Writer = origin
+
+		if cw.status != http.StatusOK {
+			origin.WriteHeader(cw.status)
+			_, _ = origin.Write(cw.body.Bytes())
+			return
+		}
+
+		header := origin.Header()
+		if header.Get("ETag") == "" {
+			sum := sha1.Sum(cw.body.Bytes())
+			header.Set("ETag", "\""+ctx.GetString(etagPrefixKey)+hex.EncodeToString(sum[:])+"\"")
+		}
+		if cacheControl != "" {
+			header.Set("Cache-Control", cacheControl)
+		}
+
+		if notModified(ctx.Request, header) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			origin.WriteHeader(http.StatusNotModified)
+			origin.WriteHeaderNow()
+			return
+		}
+
+		origin.WriteHeader(http.StatusOK)
+		_, _ = origin.Write(cw.body.Bytes())
+	}
+}
+
+const etagPrefixKey = "etagPrefix"
+
+// SetETagPrefix 设置按响应内容计算的 ETag 的前缀，如 "v3-"，便于写接口从 If-Match 中取回版本号
+func SetETagPrefix(ctx *gin.Context, prefix string) {
+	ctx.Set(etagPrefixKey, prefix)
+}
+
+// SetLastModified 设置 Last-Modified 响应头
+func SetLastModified(ctx *gin.Context, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	ctx.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// notModified 判断条件请求是否命中，If-None-Match 优先于 If-Modified-Since
+func notModified(req *http.Request, header http.Header) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagWeakMatch(inm, header.Get("ETag"))
+	}
+
+	ims := req.Header.Get("If-Modified-Since")
+	lm := header.Get("Last-Modified")
+	if ims == "" || lm == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lm)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+// etagWeakMatch 按弱比较规则匹配 If-None-Match 中的 ETag 列表
+func etagWeakMatch(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+type cacheWriter struct {
+	gin.ResponseWriter
+	body   *bytes.Buffer
+	status int
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *cacheWriter) WriteHeaderNow() {}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *cacheWriter) Status() int {
+	return w.status
+}
+
+func (w *cacheWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *cacheWriter) Written() bool {
+	return w.body.Len() > 0
+}
@@ -11,6 +11,7 @@ func CORSMiddleware() gin.HandlerFunc {
 		method := c.Request.Method
 		c.Header("Access-Control-Allow-Origin", c.GetHeader("Origin"))
 		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Expose-Headers", "ETag")
 
 		if method == "OPTIONS" {
 			c.Header("Access-Control-Allow-Methods", c.GetHeader("Access-Control-Request-Method"))
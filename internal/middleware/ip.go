@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"novel-site-backend/pkg/log"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -27,24 +26,8 @@ func ClientIPMiddleware(logger *log.Logger) gin.HandlerFunc {
 }
 
 // getClientIP 获取客户端真实IP
+// 只有来自 http.trusted_proxies 的请求才按 X-Forwarded-For、X-Real-IP 取客户端IP，避免客户端伪造请求头
 func getClientIP(ctx *gin.Context) string {
-	// 从 X-Forwarded-For 获取
-	xForwardedFor := ctx.GetHeader("X-Forwarded-For")
-	if xForwardedFor != "" {
-		// X-Forwarded-For 可能包含多个 IP，第一个是真实客户端 IP
-		ips := strings.Split(xForwardedFor, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	// 从 X-Real-IP 获取
-	xRealIP := ctx.GetHeader("X-Real-IP")
-	if xRealIP != "" {
-		return xRealIP
-	}
-
-	// 从 RemoteAddr 获取
 	return ctx.ClientIP()
 }
 
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"novel-site-backend/api/v1"
 	"novel-site-backend/pkg/jwt"
@@ -9,7 +11,12 @@ import (
 	"net/http"
 )
 
-func StrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
+// SessionVerifier 校验访问令牌所属的会话是否仍然有效，例如令牌是否已注销
+type SessionVerifier interface {
+	VerifySession(ctx context.Context, claims *jwt.MyCustomClaims) error
+}
+
+func StrictAuth(j *jwt.JWT, verifier SessionVerifier, logger *log.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		tokenString := ctx.Request.Header.Get("Authorization")
 		if tokenString == "" {
@@ -32,6 +39,17 @@ func StrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
 			ctx.Abort()
 			return
 		}
+		if err := verifier.VerifySession(ctx, claims); err != nil {
+			logger.WithContext(ctx).Warn("session invalid", zap.String("UserId", claims.UserId), zap.Error(err))
+			if errors.Is(err, v1.ErrUserBanned) {
+				v1.HandleError(ctx, http.StatusForbidden, v1.ErrUserBanned, nil)
+				ctx.Abort()
+				return
+			}
+			v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+			ctx.Abort()
+			return
+		}
 
 		ctx.Set("claims", claims)
 		recoveryLoggerFunc(ctx, logger)
@@ -39,7 +57,7 @@ func StrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
 	}
 }
 
-func NoStrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
+func NoStrictAuth(j *jwt.JWT, verifier SessionVerifier, logger *log.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		tokenString := ctx.Request.Header.Get("Authorization")
 		if tokenString == "" {
@@ -58,6 +76,16 @@ func NoStrictAuth(j *jwt.JWT, logger *log.Logger) gin.HandlerFunc {
 			ctx.Next()
 			return
 		}
+		// 已注销的令牌按匿名访问处理，被封禁的用户与 StrictAuth 一样返回 403
+		if err := verifier.VerifySession(ctx, claims); err != nil {
+			if errors.Is(err, v1.ErrUserBanned) {
+				v1.HandleError(ctx, http.StatusForbidden, v1.ErrUserBanned, nil)
+				ctx.Abort()
+				return
+			}
+			ctx.Next()
+			return
+		}
 
 		ctx.Set("claims", claims)
 		recoveryLoggerFunc(ctx, logger)
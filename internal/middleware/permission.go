@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/pkg/jwt"
+	"novel-site-backend/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PermissionChecker 判断用户是否拥有某个权限
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userId string, code string) (bool, error)
+}
+
+// RequirePermission 要求当前用户拥有 code 权限或当前路由对应的接口权限，需放在 StrictAuth 之后
+func RequirePermission(checker PermissionChecker, logger *log.Logger, code string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		v, exists := ctx.Get("claims")
+		if !exists {
+			v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+			ctx.Abort()
+			return
+		}
+		claims := v.(*jwt.MyCustomClaims)
+
+		ok, err := checker.HasPermission(ctx, claims.UserId, code)
+		if err == nil && !ok && ctx.FullPath() != "" {
+			ok, err = checker.HasPermission(ctx, claims.UserId, model.APIPermissionCode(ctx.Request.Method, ctx.FullPath()))
+		}
+		if err != nil {
+			logger.WithContext(ctx).Error("check permission error", zap.String("UserId", claims.UserId), zap.String("permission", code), zap.Error(err))
+			v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternalServerError, nil)
+			ctx.Abort()
+			return
+		}
+		if !ok {
+			logger.WithContext(ctx).Warn("permission denied", zap.String("UserId", claims.UserId), zap.String("permission", code))
+			v1.HandleError(ctx, http.StatusForbidden, v1.ErrForbidden, nil)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	CtxVisitorIdKey       = "visitor_id"
+	CtxVisitorIssuedAtKey = "visitor_issued_at"
+
+	visitorCookieName   = "visitor_id"
+	visitorCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// VisitorMiddleware 为匿名访客签发并校验带签名的访客 Cookie
+// Cookie 格式为 "访客ID.签发时间戳.签名"，签名无效或缺失时重新签发
+// 只应挂在需要识别访客的写接口上，避免可缓存的响应携带 Set-Cookie
+func VisitorMiddleware(conf *viper.Viper) gin.HandlerFunc {
+	key := []byte(conf.GetString("security.visitor.key"))
+	if len(key) == 0 {
+		panic("security.visitor.key is required, set it with APP_SECURITY_VISITOR_KEY")
+	}
+	return func(ctx *gin.Context) {
+		value, _ := ctx.Cookie(visitorCookieName)
+		visitorId, issuedAt, ok := parseVisitorCookie(key, value)
+		if !ok {
+			visitorId = newVisitorId()
+			issuedAt = time.Now()
+			ctx.SetSameSite(http.SameSiteLaxMode)
+			ctx.SetCookie(visitorCookieName, signVisitorCookie(key, visitorId, issuedAt), visitorCookieMaxAge, "/", "", false, true)
+		}
+
+		ctx.Set(CtxVisitorIdKey, visitorId)
+		ctx.Set(CtxVisitorIssuedAtKey, issuedAt)
+		ctx.Next()
+	}
+}
+
+// GetVisitorId 从上下文获取访客ID
+func GetVisitorId(ctx *gin.Context) string {
+	if id, exists := ctx.Get(CtxVisitorIdKey); exists {
+		return id.(string)
+	}
+	return ""
+}
+
+// GetVisitorIssuedAt 从上下文获取访客 Cookie 的签发时间
+func GetVisitorIssuedAt(ctx *gin.Context) time.Time {
+	if t, exists := ctx.Get(CtxVisitorIssuedAtKey); exists {
+		return t.(time.Time)
+	}
+	return time.Time{}
+}
+
+func newVisitorId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func signVisitorCookie(key []byte, visitorId string, issuedAt time.Time) string {
+	payload := visitorId + "." + strconv.FormatInt(issuedAt.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func parseVisitorCookie(key []byte, value string) (string, time.Time, bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", time.Time{}, false
+	}
+	sig, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", time.Time{}, false
+	}
+	issued, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return parts[0], time.Unix(issued, 0), true
+}
@@ -8,25 +8,30 @@ import (
 
 // Book 书籍实体
 type Book struct {
-	Id          uint   `gorm:"primarykey"`
-	FileName    string `gorm:"column:file_name;not null"`
-	Title       string `gorm:"not null"`
-	Author      string `gorm:"not null"`
-	FileSize    int64  `gorm:"column:file_size;not null"`
-	MD5         string `gorm:"column:md5;unique;not null"`
-	NewFileName string `gorm:"column:new_file_name;not null"`
-	Cover       string
-	Intro       string
-	Parts       string
-	FileURL     string `gorm:"column:file_url"`
-	Sort        string
-	Type        string
-	Tag         string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
-	HotValue    int64          `gorm:"column:hot_value;default:0"`
-	Downloads   int64          `gorm:"column:downloads;default:0"`
+	Id            uint   `gorm:"primarykey"`
+	FileName      string `gorm:"column:file_name;not null"`
+	Title         string `gorm:"not null"`
+	Author        string `gorm:"not null"`
+	FileSize      int64  `gorm:"column:file_size;not null"`
+	MD5           string `gorm:"column:md5;unique;not null"`
+	NewFileName   string `gorm:"column:new_file_name;not null"`
+	Cover         string
+	Intro         string
+	Parts         string
+	FileURL       string `gorm:"column:file_url"`
+	Sort          string
+	Type          string
+	Tag           string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+	HotValue      int64          `gorm:"column:hot_value;default:0"`
+	Downloads     int64          `gorm:"column:downloads;default:0"`
+	Version       uint           `gorm:"column:version;not null;default:1"`              // 乐观锁版本号
+	TrendingScore float64        `gorm:"column:trending_score;not null;default:0;index"` // 按时间衰减的热度分，由定时任务计算
+	RatingScore   float64        `gorm:"column:rating_score;not null;default:0;index"`   // 贝叶斯加权评分，无评分时为 0
+	RatingCount   int64          `gorm:"column:rating_count;not null;default:0"`         // 评分数
+	Hidden        bool           `gorm:"column:hidden;not null;default:false;index"`     // 被举报隐藏，不在前台展示
 }
 
 func (b *Book) TableName() string {
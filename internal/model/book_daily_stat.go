@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// StatDateLayout 每日统计的日期格式
+const StatDateLayout = "2006-01-02"
+
+// BookDailyStat 图书每日访问统计
+type BookDailyStat struct {
+	Id        uint   `gorm:"primarykey"`
+	BookId    uint   `gorm:"not null;uniqueIndex:idx_book_daily_stat"`
+	Date      string `gorm:"size:10;not null;uniqueIndex:idx_book_daily_stat;index"` // 日期，格式 2006-01-02
+	Views     int64  `gorm:"not null;default:0"`                                     // 浏览量
+	Downloads int64  `gorm:"not null;default:0"`                                     // 下载量
+}
+
+// BookRankItem 榜单条目
+type BookRankItem struct {
+	BookId    uint
+	Title     string
+	Author    string
+	Cover     string
+	Score     float64
+	CreatedAt time.Time
+}
+
+func (s *BookDailyStat) TableName() string {
+	return "book_daily_stats"
+}
@@ -6,13 +6,29 @@ import (
 	"gorm.io/gorm"
 )
 
+// 评分评论的审核状态
+const (
+	RatingStatusPending  = "pending"  // 待审核
+	RatingStatusApproved = "approved" // 审核通过，公开展示
+	RatingStatusRejected = "rejected" // 审核拒绝
+)
+
 // BookRating 书籍评分实体
+// 每个登录用户、每个匿名访客对同一本书只能有一条未删除的评分，由 (book_id, voter_key) 唯一索引保证
 type BookRating struct {
 	Id           uint `gorm:"primarykey"`
-	BookId       uint `gorm:"not null"`
+	BookId       uint `gorm:"not null;index:idx_book_rating_feed,priority:1;uniqueIndex:idx_book_rating_voter,priority:1"`
 	RatingTypeId uint `gorm:"not null"`
 	Comment      string
-	IP           string
+	IP           string     `gorm:"index"`
+	UserId       string     `gorm:"index"`                                                 // 登录用户ID，匿名评分为空
+	VisitorId    string     `gorm:"index"`                                                 // 匿名访客ID，来自签名访客 Cookie
+	VoterKey     *string    `gorm:"size:128;uniqueIndex:idx_book_rating_voter,priority:2"` // 评分者标识 u:<用户ID> 或 v:<访客ID>，删除评分或没有评分者时为 NULL
+	VisitorSince *time.Time // 评分时访客 Cookie 的签发时间，用于识别新访客
+	Status       string     `gorm:"size:16;not null;default:approved;index:idx_book_rating_feed,priority:2"` // 评论审核状态
+	LikeCount    int64      `gorm:"not null;default:0"`                                                      // 点赞数
+	ReplyCount   int64      `gorm:"not null;default:0"`                                                      // 审核通过的回复数
+	Flagged      bool       `gorm:"not null;default:false;index"`                                            // 被反作弊任务标记为可疑，不计入评分统计
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	DeletedAt    gorm.DeletedAt `gorm:"index"`
@@ -24,6 +40,24 @@ type RatingTypeCount struct {
 	Count        int64 `json:"count"`
 }
 
+// RatingDailyCount 某天某个评分类型的评分数
+type RatingDailyCount struct {
+	Date         string // 日期，格式同 StatDateLayout
+	RatingTypeId uint
+	Count        int64
+}
+
+// RatingScore 图书评分汇总
+type RatingScore struct {
+	Score float64 // 贝叶斯加权评分
+	Count int64   // 评分数
+}
+
+// Counted 是否计入评分统计，审核通过且未被标记为可疑的评分才计入汇总、贝叶斯评分、趋势和榜单
+func (br *BookRating) Counted() bool {
+	return br.Status == RatingStatusApproved && !br.Flagged
+}
+
 func (br *BookRating) TableName() string {
 	return "book_ratings"
 }
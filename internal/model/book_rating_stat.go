@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// BookRatingStat 图书各评分类型的评分数汇总，随评分的增删改在同一事务内增量维护
+type BookRatingStat struct {
+	Id           uint  `gorm:"primarykey"`
+	BookId       uint  `gorm:"not null;uniqueIndex:idx_book_rating_stat"`
+	RatingTypeId uint  `gorm:"not null;uniqueIndex:idx_book_rating_stat;index"`
+	Count        int64 `gorm:"not null;default:0"` // 评分数
+	UpdatedAt    time.Time
+}
+
+// RatingTypeStat 评分类型及某本书在该类型下的评分数
+type RatingTypeStat struct {
+	RatingTypeId uint
+	Name         string
+	Description  string
+	Level        int
+	Count        int64
+}
+
+func (s *BookRatingStat) TableName() string {
+	return "book_rating_stats"
+}
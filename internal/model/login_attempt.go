@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// 登录尝试结果
+const (
+	LoginResultSuccess            = "success"             // 登录成功
+	LoginResultInvalidCredentials = "invalid_credentials" // 账号不存在或密码错误
+	LoginResultLocked             = "locked"              // 账号或 IP 被限制，未校验密码
+)
+
+// LoginAttempt 登录尝试记录，供管理员审计
+type LoginAttempt struct {
+	Id        uint      `gorm:"primarykey"`
+	Account   string    `gorm:"size:128;not null;index"` // 登录时填写的用户名或邮箱
+	UserId    string    `gorm:"size:64;index"`           // 账号存在时的用户ID
+	IP        string    `gorm:"size:64;index"`
+	Result    string    `gorm:"size:32;not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// LoginThrottle 账号或 IP 的连续登录失败计数，Key 为 user:<用户ID>、account:<账号> 或 ip:<IP>
+// 登录成功后删除账号的计数；最后一次失败超过 failure_window 后计数重新开始
+type LoginThrottle struct {
+	Id           uint   `gorm:"primarykey"`
+	Key          string `gorm:"column:throttle_key;size:191;not null;uniqueIndex"`
+	Failures     int    `gorm:"not null;default:0"` // 连续失败次数
+	LastFailedAt time.Time
+	LockedUntil  *time.Time // 锁定截止时间
+	UpdatedAt    time.Time
+}
+
+func (a *LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+func (t *LoginThrottle) TableName() string {
+	return "login_throttles"
+}
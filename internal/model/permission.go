@@ -6,15 +6,42 @@ import (
 	"gorm.io/gorm"
 )
 
+// 权限类型
+const (
+	PermissionTypeMenu   = "menu"
+	PermissionTypeButton = "button"
+	PermissionTypeAPI    = "api"
+)
+
+// 内置权限编码
+const (
+	PermBookManage          = "book:manage"
+	PermAnalyticsView       = "analytics:view"
+	PermRatingTypeManage    = "rating_type:manage"
+	PermReviewModerate      = "review:moderate"
+	PermRatingFlagManage    = "rating_flag:manage"
+	PermReportManage        = "report:manage"
+	PermSensitiveWordManage = "sensitive_word:manage"
+	PermLoginAttemptView    = "login_attempt:view"
+	PermUserBan             = "user:ban"
+	PermRoleManage          = "role:manage"
+	PermPermissionManage    = "permission:manage"
+
+	// PermAPIRoot 自动注册的接口权限默认挂在该权限下
+	PermAPIRoot = "api"
+)
+
 // Permission 权限表
 type Permission struct {
 	Id          uint   `gorm:"primarykey"`
-	Name        string `gorm:"size:50;not null" json:"name"`        // 权限名称
-	Code        string `gorm:"size:50;not null;unique" json:"code"` // 权限编码
-	Type        string `gorm:"size:20;not null" json:"type"`        // 权限类型(menu,button,api)
-	ParentId    uint   `gorm:"default:0" json:"parent_id"`          // 父级ID
-	Path        string `gorm:"size:200" json:"path"`                // 路径
-	Description string `gorm:"size:200" json:"description"`         // 描述
+	Name        string `gorm:"size:50;not null" json:"name"`         // 权限名称
+	Code        string `gorm:"size:200;not null;unique" json:"code"` // 权限编码，api 类型为 "请求方法 路由模板"
+	Type        string `gorm:"size:20;not null" json:"type"`         // 权限类型(menu,button,api)
+	ParentId    uint   `gorm:"default:0" json:"parent_id"`           // 父级ID
+	Path        string `gorm:"size:200" json:"path"`                 // 路径，api 类型为路由模板
+	Method      string `gorm:"size:10" json:"method"`                // 请求方法，仅 api 类型
+	Stale       bool   `gorm:"default:false" json:"stale"`           // 对应的路由已不存在，仅 api 类型
+	Description string `gorm:"size:200" json:"description"`          // 描述
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
@@ -23,3 +50,8 @@ type Permission struct {
 func (p *Permission) TableName() string {
 	return "permissions"
 }
+
+// APIPermissionCode 接口权限的编码，path 为 gin 的路由模板，例如 "PUT /v1/books/:id"
+func APIPermissionCode(method, path string) string {
+	return method + " " + path
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// 可疑评分命中的规则
+const (
+	RatingFlagReasonSubnet           = "subnet"            // 同一网段短时间内集中评分
+	RatingFlagReasonDuplicateComment = "duplicate_comment" // 多条评分的评论内容相同
+	RatingFlagReasonBurst            = "burst"             // 数秒内集中评分
+	RatingFlagReasonNewVisitor       = "new_visitor"       // 大量刚签发 Cookie 的访客集中评分
+)
+
+// 可疑评分的复核状态
+const (
+	RatingFlagStatusPending = "pending" // 待复核，评分不计入统计
+	RatingFlagStatusCleared = "cleared" // 误判，评分恢复计入统计
+	RatingFlagStatusRemoved = "removed" // 已删除评分
+)
+
+// RatingFlag 反作弊任务标记的可疑评分，每条评分只标记一次，复核后不会被再次标记
+type RatingFlag struct {
+	Id        uint   `gorm:"primarykey"`
+	RatingId  uint   `gorm:"not null;uniqueIndex"`
+	BookId    uint   `gorm:"not null;index"`
+	Reasons   string `gorm:"size:128;not null"` // 命中的规则，逗号分隔
+	Status    string `gorm:"size:16;not null;default:pending;index"`
+	Operator  string `gorm:"size:64"` // 复核的管理员ID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (f *RatingFlag) TableName() string {
+	return "rating_flags"
+}
@@ -8,13 +8,15 @@ import (
 
 // RatingType 评分类型实体
 type RatingType struct {
-	Id          uint   `gorm:"primarykey"`
-	Name        string `gorm:"not null"`
-	Description string
-	Level       int `gorm:"not null"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	Id           uint   `gorm:"primarykey"`
+	Name         string `gorm:"not null"`
+	Description  string
+	Level        int  `gorm:"not null"`           // 评分等级，未删除的评分类型之间不能重复
+	ActiveLevel  *int `gorm:"uniqueIndex"`        // 未删除时等于 Level，删除后为 NULL，由唯一索引保证等级不重复
+	DisplayOrder int  `gorm:"not null;default:0"` // 展示顺序，越小越靠前
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
 }
 
 func (rt *RatingType) TableName() string {
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// 举报对象类型
+const (
+	ReportTargetBook    = "book"
+	ReportTargetReview  = "review"
+	ReportTargetChapter = "chapter"
+)
+
+// 举报处理状态
+const (
+	ReportStatusOpen      = "open"      // 待处理
+	ReportStatusResolved  = "resolved"  // 举报成立，已处理
+	ReportStatusDismissed = "dismissed" // 举报不成立
+)
+
+// 举报处理记录的动作
+const (
+	ReportActionAutoHide = "auto_hide" // 举报数达到阈值后自动隐藏
+	ReportActionRemove   = "remove"    // 管理员确认举报成立，下架对象
+	ReportActionDismiss  = "dismiss"   // 管理员驳回举报，恢复对象
+)
+
+// Report 举报，同一举报人对同一对象只能举报一次
+type Report struct {
+	Id         uint   `gorm:"primarykey"`
+	TargetType string `gorm:"size:16;not null;uniqueIndex:idx_report_reporter,priority:1"`
+	TargetId   uint   `gorm:"not null;uniqueIndex:idx_report_reporter,priority:2"`
+	Reporter   string `gorm:"size:64;not null;uniqueIndex:idx_report_reporter,priority:3"` // 举报人标识，u:用户ID 或 v:访客ID
+	Reason     string `gorm:"size:32;not null"`                                            // 举报原因代码
+	Detail     string `gorm:"size:1000"`                                                   // 补充说明
+	IP         string `gorm:"size:64;index"`
+	Status     string `gorm:"size:16;not null;default:open;index"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ReportAudit 举报处理记录
+type ReportAudit struct {
+	Id         uint   `gorm:"primarykey"`
+	TargetType string `gorm:"size:16;not null;index:idx_report_audit_target,priority:1"`
+	TargetId   uint   `gorm:"not null;index:idx_report_audit_target,priority:2"`
+	Action     string `gorm:"size:16;not null"`
+	Operator   string `gorm:"size:64"` // 操作的管理员ID，系统自动处理时为空
+	Note       string `gorm:"size:1000"`
+	CreatedAt  time.Time
+}
+
+// ReportTargetSummary 按举报对象汇总的举报
+type ReportTargetSummary struct {
+	TargetType string
+	TargetId   uint
+	Reports    int64
+	LatestId   uint // 最近一次举报的ID
+	LatestAt   time.Time
+}
+
+func (r *Report) TableName() string {
+	return "reports"
+}
+
+func (a *ReportAudit) TableName() string {
+	return "report_audits"
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReviewReply 评论回复，最多两层：ParentId 为 0 的是对评论的直接回复，否则是对某条直接回复的回复
+type ReviewReply struct {
+	Id        uint   `gorm:"primarykey"`
+	RatingId  uint   `gorm:"not null;index"` // 所属评论(评分)ID
+	ParentId  uint   `gorm:"not null;default:0;index"`
+	Comment   string `gorm:"not null"`
+	IP        string
+	UserId    string `gorm:"index"`                                   // 登录用户ID，匿名回复为空
+	VisitorId string `gorm:"index"`                                   // 匿名访客ID
+	Status    string `gorm:"size:16;not null;default:approved;index"` // 审核状态，同 BookRating.Status
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// ReviewLike 评论点赞，每个登录用户或匿名访客对同一条评论只能点赞一次
+type ReviewLike struct {
+	Id        uint   `gorm:"primarykey"`
+	RatingId  uint   `gorm:"not null;uniqueIndex:idx_review_like"`
+	Voter     string `gorm:"size:64;not null;uniqueIndex:idx_review_like"` // 点赞者标识，u:用户ID 或 v:访客ID
+	IP        string `gorm:"size:64;index"`                                // 点赞者IP，用于限制同一IP的点赞数
+	CreatedAt time.Time
+}
+
+func (r *ReviewReply) TableName() string {
+	return "review_replies"
+}
+
+func (l *ReviewLike) TableName() string {
+	return "review_likes"
+}
@@ -6,6 +6,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// 内置角色编码
+const (
+	RoleAdmin     = "admin"     // 管理员，拥有全部权限
+	RoleEditor    = "editor"    // 编辑，管理书籍和评分类型
+	RoleModerator = "moderator" // 审核员，处理评论、举报和可疑评分
+	RoleReader    = "reader"    // 普通读者
+)
+
 // Role 角色表
 type Role struct {
 	Id          uint         `gorm:"primarykey"`
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// 敏感词命中后的处理方式
+const (
+	SensitiveActionMask   = "mask"   // 用 * 替换命中内容后直接发布
+	SensitiveActionReview = "review" // 进入人工审核队列
+	SensitiveActionReject = "reject" // 直接拒绝提交
+)
+
+// SensitiveWord 敏感词
+type SensitiveWord struct {
+	Id        uint   `gorm:"primarykey"`
+	Word      string `gorm:"size:64;not null;uniqueIndex"`
+	Action    string `gorm:"size:16;not null;default:mask"` // 命中后的处理方式
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (w *SensitiveWord) TableName() string {
+	return "sensitive_words"
+}
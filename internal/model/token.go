@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// RefreshToken 刷新令牌，每次刷新后作废并签发新令牌，同一次登录签发的令牌属于同一个令牌族
+// 已作废的令牌被再次使用时视为令牌泄露，吊销整个令牌族
+type RefreshToken struct {
+	Id              uint   `gorm:"primarykey"`
+	UserId          string `gorm:"size:64;not null;index"`
+	FamilyId        string `gorm:"size:64;not null;index"`
+	TokenHash       string `gorm:"size:64;not null;uniqueIndex"` // 令牌的 SHA-256，不保存明文
+	AccessJti       string `gorm:"size:64;not null;index"`       // 同时签发的访问令牌ID
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	UsedAt          *time.Time // 刷新时间，刷新后令牌作废
+	RevokedAt       *time.Time // 吊销时间
+	CreatedAt       time.Time
+}
+
+// RevokedToken 已吊销但尚未过期的访问令牌
+type RevokedToken struct {
+	Id        uint      `gorm:"primarykey"`
+	Jti       string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"index"` // 访问令牌的过期时间，过期后可清理
+	CreatedAt time.Time
+}
+
+// 一次性令牌用途
+const (
+	UserTokenVerifyEmail    = "verify_email"
+	UserTokenResetPassword  = "reset_password"
+	UserTokenLoginChallenge = "login_challenge" // 密码校验通过后等待两步验证的登录
+)
+
+// UserToken 一次性令牌，用于验证邮箱、重置密码和两步验证登录
+type UserToken struct {
+	Id        uint   `gorm:"primarykey"`
+	UserId    string `gorm:"size:64;not null;index"`
+	Purpose   string `gorm:"size:32;not null"`
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+	Email     string `gorm:"size:128;not null"` // 发送令牌时的邮箱，验证时邮箱已修改则令牌失效
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (t *RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+func (t *RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+func (t *UserToken) TableName() string {
+	return "user_tokens"
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// UserTwoFactor 用户的 TOTP 两步验证配置
+type UserTwoFactor struct {
+	Id        uint       `gorm:"primarykey"`
+	UserId    string     `gorm:"size:64;not null;uniqueIndex"`
+	Secret    string     `gorm:"size:255;not null"` // 加密保存的 TOTP 密钥
+	EnabledAt *time.Time // 启用时间，为空表示已生成密钥但尚未确认
+	LastStep  int64      `gorm:"not null;default:0"` // 最近一次通过校验的时间步，防止验证码被重放
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RecoveryCode 两步验证恢复码，无法使用验证器时代替验证码，每个只能使用一次
+type RecoveryCode struct {
+	Id        uint   `gorm:"primarykey"`
+	UserId    string `gorm:"size:64;not null;index"`
+	CodeHash  string `gorm:"size:64;not null"` // 恢复码的 SHA-256
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (t *UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+func (c *RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
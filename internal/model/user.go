@@ -6,29 +6,49 @@ import (
 	"gorm.io/gorm"
 )
 
+// 用户状态
+const (
+	UserStatusNormal   = 1 // 正常
+	UserStatusDisabled = 2 // 禁用(封禁)
+)
+
 type User struct {
-	Id        uint   `gorm:"primarykey"`
-	UserId    string `gorm:"unique;not null"`
-	Nickname  string `gorm:"not null"`
-	Username  string `gorm:"unique;not null"`
-	Password  string `gorm:"not null"`
-	Avatar    string `gorm:"comment:头像地址"`
-	Intro     string `gorm:"comment:个人简介"`
-	Email     string `gorm:"not null"`
-	Status    int    `gorm:"default:1" json:"status"` // 状态 1:正常 2:禁用
-	Roles     []Role `gorm:"many2many:user_roles"`    // 用户角色多对多关系
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Id              uint       `gorm:"primarykey"`
+	UserId          string     `gorm:"unique;not null"`
+	Nickname        string     `gorm:"not null"`
+	Username        string     `gorm:"unique;not null"`
+	Password        string     `gorm:"not null"`
+	Avatar          string     `gorm:"comment:头像地址"`
+	Intro           string     `gorm:"comment:个人简介"`
+	Email           string     `gorm:"not null;unique"` // 小写保存
+	EmailVerifiedAt *time.Time // 邮箱验证时间，修改邮箱后清空
+	Status          int        `gorm:"default:1" json:"status"` // 状态 1:正常 2:禁用
+	BanReason       string     `gorm:"size:200"`                // 封禁原因
+	BannedUntil     *time.Time // 封禁截止时间，为空表示永久封禁
+	Roles           []Role     `gorm:"many2many:user_roles"` // 用户角色多对多关系
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
 
 func (u *User) TableName() string {
 	return "users"
 }
 
-// HasPermission 检查用户是否有某个权限
+// IsBanned 用户在 now 时是否处于封禁中，封禁到期后视为正常
+func (u *User) IsBanned(now time.Time) bool {
+	if u.Status != UserStatusDisabled {
+		return false
+	}
+	return u.BannedUntil == nil || now.Before(*u.BannedUntil)
+}
+
+// HasPermission 检查用户是否有某个权限，管理员拥有全部权限
 func (u *User) HasPermission(permissionCode string) bool {
 	for _, role := range u.Roles {
+		if role.Code == RoleAdmin {
+			return true
+		}
 		for _, perm := range role.Permissions {
 			if perm.Code == permissionCode {
 				return true
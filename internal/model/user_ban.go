@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// 封禁操作
+const (
+	UserBanActionBan   = "ban"   // 封禁
+	UserBanActionUnban = "unban" // 解封
+)
+
+// UserBan 用户封禁和解封记录，记录谁在何时因何原因封禁或解封了谁
+type UserBan struct {
+	Id         uint       `gorm:"primarykey"`
+	UserId     string     `gorm:"size:64;not null;index"` // 被封禁的用户ID
+	Action     string     `gorm:"size:16;not null"`
+	Reason     string     `gorm:"size:200"`
+	ExpiresAt  *time.Time // 封禁截止时间，为空表示永久封禁，解封记录为空
+	OperatorId string     `gorm:"size:64;index"` // 操作人的用户ID，封禁到期自动解封时为空
+	CreatedAt  time.Time  `gorm:"index"`
+}
+
+func (b *UserBan) TableName() string {
+	return "user_bans"
+}
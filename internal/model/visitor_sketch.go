@@ -0,0 +1,22 @@
+package model
+
+// VisitorSketch 每日独立访客 HyperLogLog 草图
+type VisitorSketch struct {
+	Id        uint   `gorm:"primarykey"`
+	BookId    uint   `gorm:"not null;uniqueIndex:idx_visitor_sketch"`         // 图书ID，0 表示全站
+	Date      string `gorm:"size:10;not null;uniqueIndex:idx_visitor_sketch"` // 日期，格式 2006-01-02
+	Registers []byte `gorm:"not null"`                                        // 草图寄存器
+	Estimate  int64  `gorm:"not null;default:0"`                              // 独立访客估计值
+}
+
+// DailySeriesPoint 每日统计时间序列中的一个点
+type DailySeriesPoint struct {
+	Date           string
+	Views          int64
+	Downloads      int64
+	UniqueVisitors int64
+}
+
+func (s *VisitorSketch) TableName() string {
+	return "visitor_sketches"
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -12,11 +14,18 @@ import (
 type BookRepository interface {
 	Create(ctx context.Context, book *model.Book) error
 	Update(ctx context.Context, book *model.Book) error
-	Delete(ctx context.Context, id uint) error
+	Delete(ctx context.Context, id uint, version uint) error
 	GetByID(ctx context.Context, id uint) (*model.Book, error)
 	List(ctx context.Context, req *v1.ListBooksRequest) ([]*model.Book, int64, error)
 	GetByMD5(ctx context.Context, md5 string) (*model.Book, error)
-	IncrementHotValue(ctx context.Context, id uint) error
+	IncrementHotValue(ctx context.Context, id uint, delta int64) error
+	IncrementDownloads(ctx context.Context, id uint, delta int64) error
+	TopBy(ctx context.Context, column string, createdSince time.Time, limit int) ([]*model.BookRankItem, error)
+	Newest(ctx context.Context, createdSince time.Time, limit int) ([]*model.BookRankItem, error)
+	UpdateTrendingScores(ctx context.Context, scores map[uint]float64) error
+	UpdateRatingScore(ctx context.Context, id uint, score *model.RatingScore) error
+	UpdateRatingScores(ctx context.Context, scores map[uint]*model.RatingScore) error
+	SetHidden(ctx context.Context, id uint, hidden bool) error
 	GetAllSorts(ctx context.Context) ([]string, error)
 	QuickSearch(ctx context.Context, keyword string, limit int) ([]*model.Book, error)
 }
@@ -35,12 +44,38 @@ func (r *bookRepository) Create(ctx context.Context, book *model.Book) error {
 	return r.DB(ctx).Create(book).Error
 }
 
+// Update 按版本号更新图书，版本号不匹配时返回 ErrBookVersionConflict
+// 热度值、下载量、热度分、评分汇总和隐藏状态由计数器、定时任务、评分模块和举报模块单独维护，这里不覆盖
 func (r *bookRepository) Update(ctx context.Context, book *model.Book) error {
-	return r.DB(ctx).Save(book).Error
+	version := book.Version
+	book.Version = version + 1
+
+	result := r.DB(ctx).Model(book).
+		Where("version = ?", version).
+		Select("*").
+		Omit("created_at", "hot_value", "downloads", "trending_score", "rating_score", "rating_count", "hidden").
+		Updates(book)
+	if result.Error != nil {
+		book.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		book.Version = version
+		return v1.ErrBookVersionConflict
+	}
+	return nil
 }
 
-func (r *bookRepository) Delete(ctx context.Context, id uint) error {
-	return r.DB(ctx).Delete(&model.Book{}, id).Error
+// Delete 按版本号删除图书，版本号不匹配时返回 ErrBookVersionConflict
+func (r *bookRepository) Delete(ctx context.Context, id uint, version uint) error {
+	result := r.DB(ctx).Where("version = ?", version).Delete(&model.Book{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return v1.ErrBookVersionConflict
+	}
+	return nil
 }
 
 func (r *bookRepository) GetByID(ctx context.Context, id uint) (*model.Book, error) {
@@ -61,7 +96,7 @@ func (r *bookRepository) List(ctx context.Context, req *v1.ListBooksRequest) ([]
 	db := r.DB(ctx)
 
 	// 构建查询条件
-	query := db.Model(&model.Book{})
+	query := db.Model(&model.Book{}).Where("hidden = ?", false)
 
 	// 添加模糊查询条件
 	if req.Title != "" {
@@ -80,8 +115,13 @@ func (r *bookRepository) List(ctx context.Context, req *v1.ListBooksRequest) ([]
 	if req.Type == "latest" {
 		query = query.Order("created_at DESC")
 	}
+	// hotest 按时间衰减的热度分排序，避免老书长期霸榜
 	if req.Type == "hotest" {
-		query = query.Order("hot_value DESC")
+		query = query.Order("trending_score DESC")
+	}
+	// rating 按贝叶斯加权评分排序
+	if req.Type == "rating" {
+		query = query.Order("rating_score DESC").Order("rating_count DESC")
 	}
 
 	// 获取总数
@@ -112,14 +152,117 @@ func (r *bookRepository) GetByMD5(ctx context.Context, md5 string) (*model.Book,
 	return &book, nil
 }
 
-func (r *bookRepository) IncrementHotValue(ctx context.Context, id uint) error {
+func (r *bookRepository) IncrementHotValue(ctx context.Context, id uint, delta int64) error {
 	// 使用 SQL 的 UPDATE 语句直接增加热度值
 	return r.DB(ctx).Model(&model.Book{}).
 		Where("id = ?", id).
-		UpdateColumn("hot_value", gorm.Expr("hot_value + ?", 1)).
+		UpdateColumn("hot_value", gorm.Expr("hot_value + ?", delta)).
 		Error
 }
 
+func (r *bookRepository) IncrementDownloads(ctx context.Context, id uint, delta int64) error {
+	return r.DB(ctx).Model(&model.Book{}).
+		Where("id = ?", id).
+		UpdateColumn("downloads", gorm.Expr("downloads + ?", delta)).
+		Error
+}
+
+// bookRankColumns 可用于 TopBy 排行的列，列名会拼接进 SQL，只允许固定的列
+var bookRankColumns = map[string]bool{
+	"hot_value":    true,
+	"downloads":    true,
+	"rating_score": true,
+}
+
+// TopBy 按指定列降序取榜单，createdSince 非零时只统计该时间之后创建的图书
+func (r *bookRepository) TopBy(ctx context.Context, column string, createdSince time.Time, limit int) ([]*model.BookRankItem, error) {
+	if !bookRankColumns[column] {
+		return nil, fmt.Errorf("invalid rank column %q", column)
+	}
+	var items []*model.BookRankItem
+
+	query := r.DB(ctx).Model(&model.Book{}).
+		Select("id AS book_id, title, author, cover, created_at, "+column+" AS score").
+		Where("hidden = ?", false)
+	if !createdSince.IsZero() {
+		query = query.Where("created_at >= ?", createdSince)
+	}
+	err := query.Order(column + " DESC").
+		Order("id DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+// Newest 按创建时间降序取榜单
+func (r *bookRepository) Newest(ctx context.Context, createdSince time.Time, limit int) ([]*model.BookRankItem, error) {
+	var items []*model.BookRankItem
+
+	query := r.DB(ctx).Model(&model.Book{}).
+		Select("id AS book_id, title, author, cover, created_at").
+		Where("hidden = ?", false)
+	if !createdSince.IsZero() {
+		query = query.Where("created_at >= ?", createdSince)
+	}
+	err := query.Order("created_at DESC").
+		Order("id DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+// UpdateTrendingScores 重置全部热度分后写入新的热度分
+func (r *bookRepository) UpdateTrendingScores(ctx context.Context, scores map[uint]float64) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Model(&model.Book{}).
+			Where("trending_score <> ?", 0).
+			UpdateColumn("trending_score", 0).Error; err != nil {
+			return err
+		}
+		for id, score := range scores {
+			if err := r.DB(ctx).Model(&model.Book{}).
+				Where("id = ?", id).
+				UpdateColumn("trending_score", score).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *bookRepository) UpdateRatingScore(ctx context.Context, id uint, score *model.RatingScore) error {
+	return r.DB(ctx).Model(&model.Book{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"rating_score": score.Score,
+			"rating_count": score.Count,
+		}).Error
+}
+
+// UpdateRatingScores 重置全部评分汇总后写入新的评分汇总
+func (r *bookRepository) UpdateRatingScores(ctx context.Context, scores map[uint]*model.RatingScore) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Model(&model.Book{}).
+			Where("rating_count <> ? OR rating_score <> ?", 0, 0).
+			UpdateColumns(map[string]interface{}{"rating_score": 0, "rating_count": 0}).Error; err != nil {
+			return err
+		}
+		for id, score := range scores {
+			if err := r.UpdateRatingScore(ctx, id, score); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetHidden 设置图书的隐藏状态，不修改版本号
+func (r *bookRepository) SetHidden(ctx context.Context, id uint, hidden bool) error {
+	return r.DB(ctx).Model(&model.Book{}).
+		Where("id = ?", id).
+		UpdateColumn("hidden", hidden).Error
+}
+
 func (r *bookRepository) GetAllSorts(ctx context.Context) ([]string, error) {
 	var sorts []string
 	err := r.DB(ctx).Model(&model.Book{}).
@@ -134,6 +277,7 @@ func (r *bookRepository) QuickSearch(ctx context.Context, keyword string, limit
 	var books []*model.Book
 
 	err := r.DB(ctx).Model(&model.Book{}).
+		Where("hidden = ?", false).
 		Where("title LIKE ? OR author LIKE ? OR tag LIKE ?",
 						"%"+keyword+"%",
 						"%"+keyword+"%",
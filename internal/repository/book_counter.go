@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+// CounterKind 计数类型
+type CounterKind string
+
+const (
+	CounterViews     CounterKind = "views"     // 浏览量，同时计入热度值
+	CounterDownloads CounterKind = "downloads" // 下载量
+)
+
+// BookCounter 图书计数缓冲区，累积增量后由定时任务批量写回数据库
+type BookCounter interface {
+	// Incr 为指定图书累加增量
+	Incr(ctx context.Context, kind CounterKind, bookId uint, delta int64) error
+	// Drain 取出并清空指定类型当前累积的全部增量
+	Drain(ctx context.Context, kind CounterKind) (map[uint]int64, error)
+	// AddVisitor 记录一次访客访问，visitor 为访客标识的散列值
+	AddVisitor(ctx context.Context, bookId uint, visitor uint64) error
+	// DrainVisitors 取出并清空当前记录的全部访客
+	DrainVisitors(ctx context.Context) (map[uint][]uint64, error)
+}
+
+// NewBookCounter 根据配置选择计数后端
+// data.counter.backend 为 redis 时使用 Redis（多实例部署），否则使用进程内存
+func NewBookCounter(conf *viper.Viper) BookCounter {
+	if conf.GetString("data.counter.backend") == "redis" {
+		return &redisBookCounter{
+			rdb:    NewRedis(conf),
+			prefix: "book:counter:",
+		}
+	}
+	return &memoryBookCounter{
+		counts:   make(map[CounterKind]map[uint]int64),
+		visitors: make(map[uint]map[uint64]struct{}),
+	}
+}
+
+type memoryBookCounter struct {
+	mu       sync.Mutex
+	counts   map[CounterKind]map[uint]int64
+	visitors map[uint]map[uint64]struct{}
+}
+
+func (c *memoryBookCounter) Incr(ctx context.Context, kind CounterKind, bookId uint, delta int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[kind] == nil {
+		c.counts[kind] = make(map[uint]int64)
+	}
+	c.counts[kind][bookId] += delta
+	return nil
+}
+
+func (c *memoryBookCounter) Drain(ctx context.Context, kind CounterKind) (map[uint]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := c.counts[kind]
+	delete(c.counts, kind)
+	if counts == nil {
+		counts = make(map[uint]int64)
+	}
+	return counts, nil
+}
+
+func (c *memoryBookCounter) AddVisitor(ctx context.Context, bookId uint, visitor uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.visitors[bookId] == nil {
+		c.visitors[bookId] = make(map[uint64]struct{})
+	}
+	c.visitors[bookId][visitor] = struct{}{}
+	return nil
+}
+
+func (c *memoryBookCounter) DrainVisitors(ctx context.Context) (map[uint][]uint64, error) {
+	c.mu.Lock()
+	visitors := c.visitors
+	c.visitors = make(map[uint]map[uint64]struct{})
+	c.mu.Unlock()
+
+	result := make(map[uint][]uint64, len(visitors))
+	for bookId, set := range visitors {
+		for visitor := range set {
+			result[bookId] = append(result[bookId], visitor)
+		}
+	}
+	return result, nil
+}
+
+// drainScript 原子地读取并删除计数哈希，避免多实例重复写回
+var drainScript = redis.NewScript(`
+local v = redis.call('HGETALL', KEYS[1])
+redis.call('DEL', KEYS[1])
+return v
+`)
+
+type redisBookCounter struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func (c *redisBookCounter) Incr(ctx context.Context, kind CounterKind, bookId uint, delta int64) error {
+	return c.rdb.HIncrBy(ctx, c.prefix+string(kind), strconv.FormatUint(uint64(bookId), 10), delta).Err()
+}
+
+func (c *redisBookCounter) Drain(ctx context.Context, kind CounterKind) (map[uint]int64, error) {
+	values, err := drainScript.Run(ctx, c.rdb, []string{c.prefix + string(kind)}).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		id, err := strconv.ParseUint(values[i], 10, 32)
+		if err != nil {
+			continue
+		}
+		delta, err := strconv.ParseInt(values[i+1], 10, 64)
+		if err != nil {
+			continue
+		}
+		counts[uint(id)] += delta
+	}
+	return counts, nil
+}
+
+// AddVisitor 访客以 "图书ID:散列值" 为字段写入同一个哈希，复用 drainScript 取出
+func (c *redisBookCounter) AddVisitor(ctx context.Context, bookId uint, visitor uint64) error {
+	field := strconv.FormatUint(uint64(bookId), 10) + ":" + strconv.FormatUint(visitor, 16)
+	return c.rdb.HSet(ctx, c.prefix+"visitors", field, 1).Err()
+}
+
+func (c *redisBookCounter) DrainVisitors(ctx context.Context) (map[uint][]uint64, error) {
+	values, err := drainScript.Run(ctx, c.rdb, []string{c.prefix + "visitors"}).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint][]uint64)
+	for i := 0; i+1 < len(values); i += 2 {
+		parts := strings.SplitN(values[i], ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		id, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil {
+			continue
+		}
+		visitor, err := strconv.ParseUint(parts[1], 16, 64)
+		if err != nil {
+			continue
+		}
+		result[uint(id)] = append(result[uint(id)], visitor)
+	}
+	return result, nil
+}
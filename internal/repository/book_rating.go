@@ -2,7 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookRatingRepository interface {
@@ -10,8 +17,30 @@ type BookRatingRepository interface {
 	Update(ctx context.Context, br *model.BookRating) error
 	Delete(ctx context.Context, id uint) error
 	GetByID(ctx context.Context, id uint) (*model.BookRating, error)
-	ListByBookID(ctx context.Context, bookId uint, page, pageSize int) ([]*model.BookRating, int64, error)
+	ListFeed(ctx context.Context, q *ReviewFeedQuery) ([]*model.BookRating, error)
+	ListByStatus(ctx context.Context, status string, page, pageSize int) ([]*model.BookRating, int64, error)
+	UpdateStatus(ctx context.Context, ids []uint, status string) (int64, error)
+	IncrCounter(ctx context.Context, id uint, column string, delta int64) error
 	GetRatingStats(ctx context.Context, bookId uint) ([]*model.RatingTypeCount, int64, error)
+	FindByVoter(ctx context.Context, bookId uint, userId, visitorId string) (*model.BookRating, error)
+	CountByIP(ctx context.Context, bookId uint, ip string) (int64, error)
+	CountByRatingType(ctx context.Context, ratingTypeId uint) (int64, error)
+	MigrateRatingType(ctx context.Context, fromTypeId, toTypeId uint) (int64, error)
+	TopRated(ctx context.Context, since time.Time, minCount int, limit int) ([]*model.BookRankItem, error)
+	DailyCounts(ctx context.Context, bookId uint, from, to time.Time) ([]*model.RatingDailyCount, error)
+	ListSince(ctx context.Context, since time.Time) ([]*model.BookRating, error)
+	GetByIds(ctx context.Context, ids []uint) ([]*model.BookRating, error)
+	SetFlagged(ctx context.Context, id uint, flagged bool) error
+}
+
+// ReviewFeedQuery 评论列表查询条件
+type ReviewFeedQuery struct {
+	BookId       uint
+	RatingTypeId uint  // 为 0 时不过滤评分类型
+	OrderByLikes bool  // 按点赞数排序，否则按最新排序
+	AfterLikes   int64 // 游标：上一页最后一条的点赞数
+	AfterId      uint  // 游标：上一页最后一条的ID，为 0 表示首页
+	Limit        int
 }
 
 type bookRatingRepository struct {
@@ -24,9 +53,14 @@ func NewBookRatingRepository(r *Repository) BookRatingRepository {
 	}
 }
 
+// Create 创建评分，同一评分者对同一本书已有评分时返回 ErrRatingDuplicate
 func (r *bookRatingRepository) Create(ctx context.Context, br *model.BookRating) error {
-	if err := r.DB(ctx).Create(br).Error; err != nil {
-		return err
+	result := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(br)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return v1.ErrRatingDuplicate
 	}
 	return nil
 }
@@ -38,7 +72,11 @@ func (r *bookRatingRepository) Update(ctx context.Context, br *model.BookRating)
 	return nil
 }
 
+// Delete 软删除评分，同时清空评分者标识，使评分者可以重新评分
 func (r *bookRatingRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.DB(ctx).Model(&model.BookRating{}).Where("id = ?", id).Update("voter_key", nil).Error; err != nil {
+		return err
+	}
 	if err := r.DB(ctx).Delete(&model.BookRating{}, id).Error; err != nil {
 		return err
 	}
@@ -48,26 +86,84 @@ func (r *bookRatingRepository) Delete(ctx context.Context, id uint) error {
 func (r *bookRatingRepository) GetByID(ctx context.Context, id uint) (*model.BookRating, error) {
 	var br model.BookRating
 	if err := r.DB(ctx).First(&br, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
 		return nil, err
 	}
 	return &br, nil
 }
 
-func (r *bookRatingRepository) ListByBookID(ctx context.Context, bookId uint, page, pageSize int) ([]*model.BookRating, int64, error) {
-	var ratings []*model.BookRating
-	var total int64
+// FindByVoter 查找评分者对某本书的已有评分，登录用户按用户ID查找，匿名访客按访客ID查找
+// 不存在时返回 nil
+func (r *bookRatingRepository) FindByVoter(ctx context.Context, bookId uint, userId, visitorId string) (*model.BookRating, error) {
+	query := r.DB(ctx).Where("book_id = ?", bookId)
+	switch {
+	case userId != "":
+		query = query.Where("user_id = ?", userId)
+	case visitorId != "":
+		query = query.Where("visitor_id = ?", visitorId)
+	default:
+		return nil, nil
+	}
 
-	offset := (page - 1) * pageSize
+	var br model.BookRating
+	if err := query.Order("id DESC").First(&br).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &br, nil
+}
 
-	if err := r.DB(ctx).Model(&model.BookRating{}).Where("book_id = ?", bookId).Count(&total).Error; err != nil {
-		return nil, 0, err
+// CountByIP 统计某个IP对某本书的评分数
+func (r *bookRatingRepository) CountByIP(ctx context.Context, bookId uint, ip string) (int64, error) {
+	var count int64
+	err := r.DB(ctx).Model(&model.BookRating{}).
+		Where("book_id = ? AND ip = ?", bookId, ip).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByRatingType 统计某个评分类型下的评分数，包含已删除的评分
+func (r *bookRatingRepository) CountByRatingType(ctx context.Context, ratingTypeId uint) (int64, error) {
+	var count int64
+	err := r.DB(ctx).Unscoped().Model(&model.BookRating{}).
+		Where("rating_type_id = ?", ratingTypeId).
+		Count(&count).Error
+	return count, err
+}
+
+// MigrateRatingType 把某个评分类型下的全部评分（包含已删除的评分）改为另一个评分类型，返回迁移的评分数
+func (r *bookRatingRepository) MigrateRatingType(ctx context.Context, fromTypeId, toTypeId uint) (int64, error) {
+	result := r.DB(ctx).Unscoped().Model(&model.BookRating{}).
+		Where("rating_type_id = ?", fromTypeId).
+		UpdateColumn("rating_type_id", toTypeId)
+	return result.RowsAffected, result.Error
+}
+
+// ListFeed 按游标分页获取某本书审核通过且带评论内容的评分
+// 游标为上一页最后一条的 (点赞数, ID)，按最新排序时只使用 ID
+func (r *bookRatingRepository) ListFeed(ctx context.Context, q *ReviewFeedQuery) ([]*model.BookRating, error) {
+	query := r.DB(ctx).
+		Where("book_id = ? AND status = ? AND comment <> '' AND flagged = ?", q.BookId, model.RatingStatusApproved, false)
+	if q.RatingTypeId > 0 {
+		query = query.Where("rating_type_id = ?", q.RatingTypeId)
 	}
 
-	if err := r.DB(ctx).Where("book_id = ?", bookId).Offset(offset).Limit(pageSize).Find(&ratings).Error; err != nil {
-		return nil, 0, err
+	if q.OrderByLikes {
+		if q.AfterId > 0 {
+			query = query.Where("like_count < ? OR (like_count = ? AND id < ?)", q.AfterLikes, q.AfterLikes, q.AfterId)
+		}
+		query = query.Order("like_count DESC")
+	} else if q.AfterId > 0 {
+		query = query.Where("id < ?", q.AfterId)
 	}
 
-	return ratings, total, nil
+	var ratings []*model.BookRating
+	err := query.Order("id DESC").Limit(q.Limit).Find(&ratings).Error
+	return ratings, err
 }
 
 func (r *bookRatingRepository) GetRatingStats(ctx context.Context, bookId uint) ([]*model.RatingTypeCount, int64, error) {
@@ -75,14 +171,14 @@ func (r *bookRatingRepository) GetRatingStats(ctx context.Context, bookId uint)
 	var total int64
 
 	if err := r.DB(ctx).Model(&model.BookRating{}).
-		Where("book_id = ?", bookId).
+		Where("book_id = ? AND status = ? AND flagged = ?", bookId, model.RatingStatusApproved, false).
 		Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	if err := r.DB(ctx).Model(&model.BookRating{}).
 		Select("rating_type_id, count(*) as count").
-		Where("book_id = ?", bookId).
+		Where("book_id = ? AND status = ? AND flagged = ?", bookId, model.RatingStatusApproved, false).
 		Group("rating_type_id").
 		Scan(&stats).Error; err != nil {
 		return nil, 0, err
@@ -90,3 +186,112 @@ func (r *bookRatingRepository) GetRatingStats(ctx context.Context, bookId uint)
 
 	return stats, total, nil
 }
+
+// TopRated 按平均评分等级降序取榜单，评分数不足 minCount 的图书不参与排名
+// since 非零时只统计该时间之后的评分
+func (r *bookRatingRepository) TopRated(ctx context.Context, since time.Time, minCount int, limit int) ([]*model.BookRankItem, error) {
+	var items []*model.BookRankItem
+
+	query := r.DB(ctx).Table("book_ratings AS r").
+		Select("b.id AS book_id, b.title, b.author, b.cover, b.created_at, AVG(t.level) AS score").
+		Joins("JOIN rating_types AS t ON t.id = r.rating_type_id").
+		Joins("JOIN books AS b ON b.id = r.book_id AND b.deleted_at IS NULL AND b.hidden = ?", false).
+		Where("r.deleted_at IS NULL AND r.status = ? AND r.flagged = ?", model.RatingStatusApproved, false)
+	if !since.IsZero() {
+		query = query.Where("r.created_at >= ?", since)
+	}
+	err := query.Group("b.id, b.title, b.author, b.cover, b.created_at").
+		Having("COUNT(*) >= ?", minCount).
+		Order("score DESC").
+		Order("COUNT(*) DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+// DailyCounts 按天和评分类型统计某本书在 [from, to) 内审核通过的评分数
+func (r *bookRatingRepository) DailyCounts(ctx context.Context, bookId uint, from, to time.Time) ([]*model.RatingDailyCount, error) {
+	db := r.DB(ctx)
+	day := dateExpr(db, "created_at")
+
+	var counts []*model.RatingDailyCount
+	err := db.Model(&model.BookRating{}).
+		Select(day+" AS date, rating_type_id, COUNT(*) AS count").
+		Where("book_id = ? AND status = ? AND flagged = ? AND created_at >= ? AND created_at < ?", bookId, model.RatingStatusApproved, false, from, to).
+		Group(day + ", rating_type_id").
+		Order("date ASC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// ListByStatus 按审核状态分页获取带评论内容的评分，先提交的排在前面
+func (r *bookRatingRepository) ListByStatus(ctx context.Context, status string, page, pageSize int) ([]*model.BookRating, int64, error) {
+	var ratings []*model.BookRating
+	var total int64
+
+	offset := (page - 1) * pageSize
+	query := r.DB(ctx).Model(&model.BookRating{}).Where("status = ? AND comment <> ''", status)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("id ASC").Offset(offset).Limit(pageSize).Find(&ratings).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return ratings, total, nil
+}
+
+// UpdateStatus 批量修改评分的审核状态，返回实际修改的条数
+func (r *bookRatingRepository) UpdateStatus(ctx context.Context, ids []uint, status string) (int64, error) {
+	result := r.DB(ctx).Model(&model.BookRating{}).
+		Where("id IN ? AND status <> ?", ids, status).
+		Update("status", status)
+	return result.RowsAffected, result.Error
+}
+
+// ratingCounterColumns 可用于 IncrCounter 的计数列，列名会拼接进 SQL，只允许固定的列
+var ratingCounterColumns = map[string]bool{
+	"like_count":  true,
+	"reply_count": true,
+}
+
+// IncrCounter 调整评分上冗余存储的计数（like_count/reply_count），不会减为负数
+func (r *bookRatingRepository) IncrCounter(ctx context.Context, id uint, column string, delta int64) error {
+	if !ratingCounterColumns[column] {
+		return fmt.Errorf("invalid counter column %q", column)
+	}
+	query := r.DB(ctx).Model(&model.BookRating{}).Where("id = ?", id)
+	if delta < 0 {
+		query = query.Where(column+" >= ?", -delta)
+	}
+	return query.UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error
+}
+
+// ListSince 获取 since 之后创建的全部评分，按创建时间升序
+func (r *bookRatingRepository) ListSince(ctx context.Context, since time.Time) ([]*model.BookRating, error) {
+	var ratings []*model.BookRating
+	err := r.DB(ctx).Where("created_at >= ?", since).
+		Order("created_at ASC").
+		Order("id ASC").
+		Find(&ratings).Error
+	return ratings, err
+}
+
+// GetByIds 批量获取评分，包含已删除的评分，用于后台展示
+func (r *bookRatingRepository) GetByIds(ctx context.Context, ids []uint) ([]*model.BookRating, error) {
+	var ratings []*model.BookRating
+	if len(ids) == 0 {
+		return ratings, nil
+	}
+	err := r.DB(ctx).Unscoped().Where("id IN ?", ids).Find(&ratings).Error
+	return ratings, err
+}
+
+// SetFlagged 设置评分的可疑标记，不修改更新时间
+func (r *bookRatingRepository) SetFlagged(ctx context.Context, id uint, flagged bool) error {
+	return r.DB(ctx).Model(&model.BookRating{}).
+		Where("id = ?", id).
+		UpdateColumn("flagged", flagged).Error
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"novel-site-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BookRatingStatRepository interface {
+	Incr(ctx context.Context, bookId, ratingTypeId uint, delta int64) error
+	ListByBook(ctx context.Context, bookId uint) ([]*model.RatingTypeStat, error)
+	ListAll(ctx context.Context) ([]*model.BookRatingStat, error)
+	MoveType(ctx context.Context, fromTypeId, toTypeId uint) error
+	Rebuild(ctx context.Context) (int64, error)
+}
+
+type bookRatingStatRepository struct {
+	*Repository
+}
+
+func NewBookRatingStatRepository(r *Repository) BookRatingStatRepository {
+	return &bookRatingStatRepository{
+		Repository: r,
+	}
+}
+
+// Incr 调整某本书某个评分类型的评分数，应与评分的写入在同一事务内调用
+// 减少时不会把评分数减为负数
+func (r *bookRatingStatRepository) Incr(ctx context.Context, bookId, ratingTypeId uint, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	now := time.Now()
+	if delta < 0 {
+		return r.DB(ctx).Model(&model.BookRatingStat{}).
+			Where("book_id = ? AND rating_type_id = ? AND count >= ?", bookId, ratingTypeId, -delta).
+			UpdateColumns(map[string]interface{}{
+				"count":      gorm.Expr("count + ?", delta),
+				"updated_at": now,
+			}).Error
+	}
+	return r.DB(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "book_id"}, {Name: "rating_type_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":      gorm.Expr("count + ?", delta),
+			"updated_at": now,
+		}),
+	}).Create(&model.BookRatingStat{
+		BookId:       bookId,
+		RatingTypeId: ratingTypeId,
+		Count:        delta,
+		UpdatedAt:    now,
+	}).Error
+}
+
+// ListByBook 获取全部评分类型及某本书在各类型下的评分数，按评分等级降序
+func (r *bookRatingStatRepository) ListByBook(ctx context.Context, bookId uint) ([]*model.RatingTypeStat, error) {
+	var stats []*model.RatingTypeStat
+	err := r.DB(ctx).Table("rating_types AS rt").
+		Select("rt.id AS rating_type_id, rt.name, rt.description, rt.level, COALESCE(s.count, 0) AS count").
+		Joins("LEFT JOIN book_rating_stats AS s ON s.rating_type_id = rt.id AND s.book_id = ?", bookId).
+		Where("rt.deleted_at IS NULL").
+		Order("rt.display_order ASC").
+		Order("rt.level DESC").
+		Order("rt.id ASC").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// ListAll 获取全部评分数不为 0 的汇总
+func (r *bookRatingStatRepository) ListAll(ctx context.Context) ([]*model.BookRatingStat, error) {
+	var stats []*model.BookRatingStat
+	err := r.DB(ctx).Where("count > 0").Find(&stats).Error
+	return stats, err
+}
+
+// MoveType 把某个评分类型的全部汇总合并到另一个评分类型，应与评分的迁移在同一事务内调用
+func (r *bookRatingStatRepository) MoveType(ctx context.Context, fromTypeId, toTypeId uint) error {
+	var stats []*model.BookRatingStat
+	if err := r.DB(ctx).Where("rating_type_id = ?", fromTypeId).Find(&stats).Error; err != nil {
+		return err
+	}
+	for _, stat := range stats {
+		if err := r.Incr(ctx, stat.BookId, toTypeId, stat.Count); err != nil {
+			return err
+		}
+	}
+	return r.DB(ctx).Where("rating_type_id = ?", fromTypeId).Delete(&model.BookRatingStat{}).Error
+}
+
+// Rebuild 按评分表全量重建汇总表，被标记为可疑的评分不计入，用于修复汇总与评分不一致的数据，返回重建后的汇总条数
+func (r *bookRatingStatRepository) Rebuild(ctx context.Context) (int64, error) {
+	var rows int64
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Where("1 = 1").Delete(&model.BookRatingStat{}).Error; err != nil {
+			return err
+		}
+		result := r.DB(ctx).Exec(`INSERT INTO book_rating_stats (book_id, rating_type_id, count, updated_at)
+SELECT book_id, rating_type_id, COUNT(*), ? FROM book_ratings
+WHERE deleted_at IS NULL AND status = ? AND flagged = ?
+GROUP BY book_id, rating_type_id`, time.Now(), model.RatingStatusApproved, false)
+		if result.Error != nil {
+			return result.Error
+		}
+		rows = result.RowsAffected
+		return nil
+	})
+	return rows, err
+}
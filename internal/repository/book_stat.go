@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"novel-site-backend/internal/model"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BookStatRepository interface {
+	IncrDaily(ctx context.Context, bookId uint, date string, views, downloads int64) error
+	ListSince(ctx context.Context, since string) ([]*model.BookDailyStat, error)
+	TopSince(ctx context.Context, column string, since string, limit int) ([]*model.BookRankItem, error)
+	GetSketchForUpdate(ctx context.Context, bookId uint, date string) (*model.VisitorSketch, error)
+	SaveSketch(ctx context.Context, sketch *model.VisitorSketch) error
+	Series(ctx context.Context, bookId uint, from, to string) ([]*model.DailySeriesPoint, error)
+}
+
+type bookStatRepository struct {
+	*Repository
+}
+
+func NewBookStatRepository(r *Repository) BookStatRepository {
+	return &bookStatRepository{
+		Repository: r,
+	}
+}
+
+// IncrDaily 累加某本书某一天的浏览量和下载量，当天没有记录时插入
+func (r *bookStatRepository) IncrDaily(ctx context.Context, bookId uint, date string, views, downloads int64) error {
+	return r.DB(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "book_id"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"views":     gorm.Expr("views + ?", views),
+			"downloads": gorm.Expr("downloads + ?", downloads),
+		}),
+	}).Create(&model.BookDailyStat{
+		BookId:    bookId,
+		Date:      date,
+		Views:     views,
+		Downloads: downloads,
+	}).Error
+}
+
+// ListSince 获取 since（含）之后的全部每日统计
+func (r *bookStatRepository) ListSince(ctx context.Context, since string) ([]*model.BookDailyStat, error) {
+	var stats []*model.BookDailyStat
+	err := r.DB(ctx).Where("date >= ?", since).Find(&stats).Error
+	return stats, err
+}
+
+// statRankColumns 可用于 TopSince 排行的列，列名会拼接进 SQL，只允许固定的列
+var statRankColumns = map[string]bool{
+	"views":     true,
+	"downloads": true,
+}
+
+// TopSince 按 since（含）之后指定列的合计值降序取榜单
+func (r *bookStatRepository) TopSince(ctx context.Context, column string, since string, limit int) ([]*model.BookRankItem, error) {
+	if !statRankColumns[column] {
+		return nil, fmt.Errorf("invalid rank column %q", column)
+	}
+	var items []*model.BookRankItem
+	err := r.DB(ctx).Table("book_daily_stats AS s").
+		Select("b.id AS book_id, b.title, b.author, b.cover, b.created_at, SUM(s."+column+") AS score").
+		Joins("JOIN books AS b ON b.id = s.book_id AND b.deleted_at IS NULL AND b.hidden = ?", false).
+		Where("s.date >= ?", since).
+		Group("b.id, b.title, b.author, b.cover, b.created_at").
+		Having("SUM(s." + column + ") > 0").
+		Order("score DESC").
+		Order("b.id DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+// GetSketchForUpdate 加锁读取某天的访客草图，不存在时返回 nil
+func (r *bookStatRepository) GetSketchForUpdate(ctx context.Context, bookId uint, date string) (*model.VisitorSketch, error) {
+	var sketch model.VisitorSketch
+	err := r.DB(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("book_id = ? AND date = ?", bookId, date).
+		First(&sketch).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &sketch, nil
+}
+
+func (r *bookStatRepository) SaveSketch(ctx context.Context, sketch *model.VisitorSketch) error {
+	return r.DB(ctx).Save(sketch).Error
+}
+
+// Series 获取 [from, to] 区间内有数据的每日统计，bookId 为 0 时统计全站
+func (r *bookStatRepository) Series(ctx context.Context, bookId uint, from, to string) ([]*model.DailySeriesPoint, error) {
+	var points []*model.DailySeriesPoint
+
+	query := r.DB(ctx).Model(&model.BookDailyStat{}).
+		Where("date BETWEEN ? AND ?", from, to)
+	if bookId > 0 {
+		query = query.Select("date, views, downloads").Where("book_id = ?", bookId)
+	} else {
+		query = query.Select("date, SUM(views) AS views, SUM(downloads) AS downloads").Group("date")
+	}
+	if err := query.Scan(&points).Error; err != nil {
+		return nil, err
+	}
+
+	var sketches []*model.VisitorSketch
+	if err := r.DB(ctx).Select("date, estimate").
+		Where("book_id = ? AND date BETWEEN ? AND ?", bookId, from, to).
+		Find(&sketches).Error; err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]*model.DailySeriesPoint, len(points))
+	for _, point := range points {
+		byDate[point.Date] = point
+	}
+	for _, sketch := range sketches {
+		point, ok := byDate[sketch.Date]
+		if !ok {
+			point = &model.DailySeriesPoint{Date: sketch.Date}
+			byDate[sketch.Date] = point
+			points = append(points, point)
+		}
+		point.UniqueVisitors = sketch.Estimate
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Date < points[j].Date })
+	return points, nil
+}
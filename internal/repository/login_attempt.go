@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"novel-site-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptFilter 登录记录查询条件，零值表示不过滤
+type LoginAttemptFilter struct {
+	Account string
+	UserId  string
+	IP      string
+	Result  string
+}
+
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *model.LoginAttempt) error
+	List(ctx context.Context, filter LoginAttemptFilter, page, pageSize int) ([]*model.LoginAttempt, int64, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	GetThrottles(ctx context.Context, keys []string) (map[string]*model.LoginThrottle, error)
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration, update func(t *model.LoginThrottle)) (*model.LoginThrottle, error)
+	DeleteThrottle(ctx context.Context, key string) error
+}
+
+type loginAttemptRepository struct {
+	*Repository
+}
+
+func NewLoginAttemptRepository(r *Repository) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		Repository: r,
+	}
+}
+
+func (r *loginAttemptRepository) Create(ctx context.Context, attempt *model.LoginAttempt) error {
+	return r.DB(ctx).Create(attempt).Error
+}
+
+// List 分页获取登录记录，新的排在前面
+func (r *loginAttemptRepository) List(ctx context.Context, filter LoginAttemptFilter, page, pageSize int) ([]*model.LoginAttempt, int64, error) {
+	var attempts []*model.LoginAttempt
+	var total int64
+
+	query := r.DB(ctx).Model(&model.LoginAttempt{})
+	if filter.Account != "" {
+		query = query.Where("account = ?", filter.Account)
+	}
+	if filter.UserId != "" {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Result != "" {
+		query = query.Where("result = ?", filter.Result)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+// DeleteBefore 删除 before 之前的登录记录，以及之后没有再失败且未处于锁定中的计数
+func (r *loginAttemptRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.DB(ctx).Where("created_at < ?", before).Delete(&model.LoginAttempt{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	rows := result.RowsAffected
+	result = r.DB(ctx).
+		Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&model.LoginThrottle{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return rows + result.RowsAffected, nil
+}
+
+// GetThrottles 按 Key 批量获取失败计数，没有计数的 Key 不在结果中
+func (r *loginAttemptRepository) GetThrottles(ctx context.Context, keys []string) (map[string]*model.LoginThrottle, error) {
+	var throttles []*model.LoginThrottle
+	if err := r.DB(ctx).Where("throttle_key IN ?", keys).Find(&throttles).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]*model.LoginThrottle, len(throttles))
+	for _, t := range throttles {
+		result[t.Key] = t
+	}
+	return result, nil
+}
+
+// RecordFailure 失败次数加一，最后一次失败早于 window 时从头计数；update 可在保存前修改锁定时间
+// 计数在数据库中原子递增，递增后该行在事务结束前被锁定，并发的失败不会丢失计数
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration, update func(t *model.LoginThrottle)) (*model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		// 先插入空计数，避免并发的首次失败插入重复的 Key
+		err := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.LoginThrottle{Key: key}).Error
+		if err != nil {
+			return err
+		}
+		err = r.DB(ctx).Model(&model.LoginThrottle{}).
+			Where("throttle_key = ?", key).
+			Updates(map[string]interface{}{
+				"failures":       gorm.Expr("CASE WHEN last_failed_at < ? THEN 1 ELSE failures + 1 END", now.Add(-window)),
+				"last_failed_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		if err := r.DB(ctx).Where("throttle_key = ?", key).First(&throttle).Error; err != nil {
+			return err
+		}
+		update(&throttle)
+		return r.DB(ctx).Model(&throttle).Select("failures", "locked_until").Updates(&throttle).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *loginAttemptRepository) DeleteThrottle(ctx context.Context, key string) error {
+	return r.DB(ctx).Where("throttle_key = ?", key).Delete(&model.LoginThrottle{}).Error
+}
@@ -1,12 +1,27 @@
 package repository
 
 import (
-    "context"
+	"context"
+	"errors"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PermissionRepository interface {
-	GetPermission(ctx context.Context, id int64) (*model.Permission, error)
+	GetPermission(ctx context.Context, id uint) (*model.Permission, error)
+	GetUnscopedByCode(ctx context.Context, code string) (*model.Permission, error)
+	ListAll(ctx context.Context) ([]*model.Permission, error)
+	ListByIds(ctx context.Context, ids []uint) ([]*model.Permission, error)
+	ListUnscopedByType(ctx context.Context, typ string) ([]*model.Permission, error)
+	ListCodesByUser(ctx context.Context, userId string) ([]string, error)
+	CountChildren(ctx context.Context, id uint) (int64, error)
+	Create(ctx context.Context, permission *model.Permission) error
+	CreateIfNotExists(ctx context.Context, permission *model.Permission) (bool, error)
+	Save(ctx context.Context, permission *model.Permission) error
+	Delete(ctx context.Context, id uint) error
 }
 
 func NewPermissionRepository(
@@ -21,8 +36,90 @@ type permissionRepository struct {
 	*Repository
 }
 
-func (r *permissionRepository) GetPermission(ctx context.Context, id int64) (*model.Permission, error) {
+func (r *permissionRepository) GetPermission(ctx context.Context, id uint) (*model.Permission, error) {
 	var permission model.Permission
+	if err := r.DB(ctx).Where("id = ?", id).First(&permission).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &permission, nil
+}
 
+// GetUnscopedByCode 按编码获取权限，包括已删除的权限，不存在时返回 nil
+func (r *permissionRepository) GetUnscopedByCode(ctx context.Context, code string) (*model.Permission, error) {
+	var permission model.Permission
+	result := r.DB(ctx).Unscoped().Where("code = ?", code).Limit(1).Find(&permission)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
 	return &permission, nil
 }
+
+func (r *permissionRepository) ListAll(ctx context.Context) ([]*model.Permission, error) {
+	var permissions []*model.Permission
+	if err := r.DB(ctx).Order("id ASC").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *permissionRepository) ListByIds(ctx context.Context, ids []uint) ([]*model.Permission, error) {
+	var permissions []*model.Permission
+	if err := r.DB(ctx).Where("id IN ?", ids).Order("id ASC").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// ListUnscopedByType 获取某类型的全部权限，包括已删除的权限
+func (r *permissionRepository) ListUnscopedByType(ctx context.Context, typ string) ([]*model.Permission, error) {
+	var permissions []*model.Permission
+	if err := r.DB(ctx).Unscoped().Where("type = ?", typ).Order("id ASC").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// ListCodesByUser 获取用户通过角色获得的全部权限编码，已删除的角色不计入
+func (r *permissionRepository) ListCodesByUser(ctx context.Context, userId string) ([]string, error) {
+	var codes []string
+	err := r.DB(ctx).Model(&model.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id AND roles.deleted_at IS NULL").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("users.user_id = ?", userId).
+		Distinct().
+		Pluck("permissions.code", &codes).Error
+	return codes, err
+}
+
+func (r *permissionRepository) CountChildren(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.DB(ctx).Model(&model.Permission{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *permissionRepository) Create(ctx context.Context, permission *model.Permission) error {
+	return r.DB(ctx).Create(permission).Error
+}
+
+// CreateIfNotExists 编码不存在时创建权限，返回是否创建，用于多个实例同时启动时注册同一权限
+func (r *permissionRepository) CreateIfNotExists(ctx context.Context, permission *model.Permission) (bool, error) {
+	result := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(permission)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Save 保存权限的全部字段，可用于恢复已删除的权限
+func (r *permissionRepository) Save(ctx context.Context, permission *model.Permission) error {
+	return r.DB(ctx).Unscoped().Save(permission).Error
+}
+
+func (r *permissionRepository) Delete(ctx context.Context, id uint) error {
+	return r.DB(ctx).Delete(&model.Permission{}, id).Error
+}
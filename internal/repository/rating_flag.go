@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"novel-site-backend/internal/model"
+
+	"gorm.io/gorm/clause"
+)
+
+type RatingFlagRepository interface {
+	Create(ctx context.Context, flag *model.RatingFlag) (bool, error)
+	ListRatingIds(ctx context.Context, ratingIds []uint) (map[uint]bool, error)
+	ListByStatus(ctx context.Context, status string, page, pageSize int) ([]*model.RatingFlag, int64, error)
+	GetByIds(ctx context.Context, ids []uint, status string) ([]*model.RatingFlag, error)
+	UpdateStatus(ctx context.Context, id uint, status, operator string) error
+}
+
+type ratingFlagRepository struct {
+	*Repository
+}
+
+func NewRatingFlagRepository(r *Repository) RatingFlagRepository {
+	return &ratingFlagRepository{
+		Repository: r,
+	}
+}
+
+// Create 标记可疑评分，评分已被标记过时不做修改并返回 false
+func (r *ratingFlagRepository) Create(ctx context.Context, flag *model.RatingFlag) (bool, error) {
+	result := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(flag)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListRatingIds 返回给定评分中已被标记过（不论复核状态）的评分ID
+func (r *ratingFlagRepository) ListRatingIds(ctx context.Context, ratingIds []uint) (map[uint]bool, error) {
+	flagged := make(map[uint]bool)
+	if len(ratingIds) == 0 {
+		return flagged, nil
+	}
+	var ids []uint
+	if err := r.DB(ctx).Model(&model.RatingFlag{}).
+		Where("rating_id IN ?", ratingIds).
+		Pluck("rating_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		flagged[id] = true
+	}
+	return flagged, nil
+}
+
+// ListByStatus 按复核状态分页获取可疑评分，先标记的排在前面
+func (r *ratingFlagRepository) ListByStatus(ctx context.Context, status string, page, pageSize int) ([]*model.RatingFlag, int64, error) {
+	var flags []*model.RatingFlag
+	var total int64
+
+	query := r.DB(ctx).Model(&model.RatingFlag{}).Where("status = ?", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id ASC").Offset(offset).Limit(pageSize).Find(&flags).Error; err != nil {
+		return nil, 0, err
+	}
+	return flags, total, nil
+}
+
+// GetByIds 批量获取处于指定复核状态的可疑评分
+func (r *ratingFlagRepository) GetByIds(ctx context.Context, ids []uint, status string) ([]*model.RatingFlag, error) {
+	var flags []*model.RatingFlag
+	err := r.DB(ctx).Where("id IN ? AND status = ?", ids, status).Find(&flags).Error
+	return flags, err
+}
+
+// UpdateStatus 修改可疑评分的复核状态
+func (r *ratingFlagRepository) UpdateStatus(ctx context.Context, id uint, status, operator string) error {
+	return r.DB(ctx).Model(&model.RatingFlag{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":   status,
+			"operator": operator,
+		}).Error
+}
@@ -2,7 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
+
+	"gorm.io/gorm"
 )
 
 type RatingTypeRepository interface {
@@ -10,6 +14,7 @@ type RatingTypeRepository interface {
 	Update(ctx context.Context, rt *model.RatingType) error
 	Delete(ctx context.Context, id uint) error
 	GetByID(ctx context.Context, id uint) (*model.RatingType, error)
+	ExistsLevel(ctx context.Context, level int, excludeId uint) (bool, error)
 	List(ctx context.Context, page, pageSize int) ([]*model.RatingType, int64, error)
 }
 
@@ -23,35 +28,64 @@ func NewRatingTypeRepository(r *Repository) RatingTypeRepository {
 	}
 }
 
+// Create 创建评分类型，等级与其他评分类型重复时返回 ErrRatingLevelExists
 func (r *ratingTypeRepository) Create(ctx context.Context, rt *model.RatingType) error {
+	rt.ActiveLevel = &rt.Level
 	if err := r.DB(ctx).Create(rt).Error; err != nil {
-		return err
+		return r.conflictError(err)
 	}
 	return nil
 }
 
+// Update 保存评分类型，等级与其他评分类型重复时返回 ErrRatingLevelExists
 func (r *ratingTypeRepository) Update(ctx context.Context, rt *model.RatingType) error {
+	rt.ActiveLevel = &rt.Level
 	if err := r.DB(ctx).Save(rt).Error; err != nil {
-		return err
+		return r.conflictError(err)
 	}
 	return nil
 }
 
+// Delete 软删除评分类型，同时释放其等级
 func (r *ratingTypeRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.DB(ctx).Model(&model.RatingType{}).Where("id = ?", id).Update("active_level", nil).Error; err != nil {
+		return err
+	}
 	if err := r.DB(ctx).Delete(&model.RatingType{}, id).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
+// conflictError 把等级唯一索引冲突转换为 ErrRatingLevelExists
+func (r *ratingTypeRepository) conflictError(err error) error {
+	translator, ok := r.db.Dialector.(gorm.ErrorTranslator)
+	if ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return v1.ErrRatingLevelExists
+	}
+	return err
+}
+
 func (r *ratingTypeRepository) GetByID(ctx context.Context, id uint) (*model.RatingType, error) {
 	var rt model.RatingType
 	if err := r.DB(ctx).First(&rt, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
 		return nil, err
 	}
 	return &rt, nil
 }
 
+// ExistsLevel 判断除 excludeId 外是否已有该等级的评分类型，已删除的评分类型不计入
+func (r *ratingTypeRepository) ExistsLevel(ctx context.Context, level int, excludeId uint) (bool, error) {
+	var count int64
+	err := r.DB(ctx).Model(&model.RatingType{}).
+		Where("level = ? AND id <> ?", level, excludeId).
+		Count(&count).Error
+	return count > 0, err
+}
+
 func (r *ratingTypeRepository) List(ctx context.Context, page, pageSize int) ([]*model.RatingType, int64, error) {
 	var rts []*model.RatingType
 	var total int64
@@ -62,7 +96,12 @@ func (r *ratingTypeRepository) List(ctx context.Context, page, pageSize int) ([]
 		return nil, 0, err
 	}
 
-	if err := r.DB(ctx).Offset(offset).Limit(pageSize).Find(&rts).Error; err != nil {
+	if err := r.DB(ctx).Order("display_order ASC").
+		Order("level DESC").
+		Order("id ASC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&rts).Error; err != nil {
 		return nil, 0, err
 	}
 
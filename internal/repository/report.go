@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+type ReportRepository interface {
+	Create(ctx context.Context, report *model.Report) error
+	CountOpen(ctx context.Context, targetType string, targetId uint) (int64, error)
+	CountByIPSince(ctx context.Context, ip string, since time.Time) (int64, error)
+	ListTargets(ctx context.Context, status, targetType string, page, pageSize int) ([]*model.ReportTargetSummary, int64, error)
+	ListByTarget(ctx context.Context, targetType string, targetId uint) ([]*model.Report, error)
+	CloseByTarget(ctx context.Context, targetType string, targetId uint, status string) (int64, error)
+	CreateAudit(ctx context.Context, audit *model.ReportAudit) error
+	ListAudits(ctx context.Context, targetType string, targetId uint) ([]*model.ReportAudit, error)
+}
+
+type reportRepository struct {
+	*Repository
+}
+
+func NewReportRepository(r *Repository) ReportRepository {
+	return &reportRepository{
+		Repository: r,
+	}
+}
+
+// Create 创建举报，同一举报人重复举报同一对象时返回 ErrReportDuplicate
+func (r *reportRepository) Create(ctx context.Context, report *model.Report) error {
+	result := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(report)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return v1.ErrReportDuplicate
+	}
+	return nil
+}
+
+// CountOpen 统计某个对象待处理的举报数
+// 登录用户按用户计数，匿名访客的访客ID可以随意更换，按IP去重计数，没有IP的匿名举报不计入
+func (r *reportRepository) CountOpen(ctx context.Context, targetType string, targetId uint) (int64, error) {
+	var users, ips int64
+	err := r.DB(ctx).Model(&model.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetId, model.ReportStatusOpen).
+		Where("reporter LIKE ?", "u:%").
+		Distinct("reporter").
+		Count(&users).Error
+	if err != nil {
+		return 0, err
+	}
+	err = r.DB(ctx).Model(&model.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetId, model.ReportStatusOpen).
+		Where("reporter LIKE ? AND ip <> ''", "v:%").
+		Distinct("ip").
+		Count(&ips).Error
+	return users + ips, err
+}
+
+// CountByIPSince 统计某个IP在指定时间之后提交的举报数
+func (r *reportRepository) CountByIPSince(ctx context.Context, ip string, since time.Time) (int64, error) {
+	var count int64
+	err := r.DB(ctx).Model(&model.Report{}).
+		Where("ip = ? AND created_at >= ?", ip, since).
+		Count(&count).Error
+	return count, err
+}
+
+// ListTargets 按举报对象汇总指定状态的举报，举报数多的排在前面
+func (r *reportRepository) ListTargets(ctx context.Context, status, targetType string, page, pageSize int) ([]*model.ReportTargetSummary, int64, error) {
+	var items []*model.ReportTargetSummary
+	var total int64
+
+	query := r.DB(ctx).Model(&model.Report{}).
+		Select("target_type, target_id, COUNT(*) AS reports, MAX(id) AS latest_id").
+		Where("status = ?", status).
+		Group("target_type, target_id")
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	if err := r.DB(ctx).Table("(?) AS t", query).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("reports DESC").
+		Order("latest_id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Scan(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(items) == 0 {
+		return items, total, nil
+	}
+
+	// 聚合出的时间在部分数据库驱动下无法扫描为 time.Time，按最近一次举报的ID回查
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.LatestId)
+	}
+	var latest []*model.Report
+	if err := r.DB(ctx).Select("id, created_at").Where("id IN ?", ids).Find(&latest).Error; err != nil {
+		return nil, 0, err
+	}
+	createdAt := make(map[uint]time.Time, len(latest))
+	for _, report := range latest {
+		createdAt[report.Id] = report.CreatedAt
+	}
+	for _, item := range items {
+		item.LatestAt = createdAt[item.LatestId]
+	}
+
+	return items, total, nil
+}
+
+func (r *reportRepository) ListByTarget(ctx context.Context, targetType string, targetId uint) ([]*model.Report, error) {
+	var reports []*model.Report
+	err := r.DB(ctx).
+		Where("target_type = ? AND target_id = ?", targetType, targetId).
+		Order("id DESC").
+		Find(&reports).Error
+	return reports, err
+}
+
+// CloseByTarget 把某个对象的全部待处理举报改为指定状态，返回修改的条数
+func (r *reportRepository) CloseByTarget(ctx context.Context, targetType string, targetId uint, status string) (int64, error) {
+	result := r.DB(ctx).Model(&model.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetId, model.ReportStatusOpen).
+		Update("status", status)
+	return result.RowsAffected, result.Error
+}
+
+func (r *reportRepository) CreateAudit(ctx context.Context, audit *model.ReportAudit) error {
+	return r.DB(ctx).Create(audit).Error
+}
+
+func (r *reportRepository) ListAudits(ctx context.Context, targetType string, targetId uint) ([]*model.ReportAudit, error) {
+	var audits []*model.ReportAudit
+	err := r.DB(ctx).
+		Where("target_type = ? AND target_id = ?", targetType, targetId).
+		Order("id ASC").
+		Find(&audits).Error
+	return audits, err
+}
@@ -54,8 +54,9 @@ func (r *Repository) DB(ctx context.Context) *gorm.DB {
 	return r.db.WithContext(ctx)
 }
 
+// Transaction 在事务中执行 fn，ctx 中已有事务时以保存点嵌套在该事务内
 func (r *Repository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return r.DB(ctx).Transaction(func(tx *gorm.DB) error {
 		ctx = context.WithValue(ctx, ctxTxKey, tx)
 		return fn(ctx)
 	})
@@ -119,3 +120,16 @@ func NewRedis(conf *viper.Viper) *redis.Client {
 
 	return rdb
 }
+
+// dateExpr 返回把时间列格式化为 2006-01-02 的 SQL 表达式，按存储的本地时间取日期
+// sqlite 驱动以文本保存时间，直接截取日期部分
+func dateExpr(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "DATE_FORMAT(" + column + ", '%Y-%m-%d')"
+	case "postgres":
+		return "TO_CHAR(" + column + ", 'YYYY-MM-DD')"
+	default:
+		return "SUBSTR(" + column + ", 1, 10)"
+	}
+}
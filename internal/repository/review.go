@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReviewRepository interface {
+	CreateReply(ctx context.Context, reply *model.ReviewReply) error
+	GetReply(ctx context.Context, id uint) (*model.ReviewReply, error)
+	GetRepliesByIds(ctx context.Context, ids []uint) ([]*model.ReviewReply, error)
+	ListReplies(ctx context.Context, ratingId uint, limit int) ([]*model.ReviewReply, error)
+	ListRepliesByStatus(ctx context.Context, status string, page, pageSize int) ([]*model.ReviewReply, int64, error)
+	UpdateReplyStatus(ctx context.Context, ids []uint, status string) error
+	AddLike(ctx context.Context, ratingId uint, voter, ip string) (bool, error)
+	CountLikesByIPSince(ctx context.Context, ip string, since time.Time) (int64, error)
+	RemoveLike(ctx context.Context, ratingId uint, voter string) (bool, error)
+}
+
+type reviewRepository struct {
+	*Repository
+}
+
+func NewReviewRepository(r *Repository) ReviewRepository {
+	return &reviewRepository{
+		Repository: r,
+	}
+}
+
+func (r *reviewRepository) CreateReply(ctx context.Context, reply *model.ReviewReply) error {
+	return r.DB(ctx).Create(reply).Error
+}
+
+func (r *reviewRepository) GetReply(ctx context.Context, id uint) (*model.ReviewReply, error) {
+	var reply model.ReviewReply
+	if err := r.DB(ctx).First(&reply, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &reply, nil
+}
+
+func (r *reviewRepository) GetRepliesByIds(ctx context.Context, ids []uint) ([]*model.ReviewReply, error) {
+	var replies []*model.ReviewReply
+	err := r.DB(ctx).Where("id IN ?", ids).Find(&replies).Error
+	return replies, err
+}
+
+// ListReplies 获取某条评论下审核通过的回复，按时间先后排序
+func (r *reviewRepository) ListReplies(ctx context.Context, ratingId uint, limit int) ([]*model.ReviewReply, error) {
+	var replies []*model.ReviewReply
+	err := r.DB(ctx).
+		Where("rating_id = ? AND status = ?", ratingId, model.RatingStatusApproved).
+		Order("id ASC").
+		Limit(limit).
+		Find(&replies).Error
+	return replies, err
+}
+
+// ListRepliesByStatus 按审核状态分页获取回复，先提交的排在前面
+func (r *reviewRepository) ListRepliesByStatus(ctx context.Context, status string, page, pageSize int) ([]*model.ReviewReply, int64, error) {
+	var replies []*model.ReviewReply
+	var total int64
+
+	offset := (page - 1) * pageSize
+	query := r.DB(ctx).Model(&model.ReviewReply{}).Where("status = ?", status)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("id ASC").Offset(offset).Limit(pageSize).Find(&replies).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return replies, total, nil
+}
+
+func (r *reviewRepository) UpdateReplyStatus(ctx context.Context, ids []uint, status string) error {
+	return r.DB(ctx).Model(&model.ReviewReply{}).
+		Where("id IN ?", ids).
+		Update("status", status).Error
+}
+
+// AddLike 点赞，已点赞过时返回 false
+func (r *reviewRepository) AddLike(ctx context.Context, ratingId uint, voter, ip string) (bool, error) {
+	result := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ReviewLike{
+		RatingId: ratingId,
+		Voter:    voter,
+		IP:       ip,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountLikesByIPSince 统计某个IP在指定时间之后的点赞数，取消的点赞不计入
+func (r *reviewRepository) CountLikesByIPSince(ctx context.Context, ip string, since time.Time) (int64, error) {
+	var count int64
+	err := r.DB(ctx).Model(&model.ReviewLike{}).
+		Where("ip = ? AND created_at >= ?", ip, since).
+		Count(&count).Error
+	return count, err
+}
+
+// RemoveLike 取消点赞，未点赞过时返回 false
+func (r *reviewRepository) RemoveLike(ctx context.Context, ratingId uint, voter string) (bool, error) {
+	result := r.DB(ctx).
+		Where("rating_id = ? AND voter = ?", ratingId, voter).
+		Delete(&model.ReviewLike{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -1,12 +1,26 @@
 package repository
 
 import (
-    "context"
+	"context"
+	"errors"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
+
+	"gorm.io/gorm"
 )
 
 type RoleRepository interface {
-	GetRole(ctx context.Context, id int64) (*model.Role, error)
+	GetRole(ctx context.Context, id uint) (*model.Role, error)
+	GetByCode(ctx context.Context, code string) (*model.Role, error)
+	GetUnscopedByCode(ctx context.Context, code string) (*model.Role, error)
+	ExistsName(ctx context.Context, name string, excludeId uint) (bool, error)
+	ListByIds(ctx context.Context, ids []uint) ([]*model.Role, error)
+	ListByUser(ctx context.Context, id uint) ([]*model.Role, error)
+	ListCodesByUser(ctx context.Context, userId string) ([]string, error)
+	List(ctx context.Context, page, pageSize int) ([]*model.Role, int64, error)
+	Create(ctx context.Context, role *model.Role) error
+	Save(ctx context.Context, role *model.Role) error
+	Delete(ctx context.Context, id uint) error
 }
 
 func NewRoleRepository(
@@ -21,8 +35,104 @@ type roleRepository struct {
 	*Repository
 }
 
-func (r *roleRepository) GetRole(ctx context.Context, id int64) (*model.Role, error) {
+func (r *roleRepository) GetRole(ctx context.Context, id uint) (*model.Role, error) {
 	var role model.Role
+	if err := r.DB(ctx).Where("id = ?", id).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
 
+func (r *roleRepository) GetByCode(ctx context.Context, code string) (*model.Role, error) {
+	var role model.Role
+	if err := r.DB(ctx).Where("code = ?", code).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
 	return &role, nil
 }
+
+// GetUnscopedByCode 按编码获取角色，包括已删除的角色，不存在时返回 nil
+func (r *roleRepository) GetUnscopedByCode(ctx context.Context, code string) (*model.Role, error) {
+	var role model.Role
+	result := r.DB(ctx).Unscoped().Where("code = ?", code).Limit(1).Find(&role)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &role, nil
+}
+
+// ExistsName 判断除 excludeId 外是否已有该名称的角色，名称有唯一索引，已删除的角色也计入
+func (r *roleRepository) ExistsName(ctx context.Context, name string, excludeId uint) (bool, error) {
+	var count int64
+	err := r.DB(ctx).Unscoped().Model(&model.Role{}).
+		Where("name = ? AND id <> ?", name, excludeId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *roleRepository) ListByIds(ctx context.Context, ids []uint) ([]*model.Role, error) {
+	var roles []*model.Role
+	if err := r.DB(ctx).Where("id IN ?", ids).Order("id ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// ListByUser 获取用户拥有的角色，id 为用户表主键
+func (r *roleRepository) ListByUser(ctx context.Context, id uint) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := r.DB(ctx).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", id).
+		Order("roles.id ASC").
+		Find(&roles).Error
+	return roles, err
+}
+
+// ListCodesByUser 获取用户拥有的角色编码，userId 为对外的用户ID
+func (r *roleRepository) ListCodesByUser(ctx context.Context, userId string) ([]string, error) {
+	var codes []string
+	err := r.DB(ctx).Model(&model.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("users.user_id = ?", userId).
+		Pluck("roles.code", &codes).Error
+	return codes, err
+}
+
+func (r *roleRepository) List(ctx context.Context, page, pageSize int) ([]*model.Role, int64, error) {
+	var roles []*model.Role
+	var total int64
+
+	if err := r.DB(ctx).Model(&model.Role{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := r.DB(ctx).Order("id ASC").Offset(offset).Limit(pageSize).Find(&roles).Error; err != nil {
+		return nil, 0, err
+	}
+	return roles, total, nil
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *model.Role) error {
+	return r.DB(ctx).Omit("Permissions").Create(role).Error
+}
+
+// Save 保存角色的全部字段，可用于恢复已删除的角色
+func (r *roleRepository) Save(ctx context.Context, role *model.Role) error {
+	return r.DB(ctx).Unscoped().Omit("Permissions").Save(role).Error
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id uint) error {
+	return r.DB(ctx).Delete(&model.Role{}, id).Error
+}
@@ -1,12 +1,21 @@
 package repository
 
 import (
-    "context"
+	"context"
+	"errors"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
+
+	"gorm.io/gorm"
 )
 
 type RolePermissionRepository interface {
-	GetRolePermission(ctx context.Context, id int64) (*model.RolePermission, error)
+	GetRolePermission(ctx context.Context, id uint) (*model.RolePermission, error)
+	ListPermissionIds(ctx context.Context, roleId uint) ([]uint, error)
+	Grant(ctx context.Context, roleId uint, permissionIds []uint) (int64, error)
+	Revoke(ctx context.Context, roleId uint, permissionIds []uint) (int64, error)
+	DeleteByRole(ctx context.Context, roleId uint) error
+	DeleteByPermission(ctx context.Context, permissionId uint) error
 }
 
 func NewRolePermissionRepository(
@@ -21,8 +30,67 @@ type rolePermissionRepository struct {
 	*Repository
 }
 
-func (r *rolePermissionRepository) GetRolePermission(ctx context.Context, id int64) (*model.RolePermission, error) {
+func (r *rolePermissionRepository) GetRolePermission(ctx context.Context, id uint) (*model.RolePermission, error) {
 	var rolePermission model.RolePermission
-
+	if err := r.DB(ctx).Where("id = ?", id).First(&rolePermission).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
 	return &rolePermission, nil
 }
+
+func (r *rolePermissionRepository) ListPermissionIds(ctx context.Context, roleId uint) ([]uint, error) {
+	var ids []uint
+	err := r.DB(ctx).Model(&model.RolePermission{}).
+		Where("role_id = ?", roleId).
+		Order("permission_id ASC").
+		Pluck("permission_id", &ids).Error
+	return ids, err
+}
+
+// Grant 给角色授予权限，已有的权限跳过，返回新授予的数量
+func (r *rolePermissionRepository) Grant(ctx context.Context, roleId uint, permissionIds []uint) (int64, error) {
+	var granted int64
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		existing, err := r.ListPermissionIds(ctx, roleId)
+		if err != nil {
+			return err
+		}
+		has := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			has[id] = true
+		}
+		var rows []*model.RolePermission
+		for _, id := range permissionIds {
+			if has[id] {
+				continue
+			}
+			has[id] = true
+			rows = append(rows, &model.RolePermission{RoleId: roleId, PermissionId: id})
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		granted = int64(len(rows))
+		return r.DB(ctx).Create(&rows).Error
+	})
+	return granted, err
+}
+
+// Revoke 收回角色的权限，返回实际收回的数量
+func (r *rolePermissionRepository) Revoke(ctx context.Context, roleId uint, permissionIds []uint) (int64, error) {
+	result := r.DB(ctx).
+		Where("role_id = ? AND permission_id IN ?", roleId, permissionIds).
+		Delete(&model.RolePermission{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *rolePermissionRepository) DeleteByRole(ctx context.Context, roleId uint) error {
+	return r.DB(ctx).Where("role_id = ?", roleId).Delete(&model.RolePermission{}).Error
+}
+
+func (r *rolePermissionRepository) DeleteByPermission(ctx context.Context, permissionId uint) error {
+	return r.DB(ctx).Where("permission_id = ?", permissionId).Delete(&model.RolePermission{}).Error
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"novel-site-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+type SensitiveWordRepository interface {
+	Upsert(ctx context.Context, words []*model.SensitiveWord) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context, page, pageSize int) ([]*model.SensitiveWord, int64, error)
+	ListAll(ctx context.Context) ([]*model.SensitiveWord, error)
+	Version(ctx context.Context) (int64, time.Time, error)
+}
+
+type sensitiveWordRepository struct {
+	*Repository
+}
+
+func NewSensitiveWordRepository(r *Repository) SensitiveWordRepository {
+	return &sensitiveWordRepository{
+		Repository: r,
+	}
+}
+
+// Upsert 批量添加敏感词，已存在的词更新处理方式
+func (r *sensitiveWordRepository) Upsert(ctx context.Context, words []*model.SensitiveWord) error {
+	return r.DB(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "word"}},
+		DoUpdates: clause.AssignmentColumns([]string{"action", "updated_at"}),
+	}).Create(&words).Error
+}
+
+func (r *sensitiveWordRepository) Delete(ctx context.Context, id uint) error {
+	return r.DB(ctx).Delete(&model.SensitiveWord{}, id).Error
+}
+
+func (r *sensitiveWordRepository) List(ctx context.Context, page, pageSize int) ([]*model.SensitiveWord, int64, error) {
+	var words []*model.SensitiveWord
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	if err := r.DB(ctx).Model(&model.SensitiveWord{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.DB(ctx).Order("id DESC").Offset(offset).Limit(pageSize).Find(&words).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return words, total, nil
+}
+
+func (r *sensitiveWordRepository) ListAll(ctx context.Context) ([]*model.SensitiveWord, error) {
+	var words []*model.SensitiveWord
+	err := r.DB(ctx).Find(&words).Error
+	return words, err
+}
+
+// Version 返回敏感词数量和最近修改时间，两者任一变化说明词库已被修改
+func (r *sensitiveWordRepository) Version(ctx context.Context) (int64, time.Time, error) {
+	var count int64
+	if err := r.DB(ctx).Model(&model.SensitiveWord{}).Count(&count).Error; err != nil {
+		return 0, time.Time{}, err
+	}
+	var latest model.SensitiveWord
+	if count > 0 {
+		if err := r.DB(ctx).Select("updated_at").Order("updated_at DESC").Limit(1).Find(&latest).Error; err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+	return count, latest.UpdatedAt, nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error)
+	GetRefreshTokenByAccessJti(ctx context.Context, jti string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	ListActiveByFamily(ctx context.Context, familyId string, now time.Time) ([]*model.RefreshToken, error)
+	ListActiveByUser(ctx context.Context, userId string, now time.Time) ([]*model.RefreshToken, error)
+	RevokeFamilies(ctx context.Context, familyIds []string, revokedAt time.Time) error
+	RevokeAccessTokens(ctx context.Context, tokens []*model.RevokedToken) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+
+	CreateUserToken(ctx context.Context, token *model.UserToken) error
+	GetUserTokenByHash(ctx context.Context, purpose, hash string) (*model.UserToken, error)
+	GetLatestUserToken(ctx context.Context, userId, purpose string) (*model.UserToken, error)
+	MarkUserTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	InvalidateUserTokens(ctx context.Context, userId, purpose string, usedAt time.Time) error
+}
+
+type tokenRepository struct {
+	*Repository
+}
+
+func NewTokenRepository(r *Repository) TokenRepository {
+	return &tokenRepository{
+		Repository: r,
+	}
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	return r.DB(ctx).Create(token).Error
+}
+
+func (r *tokenRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	return r.getRefreshToken(ctx, "token_hash = ?", hash)
+}
+
+// GetRefreshTokenByAccessJti 按同时签发的访问令牌ID查找刷新令牌
+func (r *tokenRepository) GetRefreshTokenByAccessJti(ctx context.Context, jti string) (*model.RefreshToken, error) {
+	return r.getRefreshToken(ctx, "access_jti = ?", jti)
+}
+
+func (r *tokenRepository) getRefreshToken(ctx context.Context, query string, args ...interface{}) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.DB(ctx).Where(query, args...).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed 把未使用且未吊销的刷新令牌标记为已使用，令牌已被使用或吊销时返回 false
+func (r *tokenRepository) MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.DB(ctx).Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		UpdateColumn("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListActiveByFamily 获取令牌族中访问令牌尚未过期的刷新令牌
+func (r *tokenRepository) ListActiveByFamily(ctx context.Context, familyId string, now time.Time) ([]*model.RefreshToken, error) {
+	var tokens []*model.RefreshToken
+	err := r.DB(ctx).Where("family_id = ? AND (access_expires_at > ? OR expires_at > ?)", familyId, now, now).
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// ListActiveByUser 获取用户全部访问令牌或刷新令牌尚未过期的刷新令牌
+func (r *tokenRepository) ListActiveByUser(ctx context.Context, userId string, now time.Time) ([]*model.RefreshToken, error) {
+	var tokens []*model.RefreshToken
+	err := r.DB(ctx).Where("user_id = ? AND (access_expires_at > ? OR expires_at > ?)", userId, now, now).
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeFamilies 吊销令牌族中全部尚未吊销的刷新令牌
+func (r *tokenRepository) RevokeFamilies(ctx context.Context, familyIds []string, revokedAt time.Time) error {
+	if len(familyIds) == 0 {
+		return nil
+	}
+	return r.DB(ctx).Model(&model.RefreshToken{}).
+		Where("family_id IN ? AND revoked_at IS NULL", familyIds).
+		UpdateColumn("revoked_at", revokedAt).Error
+}
+
+// RevokeAccessTokens 把访问令牌加入吊销列表，已在列表中的忽略
+func (r *tokenRepository) RevokeAccessTokens(ctx context.Context, tokens []*model.RevokedToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&tokens).Error
+}
+
+func (r *tokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	err := r.DB(ctx).Model(&model.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteExpired 清理 before 之前过期的刷新令牌、吊销记录和一次性令牌，返回清理的条数
+func (r *tokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var rows int64
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		result := r.DB(ctx).Where("expires_at < ? AND access_expires_at < ?", before, before).Delete(&model.RefreshToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		rows = result.RowsAffected
+		result = r.DB(ctx).Where("expires_at < ?", before).Delete(&model.RevokedToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		rows += result.RowsAffected
+		result = r.DB(ctx).Where("expires_at < ?", before).Delete(&model.UserToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		rows += result.RowsAffected
+		return nil
+	})
+	return rows, err
+}
+
+func (r *tokenRepository) CreateUserToken(ctx context.Context, token *model.UserToken) error {
+	return r.DB(ctx).Create(token).Error
+}
+
+func (r *tokenRepository) GetUserTokenByHash(ctx context.Context, purpose, hash string) (*model.UserToken, error) {
+	var token model.UserToken
+	if err := r.DB(ctx).Where("purpose = ? AND token_hash = ?", purpose, hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetLatestUserToken 获取用户最近签发的一次性令牌，不存在时返回 nil
+func (r *tokenRepository) GetLatestUserToken(ctx context.Context, userId, purpose string) (*model.UserToken, error) {
+	var token model.UserToken
+	if err := r.DB(ctx).Where("user_id = ? AND purpose = ?", userId, purpose).Order("id DESC").First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUserTokenUsed 把未使用的一次性令牌标记为已使用，令牌已被使用时返回 false
+func (r *tokenRepository) MarkUserTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.DB(ctx).Model(&model.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		UpdateColumn("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// InvalidateUserTokens 作废用户某一用途的全部未使用令牌
+func (r *tokenRepository) InvalidateUserTokens(ctx context.Context, userId, purpose string, usedAt time.Time) error {
+	return r.DB(ctx).Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userId, purpose).
+		UpdateColumn("used_at", usedAt).Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"novel-site-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository interface {
+	GetByUser(ctx context.Context, userId string) (*model.UserTwoFactor, error)
+	Save(ctx context.Context, tf *model.UserTwoFactor) error
+	Delete(ctx context.Context, userId string) error
+	AdvanceStep(ctx context.Context, id uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId string, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userId, hash string, usedAt time.Time) (bool, error)
+}
+
+type twoFactorRepository struct {
+	*Repository
+}
+
+func NewTwoFactorRepository(r *Repository) TwoFactorRepository {
+	return &twoFactorRepository{
+		Repository: r,
+	}
+}
+
+// GetByUser 获取用户的两步验证配置，未设置时返回 nil
+func (r *twoFactorRepository) GetByUser(ctx context.Context, userId string) (*model.UserTwoFactor, error) {
+	var tf model.UserTwoFactor
+	if err := r.DB(ctx).Where("user_id = ?", userId).First(&tf).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+func (r *twoFactorRepository) Save(ctx context.Context, tf *model.UserTwoFactor) error {
+	return r.DB(ctx).Save(tf).Error
+}
+
+// Delete 删除用户的两步验证配置和恢复码
+func (r *twoFactorRepository) Delete(ctx context.Context, userId string) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return r.DB(ctx).Where("user_id = ?", userId).Delete(&model.UserTwoFactor{}).Error
+	})
+}
+
+// AdvanceStep 记录通过校验的时间步，step 不晚于已记录的时间步时说明验证码被重放，返回 false
+func (r *twoFactorRepository) AdvanceStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := r.DB(ctx).Model(&model.UserTwoFactor{}).
+		Where("id = ? AND last_step < ?", id, step).
+		UpdateColumn("last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReplaceRecoveryCodes 删除用户原有的恢复码并保存新的恢复码
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId string, hashes []string) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]*model.RecoveryCode, 0, len(hashes))
+		for _, hash := range hashes {
+			codes = append(codes, &model.RecoveryCode{UserId: userId, CodeHash: hash})
+		}
+		return r.DB(ctx).Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode 使用一个未使用的恢复码，恢复码不存在或已使用时返回 false
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userId, hash string, usedAt time.Time) (bool, error) {
+	result := r.DB(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, hash).
+		UpdateColumn("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
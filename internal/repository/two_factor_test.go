@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/pkg/log"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return NewRepository(&log.Logger{Logger: zap.NewNop()}, db)
+}
+
+func TestTwoFactorAdvanceStepRejectsReplay(t *testing.T) {
+	r := newTestRepository(t)
+	require.NoError(t, r.db.AutoMigrate(&model.UserTwoFactor{}))
+	repo := NewTwoFactorRepository(r)
+	ctx := context.Background()
+
+	tf := &model.UserTwoFactor{UserId: "u1", Secret: "secret"}
+	require.NoError(t, repo.Save(ctx, tf))
+
+	ok, err := repo.AdvanceStep(ctx, tf.Id, 100)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 同一时间步和更早的时间步都视为重放
+	ok, err = repo.AdvanceStep(ctx, tf.Id, 100)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = repo.AdvanceStep(ctx, tf.Id, 99)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.AdvanceStep(ctx, tf.Id, 101)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	saved, err := repo.GetByUser(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(101), saved.LastStep)
+}
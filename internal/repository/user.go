@@ -6,6 +6,7 @@ import (
 	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"gorm.io/gorm"
+	"strings"
 )
 
 type UserRepository interface {
@@ -13,6 +14,9 @@ type UserRepository interface {
 	Update(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id string) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetRoleCodes(ctx context.Context, id uint) ([]string, error)
+	ListByUserIds(ctx context.Context, userIds []string) ([]*model.User, error)
 }
 
 func NewUserRepository(
@@ -27,20 +31,36 @@ type userRepository struct {
 	*Repository
 }
 
+// Create 创建用户，用户名或邮箱与已有用户重复时返回 ErrUsernameAlreadyUse 或 ErrEmailAlreadyUse
 func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 	if err := r.DB(ctx).Create(user).Error; err != nil {
-		return err
+		return r.conflictError(err)
 	}
 	return nil
 }
 
+// Update 保存用户，修改后的邮箱与其他用户重复时返回 ErrEmailAlreadyUse
 func (r *userRepository) Update(ctx context.Context, user *model.User) error {
 	if err := r.DB(ctx).Save(user).Error; err != nil {
-		return err
+		return r.conflictError(err)
 	}
 	return nil
 }
 
+// conflictError 把唯一索引冲突转换为用户名或邮箱已被使用的错误
+// 各数据库的错误信息都以冲突的索引或列名结尾，按最后出现的列名区分
+func (r *userRepository) conflictError(err error) error {
+	translator, ok := r.db.Dialector.(gorm.ErrorTranslator)
+	if !ok || !errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return err
+	}
+	msg := err.Error()
+	if strings.LastIndex(msg, "email") > strings.LastIndex(msg, "username") {
+		return v1.ErrEmailAlreadyUse
+	}
+	return v1.ErrUsernameAlreadyUse
+}
+
 func (r *userRepository) GetByID(ctx context.Context, userId string) (*model.User, error) {
 	var user model.User
 	if err := r.DB(ctx).Where("user_id = ?", userId).First(&user).Error; err != nil {
@@ -62,3 +82,34 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.U
 	}
 	return &user, nil
 }
+
+// GetByUsername 按用户名查找用户，不区分大小写，不存在时返回 nil
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	var user model.User
+	if err := r.DB(ctx).Where("LOWER(username) = ?", strings.ToLower(username)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetRoleCodes 获取用户拥有的角色编码，id 为用户表主键
+func (r *userRepository) GetRoleCodes(ctx context.Context, id uint) ([]string, error) {
+	var codes []string
+	err := r.DB(ctx).Model(&model.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", id).
+		Pluck("roles.code", &codes).Error
+	return codes, err
+}
+
+// ListByUserIds 按对外的用户ID批量获取用户，不存在的用户不在结果中
+func (r *userRepository) ListByUserIds(ctx context.Context, userIds []string) ([]*model.User, error) {
+	var users []*model.User
+	if err := r.DB(ctx).Where("user_id IN ?", userIds).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"novel-site-backend/internal/model"
+	"time"
+)
+
+// UserBanFilter 封禁记录查询条件，零值表示不过滤
+type UserBanFilter struct {
+	UserId     string
+	OperatorId string
+	Action     string
+}
+
+type UserBanRepository interface {
+	Create(ctx context.Context, ban *model.UserBan) error
+	List(ctx context.Context, filter UserBanFilter, page, pageSize int) ([]*model.UserBan, int64, error)
+	ListExpiredUsers(ctx context.Context, now time.Time, limit int) ([]*model.User, error)
+	LiftBan(ctx context.Context, user *model.User) (bool, error)
+	SetBanStatus(ctx context.Context, id uint, status int, reason string, bannedUntil *time.Time) error
+}
+
+type userBanRepository struct {
+	*Repository
+}
+
+func NewUserBanRepository(r *Repository) UserBanRepository {
+	return &userBanRepository{
+		Repository: r,
+	}
+}
+
+func (r *userBanRepository) Create(ctx context.Context, ban *model.UserBan) error {
+	return r.DB(ctx).Create(ban).Error
+}
+
+// List 分页获取封禁记录，新的排在前面
+func (r *userBanRepository) List(ctx context.Context, filter UserBanFilter, page, pageSize int) ([]*model.UserBan, int64, error) {
+	var bans []*model.UserBan
+	var total int64
+
+	query := r.DB(ctx).Model(&model.UserBan{})
+	if filter.UserId != "" {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if filter.OperatorId != "" {
+		query = query.Where("operator_id = ?", filter.OperatorId)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&bans).Error; err != nil {
+		return nil, 0, err
+	}
+	return bans, total, nil
+}
+
+// ListExpiredUsers 获取封禁已到期但仍处于禁用状态的用户
+func (r *userBanRepository) ListExpiredUsers(ctx context.Context, now time.Time, limit int) ([]*model.User, error) {
+	var users []*model.User
+	err := r.DB(ctx).
+		Where("status = ? AND banned_until IS NOT NULL AND banned_until <= ?", model.UserStatusDisabled, now).
+		Order("id").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// LiftBan 把用户恢复为正常状态，只在用户的封禁信息未被修改时生效，返回是否更新成功
+func (r *userBanRepository) LiftBan(ctx context.Context, user *model.User) (bool, error) {
+	query := r.DB(ctx).Model(&model.User{}).
+		Where("id = ? AND status = ?", user.Id, model.UserStatusDisabled)
+	if user.BannedUntil == nil {
+		query = query.Where("banned_until IS NULL")
+	} else {
+		query = query.Where("banned_until = ?", *user.BannedUntil)
+	}
+	result := query.Updates(map[string]interface{}{
+		"status":       model.UserStatusNormal,
+		"ban_reason":   "",
+		"banned_until": nil,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetBanStatus 只修改用户的状态、封禁原因和封禁截止时间，id 为用户表主键
+func (r *userBanRepository) SetBanStatus(ctx context.Context, id uint, status int, reason string, bannedUntil *time.Time) error {
+	return r.DB(ctx).Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"ban_reason":   reason,
+			"banned_until": bannedUntil,
+		}).Error
+}
@@ -1,12 +1,19 @@
 package repository
 
 import (
-    "context"
+	"context"
+	"errors"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
+
+	"gorm.io/gorm"
 )
 
 type UserRoleRepository interface {
-	GetUserRole(ctx context.Context, id int64) (*model.UserRole, error)
+	GetUserRole(ctx context.Context, id uint) (*model.UserRole, error)
+	CountByRoles(ctx context.Context, roleIds []uint) (map[uint]int64, error)
+	Grant(ctx context.Context, userIds []uint, roleIds []uint) (int64, error)
+	Revoke(ctx context.Context, userIds []uint, roleIds []uint) (int64, error)
 }
 
 func NewUserRoleRepository(
@@ -21,8 +28,77 @@ type userRoleRepository struct {
 	*Repository
 }
 
-func (r *userRoleRepository) GetUserRole(ctx context.Context, id int64) (*model.UserRole, error) {
+func (r *userRoleRepository) GetUserRole(ctx context.Context, id uint) (*model.UserRole, error) {
 	var userRole model.UserRole
-
+	if err := r.DB(ctx).Where("id = ?", id).First(&userRole).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.ErrNotFound
+		}
+		return nil, err
+	}
 	return &userRole, nil
 }
+
+// CountByRoles 统计每个角色的用户数，没有用户的角色不在结果中
+func (r *userRoleRepository) CountByRoles(ctx context.Context, roleIds []uint) (map[uint]int64, error) {
+	var rows []struct {
+		RoleId uint
+		Count  int64
+	}
+	err := r.DB(ctx).Model(&model.UserRole{}).
+		Select("role_id, COUNT(*) AS count").
+		Where("role_id IN ?", roleIds).
+		Group("role_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.RoleId] = row.Count
+	}
+	return counts, nil
+}
+
+// Grant 给每个用户授予每个角色，已有的跳过，返回新授予的数量
+func (r *userRoleRepository) Grant(ctx context.Context, userIds []uint, roleIds []uint) (int64, error) {
+	var granted int64
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		var existing []*model.UserRole
+		err := r.DB(ctx).
+			Where("user_id IN ? AND role_id IN ?", userIds, roleIds).
+			Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		has := make(map[[2]uint]bool, len(existing))
+		for _, ur := range existing {
+			has[[2]uint{ur.UserId, ur.RoleId}] = true
+		}
+		var rows []*model.UserRole
+		for _, userId := range userIds {
+			for _, roleId := range roleIds {
+				key := [2]uint{userId, roleId}
+				if has[key] {
+					continue
+				}
+				has[key] = true
+				rows = append(rows, &model.UserRole{UserId: userId, RoleId: roleId})
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		granted = int64(len(rows))
+		return r.DB(ctx).Create(&rows).Error
+	})
+	return granted, err
+}
+
+// Revoke 收回每个用户的每个角色，返回实际收回的数量
+func (r *userRoleRepository) Revoke(ctx context.Context, userIds []uint, roleIds []uint) (int64, error) {
+	result := r.DB(ctx).
+		Where("user_id IN ? AND role_id IN ?", userIds, roleIds).
+		Delete(&model.UserRole{})
+	return result.RowsAffected, result.Error
+}
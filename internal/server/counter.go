@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"novel-site-backend/internal/service"
+	"novel-site-backend/pkg/log"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// CounterFlusher 定时将浏览量、下载量计数缓冲区写回数据库，停止时再写回一次
+type CounterFlusher struct {
+	log         *log.Logger
+	bookService service.BookService
+	interval    time.Duration
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func NewCounterFlusher(
+	log *log.Logger,
+	conf *viper.Viper,
+	bookService service.BookService,
+) *CounterFlusher {
+	interval := conf.GetDuration("data.counter.flush_interval")
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &CounterFlusher{
+		log:         log,
+		bookService: bookService,
+		interval:    interval,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (f *CounterFlusher) Start(ctx context.Context) error {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.flush()
+		case <-f.stop:
+			return nil
+		}
+	}
+}
+
+func (f *CounterFlusher) Stop(ctx context.Context) error {
+	close(f.stop)
+	<-f.done
+	f.flush()
+	f.log.Info("CounterFlusher stop...")
+	return nil
+}
+
+func (f *CounterFlusher) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := f.bookService.FlushCounters(ctx); err != nil {
+		f.log.Error("flush counters error", zap.Error(err))
+	}
+}
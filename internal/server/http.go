@@ -4,6 +4,8 @@ import (
 	apiV1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/handler"
 	"novel-site-backend/internal/middleware"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/service"
 	"novel-site-backend/pkg/jwt"
 	"novel-site-backend/pkg/log"
 	"novel-site-backend/pkg/server/http"
@@ -16,14 +18,34 @@ func NewHTTPServer(
 	logger *log.Logger,
 	conf *viper.Viper,
 	jwt *jwt.JWT,
+	tokenService service.TokenService,
+	permissionService service.PermissionService,
 	userHandler *handler.UserHandler,
 	bookHandler *handler.BookHandler,
 	bookRatingHandler *handler.BookRatingHandler,
 	ratingTypeHandler *handler.RatingTypeHandler,
+	rankingHandler *handler.RankingHandler,
+	analyticsHandler *handler.AnalyticsHandler,
+	moderationHandler *handler.ModerationHandler,
+	reviewHandler *handler.ReviewHandler,
+	reportHandler *handler.ReportHandler,
+	ratingFraudHandler *handler.RatingFraudHandler,
+	loginAttemptHandler *handler.LoginAttemptHandler,
+	twoFactorHandler *handler.TwoFactorHandler,
+	roleHandler *handler.RoleHandler,
+	permissionHandler *handler.PermissionHandler,
+	rolePermissionHandler *handler.RolePermissionHandler,
+	userRoleHandler *handler.UserRoleHandler,
+	userBanHandler *handler.UserBanHandler,
 ) *http.Server {
 	gin.SetMode(gin.DebugMode)
+	engine := gin.Default()
+	// 只信任反向代理转发的客户端IP，未配置时使用连接的对端地址
+	if err := engine.SetTrustedProxies(conf.GetStringSlice("http.trusted_proxies")); err != nil {
+		panic(err)
+	}
 	s := http.NewServer(
-		gin.Default(),
+		engine,
 		logger,
 		http.WithServerHost(conf.GetString("http.host")),
 		http.WithServerPort(conf.GetInt("http.port")),
@@ -45,48 +67,156 @@ func NewHTTPServer(
 		middleware.ClientIPMiddleware(logger),
 		//middleware.SignMiddleware(log),
 	)
-	s.GET("/", func(ctx *gin.Context) {
+	// 接口说明，同步接口权限时作为权限名称
+	docs := newRouteDocs()
+	desc := docs.describe
+
+	s.GET("/", desc("欢迎页", func(ctx *gin.Context) {
 		logger.WithContext(ctx).Info("hello")
 		apiV1.HandleSuccess(ctx, map[string]interface{}{
 			":)": "Thank you for using novel-site-backend!",
 		})
-	})
+	}))
+	// 公钥集合，供其他服务校验本服务签发的令牌
+	s.GET("/.well-known/jwks.json", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.jwks")), desc("获取签名公钥", func(ctx *gin.Context) {
+		ctx.JSON(200, jwt.JWKS())
+	}))
 
+	// 管理接口按权限编码校验，也可以给角色授予单个接口的权限，只有经过 perm 注册的接口会同步为接口权限
+	perm := func(code string, handler gin.HandlerFunc) []gin.HandlerFunc {
+		docs.guard(handler)
+		return []gin.HandlerFunc{middleware.RequirePermission(permissionService, logger, code), handler}
+	}
 	v1 := s.Group("/v1")
 	{
 		// No route group has permission
 		noAuthRouter := v1.Group("/")
 		{
-			// noAuthRouter.POST("/register", userHandler.Register)
-			// noAuthRouter.POST("/login", userHandler.Login)
+			noAuthRouter.POST("/register", desc("用户注册", userHandler.Register))
+			noAuthRouter.POST("/login", desc("用户登录", userHandler.Login))
+			noAuthRouter.POST("/login/2fa", desc("两步验证登录", twoFactorHandler.LoginVerify))
+			noAuthRouter.POST("/login/2fa/setup", desc("登录时设置两步验证", twoFactorHandler.LoginSetup))
+			noAuthRouter.POST("/token/refresh", desc("刷新令牌", userHandler.RefreshToken))
+			noAuthRouter.POST("/email/verify", desc("验证邮箱", userHandler.VerifyEmail))
+			noAuthRouter.POST("/password/forgot", desc("发送重置密码邮件", userHandler.ForgotPassword))
+			noAuthRouter.POST("/password/reset", desc("重置密码", userHandler.ResetPassword))
 			// noAuthRouter.POST("/books", bookHandler.CreateBook)
-			// noAuthRouter.PUT("/books/:id", bookHandler.UpdateBook)
-			// noAuthRouter.DELETE("/books/:id", bookHandler.DeleteBook)
-			noAuthRouter.GET("/books/:id", bookHandler.GetBook)
-			noAuthRouter.POST("/books/list", bookHandler.ListBooks)
-			noAuthRouter.POST("/books/search", bookHandler.QuickSearch)
+			noAuthRouter.GET("/books/:id", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.book")), desc("获取书籍详情", bookHandler.GetBook))
+			noAuthRouter.POST("/books/:id/download", desc("下载书籍", bookHandler.DownloadBook))
+			noAuthRouter.POST("/books/list", desc("获取书籍列表", bookHandler.ListBooks))
+			noAuthRouter.POST("/books/search", desc("快速搜索书籍", bookHandler.QuickSearch))
 
 			// 评分类型相关接口
-			noAuthRouter.GET("/rating-types", ratingTypeHandler.ListRatingTypes)
+			noAuthRouter.GET("/rating-types", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.rating_types")), desc("获取评分类型列表", ratingTypeHandler.ListRatingTypes))
+			noAuthRouter.GET("/rating-types/:id", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.rating_types")), desc("获取评分类型详情", ratingTypeHandler.GetRatingType))
 
 			// 书籍评分相关接口
-			noAuthRouter.POST("/book-ratings", bookRatingHandler.CreateBookRating)
+			noAuthRouter.POST("/book-ratings",
+				middleware.NoStrictAuth(jwt, tokenService, logger),
+				middleware.VisitorMiddleware(conf),
+				desc("创建书籍评分", bookRatingHandler.CreateBookRating),
+			)
 			// noAuthRouter.PUT("/book-ratings/:id", bookRatingHandler.UpdateBookRating)
-			noAuthRouter.GET("/book-ratings/:book_id/rating-stats", bookRatingHandler.GetBookRating)
-			// noAuthRouter.GET("/books/:book_id/ratings", bookRatingHandler.ListBookRatings)
-			noAuthRouter.GET("/books/sorts", bookHandler.GetAllSorts)
+			noAuthRouter.GET("/book-ratings/:book_id/rating-stats", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.rating_stats")), desc("获取书籍评分统计", bookRatingHandler.GetBookRating))
+			noAuthRouter.GET("/books/:id/reviews", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.reviews")), desc("获取书籍评论列表", bookRatingHandler.ListBookReviews))
+			noAuthRouter.GET("/books/:id/rating-trend", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.rating_trend")), desc("获取书籍评分趋势", bookRatingHandler.GetRatingTrend))
+
+			// 评论互动接口
+			noAuthRouter.GET("/reviews/:id", desc("获取评论及回复", reviewHandler.GetThread))
+			reviewRouter := noAuthRouter.Group("/reviews/:id", middleware.NoStrictAuth(jwt, tokenService, logger), middleware.VisitorMiddleware(conf))
+			{
+				reviewRouter.POST("/replies", desc("回复评论", reviewHandler.CreateReply))
+				reviewRouter.POST("/like", desc("点赞评论", reviewHandler.Like))
+				reviewRouter.DELETE("/like", desc("取消点赞评论", reviewHandler.Unlike))
+			}
+
+			// 举报接口
+			noAuthRouter.POST("/reports",
+				middleware.NoStrictAuth(jwt, tokenService, logger),
+				middleware.VisitorMiddleware(conf),
+				desc("举报", reportHandler.CreateReport),
+			)
+
+			noAuthRouter.GET("/books/sorts", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.sorts")), desc("获取所有书籍分类", bookHandler.GetAllSorts))
+
+			// 榜单接口
+			noAuthRouter.GET("/rankings/:board", middleware.HTTPCacheMiddleware(conf.GetString("http.cache.rankings")), desc("获取榜单", rankingHandler.GetRanking))
+		}
+		// Strict permission routing group
+		strictAuthRouter := v1.Group("/").Use(middleware.StrictAuth(jwt, tokenService, logger))
+		{
+			strictAuthRouter.GET("/user", desc("获取用户信息", userHandler.GetProfile))
+			strictAuthRouter.PUT("/user", desc("更新用户信息", userHandler.UpdateProfile))
+			strictAuthRouter.POST("/logout", desc("注销", userHandler.Logout))
+			strictAuthRouter.POST("/user/email/verification", desc("发送邮箱验证邮件", userHandler.SendVerificationEmail))
+			strictAuthRouter.POST("/user/2fa/setup", desc("设置两步验证", twoFactorHandler.Setup))
+			strictAuthRouter.POST("/user/2fa/enable", desc("启用两步验证", twoFactorHandler.Enable))
+			strictAuthRouter.POST("/user/2fa/disable", desc("关闭两步验证", twoFactorHandler.Disable))
+			strictAuthRouter.POST("/user/2fa/recovery-codes", desc("重新生成恢复码", twoFactorHandler.RegenerateRecoveryCodes))
+
+			// 书籍管理接口，需要携带 If-Match 头
+			strictAuthRouter.PUT("/books/:id", perm(model.PermBookManage, desc("更新书籍", bookHandler.UpdateBook))...)
+			strictAuthRouter.DELETE("/books/:id", perm(model.PermBookManage, desc("删除书籍", bookHandler.DeleteBook))...)
+
+			// 统计接口
+			strictAuthRouter.GET("/admin/analytics/books/:id", perm(model.PermAnalyticsView, desc("获取图书每日统计", analyticsHandler.GetBookAnalytics))...)
+			strictAuthRouter.GET("/admin/analytics/site", perm(model.PermAnalyticsView, desc("获取全站每日统计", analyticsHandler.GetSiteAnalytics))...)
+
+			// 评分类型管理接口
+			strictAuthRouter.POST("/admin/rating-types", perm(model.PermRatingTypeManage, desc("创建评分类型", ratingTypeHandler.CreateRatingType))...)
+			strictAuthRouter.PUT("/admin/rating-types/:id", perm(model.PermRatingTypeManage, desc("更新评分类型", ratingTypeHandler.UpdateRatingType))...)
+			strictAuthRouter.DELETE("/admin/rating-types/:id", perm(model.PermRatingTypeManage, desc("删除评分类型", ratingTypeHandler.DeleteRatingType))...)
+
+			// 评论审核接口
+			strictAuthRouter.GET("/admin/reviews", perm(model.PermReviewModerate, desc("获取评论审核队列", moderationHandler.ListReviewQueue))...)
+			strictAuthRouter.POST("/admin/reviews/moderate", perm(model.PermReviewModerate, desc("批量审核评论", moderationHandler.ModerateReviews))...)
+			strictAuthRouter.GET("/admin/replies", perm(model.PermReviewModerate, desc("获取回复审核队列", reviewHandler.ListReplyQueue))...)
+			strictAuthRouter.POST("/admin/replies/moderate", perm(model.PermReviewModerate, desc("批量审核回复", reviewHandler.ModerateReplies))...)
+
+			// 可疑评分复核接口
+			strictAuthRouter.GET("/admin/rating-flags", perm(model.PermRatingFlagManage, desc("获取可疑评分列表", ratingFraudHandler.ListRatingFlags))...)
+			strictAuthRouter.POST("/admin/rating-flags/remove", perm(model.PermRatingFlagManage, desc("批量删除可疑评分", ratingFraudHandler.RemoveFlaggedRatings))...)
+			strictAuthRouter.POST("/admin/rating-flags/clear", perm(model.PermRatingFlagManage, desc("批量恢复误判的可疑评分", ratingFraudHandler.ClearRatingFlags))...)
+
+			// 登录审计接口
+			strictAuthRouter.GET("/admin/login-attempts", perm(model.PermLoginAttemptView, desc("获取登录记录", loginAttemptHandler.ListLoginAttempts))...)
+
+			// 用户封禁接口
+			strictAuthRouter.POST("/admin/users/:user_id/ban", perm(model.PermUserBan, desc("封禁用户", userBanHandler.BanUser))...)
+			strictAuthRouter.POST("/admin/users/:user_id/unban", perm(model.PermUserBan, desc("解封用户", userBanHandler.UnbanUser))...)
+			strictAuthRouter.GET("/admin/user-bans", perm(model.PermUserBan, desc("获取封禁记录", userBanHandler.ListUserBans))...)
+
+			// 角色和权限管理接口
+			strictAuthRouter.GET("/admin/roles", perm(model.PermRoleManage, desc("获取角色列表", roleHandler.ListRoles))...)
+			strictAuthRouter.GET("/admin/roles/:id", perm(model.PermRoleManage, desc("获取角色详情", roleHandler.GetRole))...)
+			strictAuthRouter.POST("/admin/roles", perm(model.PermRoleManage, desc("创建角色", roleHandler.CreateRole))...)
+			strictAuthRouter.PUT("/admin/roles/:id", perm(model.PermRoleManage, desc("更新角色", roleHandler.UpdateRole))...)
+			strictAuthRouter.DELETE("/admin/roles/:id", perm(model.PermRoleManage, desc("删除角色", roleHandler.DeleteRole))...)
+			strictAuthRouter.POST("/admin/roles/:id/permissions", perm(model.PermRoleManage, desc("给角色授予权限", rolePermissionHandler.GrantPermissions))...)
+			strictAuthRouter.PUT("/admin/roles/:id/permissions", perm(model.PermRoleManage, desc("设置角色的权限", rolePermissionHandler.SetPermissions))...)
+			strictAuthRouter.POST("/admin/roles/:id/permissions/revoke", perm(model.PermRoleManage, desc("收回角色的权限", rolePermissionHandler.RevokePermissions))...)
+			strictAuthRouter.GET("/admin/users/:user_id/roles", perm(model.PermRoleManage, desc("获取用户的角色", userRoleHandler.GetUserRoles))...)
+			strictAuthRouter.POST("/admin/user-roles", perm(model.PermRoleManage, desc("批量授予用户角色", userRoleHandler.GrantRoles))...)
+			strictAuthRouter.POST("/admin/user-roles/revoke", perm(model.PermRoleManage, desc("批量收回用户角色", userRoleHandler.RevokeRoles))...)
+			// 分配角色权限时需要读取权限树
+			strictAuthRouter.GET("/admin/permissions", perm(model.PermRoleManage, desc("获取权限树", permissionHandler.GetPermissionTree))...)
+			strictAuthRouter.GET("/admin/permissions/:id", perm(model.PermRoleManage, desc("获取权限详情", permissionHandler.GetPermission))...)
+			strictAuthRouter.POST("/admin/permissions", perm(model.PermPermissionManage, desc("创建权限", permissionHandler.CreatePermission))...)
+			strictAuthRouter.PUT("/admin/permissions/:id", perm(model.PermPermissionManage, desc("更新权限", permissionHandler.UpdatePermission))...)
+			strictAuthRouter.DELETE("/admin/permissions/:id", perm(model.PermPermissionManage, desc("删除权限", permissionHandler.DeletePermission))...)
+
+			// 举报处理接口
+			strictAuthRouter.GET("/admin/reports", perm(model.PermReportManage, desc("获取举报处理列表", reportHandler.ListReportTargets))...)
+			strictAuthRouter.GET("/admin/reports/:target_type/:target_id", perm(model.PermReportManage, desc("获取举报对象的举报和处理记录", reportHandler.GetReportTarget))...)
+			strictAuthRouter.POST("/admin/reports/:target_type/:target_id/resolve", perm(model.PermReportManage, desc("处理举报", reportHandler.ResolveReports))...)
+			strictAuthRouter.GET("/admin/sensitive-words", perm(model.PermSensitiveWordManage, desc("获取敏感词列表", moderationHandler.ListSensitiveWords))...)
+			strictAuthRouter.POST("/admin/sensitive-words", perm(model.PermSensitiveWordManage, desc("批量添加敏感词", moderationHandler.AddSensitiveWords))...)
+			strictAuthRouter.DELETE("/admin/sensitive-words/:id", perm(model.PermSensitiveWordManage, desc("删除敏感词", moderationHandler.DeleteSensitiveWord))...)
 		}
-		// // Non-strict permission routing group
-		// noStrictAuthRouter := v1.Group("/").Use(middleware.NoStrictAuth(jwt, logger))
-		// {
-		// 	noStrictAuthRouter.GET("/user", userHandler.GetProfile)
-		// }
-
-		// // Strict permission routing group
-		// strictAuthRouter := v1.Group("/").Use(middleware.StrictAuth(jwt, logger))
-		// {
-		// 	strictAuthRouter.PUT("/user", userHandler.UpdateProfile)
-		// }
+	}
+
+	if conf.GetBool("rbac.sync_api_permissions") {
+		syncAPIPermissions(s.Engine, docs, permissionService, logger)
 	}
 
 	return s
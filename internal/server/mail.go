@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+	"novel-site-backend/internal/service"
+	"novel-site-backend/pkg/log"
+)
+
+// MailWorker 运行后台邮件队列的 worker，停止时等待已提交的邮件任务执行完
+type MailWorker struct {
+	log   *log.Logger
+	queue service.MailQueue
+}
+
+func NewMailWorker(log *log.Logger, queue service.MailQueue) *MailWorker {
+	return &MailWorker{
+		log:   log,
+		queue: queue,
+	}
+}
+
+func (w *MailWorker) Start(ctx context.Context) error {
+	w.queue.Run()
+	return nil
+}
+
+func (w *MailWorker) Stop(ctx context.Context) error {
+	err := w.queue.Close(ctx)
+	w.log.Info("MailWorker stop...")
+	return err
+}
@@ -2,33 +2,344 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/pkg/log"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"os"
+	"strings"
 )
 
 type Migrate struct {
-	db  *gorm.DB
-	log *log.Logger
+	db   *gorm.DB
+	conf *viper.Viper
+	log  *log.Logger
 }
 
-func NewMigrate(db *gorm.DB, log *log.Logger) *Migrate {
+func NewMigrate(db *gorm.DB, conf *viper.Viper, log *log.Logger) *Migrate {
 	return &Migrate{
-		db:  db,
-		log: log,
+		db:   db,
+		conf: conf,
+		log:  log,
 	}
 }
 func (m *Migrate) Start(ctx context.Context) error {
+	if err := m.normalizeUsers(); err != nil {
+		m.log.Error("normalize users error", zap.Error(err))
+		return err
+	}
 	if err := m.db.AutoMigrate(&model.User{}); err != nil {
 		m.log.Error("user migrate error", zap.Error(err))
 		return err
 	}
+	if err := m.createUsernameIndex(); err != nil {
+		m.log.Error("username index migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.Role{}, &model.Permission{}, &model.RolePermission{}, &model.UserRole{}); err != nil {
+		m.log.Error("rbac migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.seedRBAC(); err != nil {
+		m.log.Error("rbac seed error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.RefreshToken{}, &model.RevokedToken{}, &model.UserToken{}); err != nil {
+		m.log.Error("token migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.UserTwoFactor{}, &model.RecoveryCode{}); err != nil {
+		m.log.Error("two factor migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.LoginAttempt{}, &model.LoginThrottle{}); err != nil {
+		m.log.Error("login attempt migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.UserBan{}); err != nil {
+		m.log.Error("user ban migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.Book{}); err != nil {
+		m.log.Error("book migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.dedupeBookRatings(); err != nil {
+		m.log.Error("dedupe book ratings error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.RatingType{}, &model.BookRating{}); err != nil {
+		m.log.Error("rating migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.backfillRatingTypeLevels(); err != nil {
+		m.log.Error("backfill rating type levels error", zap.Error(err))
+		return err
+	}
+	if err := m.backfillRatingVoterKeys(); err != nil {
+		m.log.Error("backfill rating voter keys error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.ReviewReply{}, &model.ReviewLike{}); err != nil {
+		m.log.Error("review migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.RatingFlag{}); err != nil {
+		m.log.Error("rating flag migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.Report{}, &model.ReportAudit{}); err != nil {
+		m.log.Error("report migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.SensitiveWord{}); err != nil {
+		m.log.Error("sensitive word migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.BookRatingStat{}); err != nil {
+		m.log.Error("book rating stat migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.BookDailyStat{}); err != nil {
+		m.log.Error("book daily stat migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.VisitorSketch{}); err != nil {
+		m.log.Error("visitor sketch migrate error", zap.Error(err))
+		return err
+	}
 	m.log.Info("AutoMigrate success")
 	os.Exit(0)
 	return nil
 }
+
+// defaultPermissions 内置权限，保护对应的管理接口
+var defaultPermissions = []model.Permission{
+	{Name: "书籍管理", Code: model.PermBookManage, Type: model.PermissionTypeMenu, Description: "修改和删除书籍"},
+	{Name: "数据统计", Code: model.PermAnalyticsView, Type: model.PermissionTypeMenu, Description: "查看书籍和全站统计"},
+	{Name: "评分类型管理", Code: model.PermRatingTypeManage, Type: model.PermissionTypeMenu, Description: "新增、修改和删除评分类型"},
+	{Name: "评论审核", Code: model.PermReviewModerate, Type: model.PermissionTypeMenu, Description: "审核评论和回复"},
+	{Name: "可疑评分复核", Code: model.PermRatingFlagManage, Type: model.PermissionTypeMenu, Description: "复核被标记为可疑的评分"},
+	{Name: "举报处理", Code: model.PermReportManage, Type: model.PermissionTypeMenu, Description: "查看和处理举报"},
+	{Name: "敏感词管理", Code: model.PermSensitiveWordManage, Type: model.PermissionTypeMenu, Description: "维护敏感词库"},
+	{Name: "登录审计", Code: model.PermLoginAttemptView, Type: model.PermissionTypeMenu, Description: "查看登录记录"},
+	{Name: "用户封禁", Code: model.PermUserBan, Type: model.PermissionTypeMenu, Description: "封禁和解封用户"},
+	{Name: "角色管理", Code: model.PermRoleManage, Type: model.PermissionTypeMenu, Description: "管理角色、角色权限和用户角色"},
+	{Name: "权限管理", Code: model.PermPermissionManage, Type: model.PermissionTypeMenu, Description: "维护权限树"},
+}
+
+// defaultRoles 内置角色及其权限，管理员不依赖权限列表，拥有全部权限
+var defaultRoles = []struct {
+	role        model.Role
+	permissions []string
+}{
+	{
+		role:        model.Role{Name: "管理员", Code: model.RoleAdmin, Description: "拥有全部权限"},
+		permissions: []string{model.PermBookManage, model.PermAnalyticsView, model.PermRatingTypeManage, model.PermReviewModerate, model.PermRatingFlagManage, model.PermReportManage, model.PermSensitiveWordManage, model.PermLoginAttemptView, model.PermUserBan, model.PermRoleManage, model.PermPermissionManage},
+	},
+	{
+		role:        model.Role{Name: "编辑", Code: model.RoleEditor, Description: "管理书籍和评分类型"},
+		permissions: []string{model.PermBookManage, model.PermAnalyticsView, model.PermRatingTypeManage},
+	},
+	{
+		role:        model.Role{Name: "审核员", Code: model.RoleModerator, Description: "处理评论、举报和可疑评分，封禁用户"},
+		permissions: []string{model.PermReviewModerate, model.PermRatingFlagManage, model.PermReportManage, model.PermSensitiveWordManage, model.PermUserBan},
+	},
+	{
+		role: model.Role{Name: "读者", Code: model.RoleReader, Description: "普通读者"},
+	},
+}
+
+// seedRBAC 写入内置角色和权限，只补充缺少的数据，不覆盖管理员的修改
+// 被删除的内置角色不会重新创建；rbac.admins 中的用户名或邮箱会被授予管理员角色
+func (m *Migrate) seedRBAC() error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		permIds := make(map[string]uint, len(defaultPermissions))
+		for _, p := range defaultPermissions {
+			perm := p
+			if err := tx.Unscoped().Where("code = ?", perm.Code).Attrs(perm).FirstOrCreate(&perm).Error; err != nil {
+				return err
+			}
+			if !perm.DeletedAt.Valid {
+				permIds[perm.Code] = perm.Id
+			}
+		}
+
+		roleIds := make(map[string]uint, len(defaultRoles))
+		for _, r := range defaultRoles {
+			role := r.role
+			var existing model.Role
+			result := tx.Unscoped().Where("code = ?", role.Code).Limit(1).Find(&existing)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				if !existing.DeletedAt.Valid {
+					roleIds[role.Code] = existing.Id
+				}
+				continue
+			}
+			if err := tx.Omit("Permissions").Create(&role).Error; err != nil {
+				return err
+			}
+			roleIds[role.Code] = role.Id
+			// 只有新建的角色才写入默认权限
+			for _, code := range r.permissions {
+				permId, ok := permIds[code]
+				if !ok {
+					continue
+				}
+				if err := tx.Create(&model.RolePermission{RoleId: role.Id, PermissionId: permId}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		adminId, ok := roleIds[model.RoleAdmin]
+		if !ok {
+			return nil
+		}
+		for _, account := range m.conf.GetStringSlice("rbac.admins") {
+			account = strings.TrimSpace(account)
+			var user model.User
+			err := tx.Where("LOWER(username) = ? OR email = ?", strings.ToLower(account), strings.ToLower(account)).First(&user).Error
+			if err == gorm.ErrRecordNotFound {
+				m.log.Warn("rbac admin not found", zap.String("account", account))
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.Where(model.UserRole{UserId: user.Id, RoleId: adminId}).FirstOrCreate(&model.UserRole{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// dedupeBookRatings 创建评分唯一索引前删除同一评分者对同一本书的重复评分，只保留最新的一条
+// 有评分被删除时需要执行 go run ./cmd/rebuild 重建评分汇总
+func (m *Migrate) dedupeBookRatings() error {
+	if !m.db.Migrator().HasTable(&model.BookRating{}) {
+		return nil
+	}
+	voters := map[string]string{
+		"user_id":    "user_id <> ''",
+		"visitor_id": "user_id = '' AND visitor_id <> ''",
+	}
+	for column, cond := range voters {
+		// 先查出重复评分的ID再删除，MySQL 不允许在 UPDATE 的子查询中引用被更新的表
+		var ids []uint
+		latest := m.db.Model(&model.BookRating{}).Select("MAX(id)").Where(cond).Group("book_id, " + column)
+		if err := m.db.Model(&model.BookRating{}).Where(cond).Where("id NOT IN (?)", latest).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		for start := 0; start < len(ids); start += 500 {
+			end := start + 500
+			if end > len(ids) {
+				end = len(ids)
+			}
+			if err := m.db.Delete(&model.BookRating{}, ids[start:end]).Error; err != nil {
+				return err
+			}
+		}
+		if len(ids) > 0 {
+			m.log.Warn("deleted duplicate book ratings, run cmd/rebuild to rebuild rating stats",
+				zap.String("voter", column), zap.Int("count", len(ids)))
+		}
+	}
+	// 旧版本的部分唯一索引只有 SQLite 和 PostgreSQL 支持，由 idx_book_rating_voter 代替
+	for _, name := range []string{"idx_book_rating_user", "idx_book_rating_visitor"} {
+		if m.db.Migrator().HasIndex(&model.BookRating{}, name) {
+			if err := m.db.Migrator().DropIndex(&model.BookRating{}, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backfillRatingTypeLevels 为新增 active_level 列之前的评分类型填充等级
+// 未删除的评分类型等级重复时返回错误，需要先手动处理重复的评分类型
+func (m *Migrate) backfillRatingTypeLevels() error {
+	var levels []int
+	err := m.db.Model(&model.RatingType{}).
+		Where("active_level IS NULL").
+		Group("level").
+		Having("COUNT(*) > 1").
+		Pluck("level", &levels).Error
+	if err != nil {
+		return err
+	}
+	if len(levels) > 0 {
+		return fmt.Errorf("duplicate rating type levels %v, resolve them before migrating", levels)
+	}
+	return m.db.Model(&model.RatingType{}).
+		Where("active_level IS NULL").
+		Update("active_level", gorm.Expr("level")).Error
+}
+
+// backfillRatingVoterKeys 为新增 voter_key 列之前的评分填充评分者标识
+func (m *Migrate) backfillRatingVoterKeys() error {
+	var ratings []*model.BookRating
+	return m.db.Select("id", "user_id", "visitor_id").
+		Where("voter_key IS NULL AND (user_id <> '' OR visitor_id <> '')").
+		FindInBatches(&ratings, 500, func(tx *gorm.DB, batch int) error {
+			for _, rating := range ratings {
+				voter := "v:" + rating.VisitorId
+				if rating.UserId != "" {
+					voter = "u:" + rating.UserId
+				}
+				if err := m.db.Model(&model.BookRating{}).Where("id = ?", rating.Id).Update("voter_key", voter).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// normalizeUsers 创建邮箱唯一索引前把已有邮箱转为小写
+// 忽略大小写后邮箱或用户名重复时返回错误，需要先手动处理重复的账号
+func (m *Migrate) normalizeUsers() error {
+	if !m.db.Migrator().HasTable(&model.User{}) {
+		return nil
+	}
+	for _, column := range []string{"email", "username"} {
+		var duplicates []string
+		err := m.db.Model(&model.User{}).
+			Select("LOWER(" + column + ")").
+			Group("LOWER(" + column + ")").
+			Having("COUNT(*) > 1").
+			Pluck("LOWER("+column+")", &duplicates).Error
+		if err != nil {
+			return err
+		}
+		if len(duplicates) > 0 {
+			return fmt.Errorf("users have duplicate %s ignoring case: %s", column, strings.Join(duplicates, ", "))
+		}
+	}
+	result := m.db.Model(&model.User{}).Where("email <> LOWER(email)").Update("email", gorm.Expr("LOWER(email)"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		m.log.Info("lowercased user emails", zap.Int64("count", result.RowsAffected))
+	}
+	return nil
+}
+
+// createUsernameIndex 创建忽略大小写的用户名唯一索引
+// mysql 默认的排序规则不区分大小写，已有的唯一索引即可保证
+func (m *Migrate) createUsernameIndex() error {
+	if m.db.Dialector.Name() == "mysql" {
+		return nil
+	}
+	return m.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username))").Error
+}
+
 func (m *Migrate) Stop(ctx context.Context) error {
 	m.log.Info("AutoMigrate stop")
 	return nil
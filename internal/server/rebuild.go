@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"novel-site-backend/internal/service"
+	"novel-site-backend/pkg/log"
+	"go.uber.org/zap"
+	"os"
+)
+
+// Rebuild 全量重建评分汇总表，用于修复汇总数据与评分不一致的问题
+type Rebuild struct {
+	log               *log.Logger
+	bookRatingService service.BookRatingService
+}
+
+func NewRebuild(log *log.Logger, bookRatingService service.BookRatingService) *Rebuild {
+	return &Rebuild{
+		log:               log,
+		bookRatingService: bookRatingService,
+	}
+}
+func (r *Rebuild) Start(ctx context.Context) error {
+	if err := r.bookRatingService.RebuildRatingStats(ctx); err != nil {
+		r.log.Error("rating stats rebuild error", zap.Error(err))
+		return err
+	}
+	r.log.Info("Rebuild success")
+	os.Exit(0)
+	return nil
+}
+func (r *Rebuild) Stop(ctx context.Context) error {
+	r.log.Info("Rebuild stop")
+	return nil
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"novel-site-backend/internal/service"
+	"novel-site-backend/pkg/log"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// routeDocs 接口说明和需要权限的接口，键为处理函数名，与 gin.RouteInfo.Handler 一致
+type routeDocs struct {
+	descriptions map[string]string
+	guarded      map[string]bool
+}
+
+func newRouteDocs() *routeDocs {
+	return &routeDocs{
+		descriptions: make(map[string]string),
+		guarded:      make(map[string]bool),
+	}
+}
+
+// describe 给接口的处理函数标注说明，原样返回处理函数，注册路由时包在最后一个处理函数外
+func (d *routeDocs) describe(description string, handler gin.HandlerFunc) gin.HandlerFunc {
+	d.descriptions[nameOfFunction(handler)] = description
+	return handler
+}
+
+// guard 标记处理函数受权限校验保护，只有这些接口会同步为接口权限
+func (d *routeDocs) guard(handler gin.HandlerFunc) {
+	d.guarded[nameOfFunction(handler)] = true
+}
+
+// apiRoutes 把 gin 已注册且受权限校验保护的路由转换为接口权限，没有标注说明的接口以处理函数名作为名称
+func (d *routeDocs) apiRoutes(routes gin.RoutesInfo) []*service.APIRoute {
+	result := make([]*service.APIRoute, 0, len(routes))
+	for _, route := range routes {
+		if !d.guarded[route.Handler] {
+			continue
+		}
+		name := shortHandlerName(route.Handler)
+		description, ok := d.descriptions[route.Handler]
+		if ok {
+			name, description = description, name
+		}
+		result = append(result, &service.APIRoute{
+			Method:      route.Method,
+			Path:        route.Path,
+			Name:        name,
+			Description: description,
+		})
+	}
+	return result
+}
+
+// syncAPIPermissions 启动时同步接口权限，失败只记录日志，不影响服务启动
+func syncAPIPermissions(engine *gin.Engine, docs *routeDocs, permissionService service.PermissionService, logger *log.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := permissionService.SyncAPIPermissions(ctx, docs.apiRoutes(engine.Routes()))
+	if err != nil {
+		logger.Error("sync api permissions error", zap.Error(err))
+		return
+	}
+	logger.Info("sync api permissions",
+		zap.Int("created", result.Created),
+		zap.Int("updated", result.Updated),
+		zap.Int("stale", result.Stale),
+	)
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// shortHandlerName 去掉处理函数名中的包路径和方法值后缀，
+// 例如 "novel-site-backend/internal/handler.(*BookHandler).UpdateBook-fm" 转换为 "BookHandler.UpdateBook"
+func shortHandlerName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
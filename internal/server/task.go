@@ -3,19 +3,44 @@ package server
 import (
 	"context"
 	"github.com/go-co-op/gocron"
+	"github.com/spf13/viper"
+	"novel-site-backend/internal/service"
 	"novel-site-backend/pkg/log"
 	"go.uber.org/zap"
 	"time"
 )
 
 type Task struct {
-	log       *log.Logger
-	scheduler *gocron.Scheduler
+	log                *log.Logger
+	conf               *viper.Viper
+	scheduler          *gocron.Scheduler
+	rankingService     service.RankingService
+	bookRatingService  service.BookRatingService
+	ratingFraudService service.RatingFraudService
+	tokenService       service.TokenService
+	loginGuardService  service.LoginGuardService
+	userBanService     service.UserBanService
 }
 
-func NewTask(log *log.Logger) *Task {
+func NewTask(
+	log *log.Logger,
+	conf *viper.Viper,
+	rankingService service.RankingService,
+	bookRatingService service.BookRatingService,
+	ratingFraudService service.RatingFraudService,
+	tokenService service.TokenService,
+	loginGuardService service.LoginGuardService,
+	userBanService service.UserBanService,
+) *Task {
 	return &Task{
-		log: log,
+		log:                log,
+		conf:               conf,
+		rankingService:     rankingService,
+		bookRatingService:  bookRatingService,
+		ratingFraudService: ratingFraudService,
+		tokenService:       tokenService,
+		loginGuardService:  loginGuardService,
+		userBanService:     userBanService,
 	}
 }
 func (t *Task) Start(ctx context.Context) error {
@@ -28,18 +53,88 @@ func (t *Task) Start(ctx context.Context) error {
 	// if you are in China, you will need to change the time zone as follows
 	// t.scheduler = gocron.NewScheduler(time.FixedZone("PRC", 8*60*60))
 
-	_, err := t.scheduler.CronWithSeconds("0/3 * * * * *").Do(func() {
-		t.log.Info("I'm a Task1.")
+	// 重新计算图书热度分
+	refreshCron := t.conf.GetString("ranking.refresh_cron")
+	if refreshCron == "" {
+		refreshCron = "0 */30 * * * *"
+	}
+	_, err := t.scheduler.CronWithSeconds(refreshCron).Do(func() {
+		if err := t.rankingService.RefreshTrendingScores(ctx); err != nil {
+			t.log.Error("RefreshTrendingScores error", zap.Error(err))
+		}
+	})
+	if err != nil {
+		t.log.Error("RefreshTrendingScores task error", zap.Error(err))
+	}
+
+	// 全量重算图书贝叶斯评分
+	ratingCron := t.conf.GetString("rating.bayesian.refresh_cron")
+	if ratingCron == "" {
+		ratingCron = "0 0 3 * * *"
+	}
+	_, err = t.scheduler.CronWithSeconds(ratingCron).Do(func() {
+		if err := t.bookRatingService.RefreshRatingScores(ctx); err != nil {
+			t.log.Error("RefreshRatingScores error", zap.Error(err))
+		}
+	})
+	if err != nil {
+		t.log.Error("RefreshRatingScores task error", zap.Error(err))
+	}
+
+	// 检测可疑评分
+	fraudCron := t.conf.GetString("fraud.detect_cron")
+	if fraudCron == "" {
+		fraudCron = "0 */10 * * * *"
+	}
+	_, err = t.scheduler.CronWithSeconds(fraudCron).Do(func() {
+		if _, err := t.ratingFraudService.DetectFraud(ctx); err != nil {
+			t.log.Error("DetectFraud error", zap.Error(err))
+		}
 	})
 	if err != nil {
-		t.log.Error("Task1 error", zap.Error(err))
+		t.log.Error("DetectFraud task error", zap.Error(err))
 	}
 
-	_, err = t.scheduler.Every("3s").Do(func() {
-		t.log.Info("I'm a Task2.")
+	// 清理过期的刷新令牌和吊销记录
+	tokenCleanupCron := t.conf.GetString("security.jwt.cleanup_cron")
+	if tokenCleanupCron == "" {
+		tokenCleanupCron = "0 30 4 * * *"
+	}
+	_, err = t.scheduler.CronWithSeconds(tokenCleanupCron).Do(func() {
+		if _, err := t.tokenService.CleanupExpired(ctx); err != nil {
+			t.log.Error("CleanupExpired error", zap.Error(err))
+		}
+	})
+	if err != nil {
+		t.log.Error("CleanupExpired task error", zap.Error(err))
+	}
+
+	// 清理过期的登录记录
+	attemptCleanupCron := t.conf.GetString("login.cleanup_cron")
+	if attemptCleanupCron == "" {
+		attemptCleanupCron = "0 45 4 * * *"
+	}
+	_, err = t.scheduler.CronWithSeconds(attemptCleanupCron).Do(func() {
+		if _, err := t.loginGuardService.CleanupAttempts(ctx); err != nil {
+			t.log.Error("CleanupAttempts error", zap.Error(err))
+		}
+	})
+	if err != nil {
+		t.log.Error("CleanupAttempts task error", zap.Error(err))
+	}
+
+	// 解除已到期的封禁
+	liftBanCron := t.conf.GetString("ban.lift_cron")
+	if liftBanCron == "" {
+		liftBanCron = "0 */5 * * * *"
+	}
+	_, err = t.scheduler.CronWithSeconds(liftBanCron).Do(func() {
+		if _, err := t.userBanService.LiftExpiredBans(ctx); err != nil {
+			t.log.Error("LiftExpiredBans error", zap.Error(err))
+		}
 	})
 	if err != nil {
-		t.log.Error("Task2 error", zap.Error(err))
+		t.log.Error("LiftExpiredBans task error", zap.Error(err))
 	}
 
 	t.scheduler.StartBlocking()
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"time"
+)
+
+// maxAnalyticsDays 单次查询允许的最大天数
+const maxAnalyticsDays = 366
+
+type AnalyticsService interface {
+	GetBookSeries(ctx context.Context, bookId uint, from, to string) (*v1.AnalyticsSeriesResponse, error)
+	GetSiteSeries(ctx context.Context, from, to string) (*v1.AnalyticsSeriesResponse, error)
+}
+
+type analyticsService struct {
+	bookRepo     repository.BookRepository
+	bookStatRepo repository.BookStatRepository
+	*Service
+}
+
+func NewAnalyticsService(
+	service *Service,
+	bookRepo repository.BookRepository,
+	bookStatRepo repository.BookStatRepository,
+) AnalyticsService {
+	return &analyticsService{
+		Service:      service,
+		bookRepo:     bookRepo,
+		bookStatRepo: bookStatRepo,
+	}
+}
+
+// GetBookSeries 获取单本图书的每日统计
+func (s *analyticsService) GetBookSeries(ctx context.Context, bookId uint, from, to string) (*v1.AnalyticsSeriesResponse, error) {
+	if _, err := s.bookRepo.GetByID(ctx, bookId); err != nil {
+		return nil, err
+	}
+	return s.series(ctx, bookId, from, to)
+}
+
+// GetSiteSeries 获取全站每日统计
+func (s *analyticsService) GetSiteSeries(ctx context.Context, from, to string) (*v1.AnalyticsSeriesResponse, error) {
+	return s.series(ctx, 0, from, to)
+}
+
+func (s *analyticsService) series(ctx context.Context, bookId uint, from, to string) (*v1.AnalyticsSeriesResponse, error) {
+	start, end, err := parseDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	from = start.Format(model.StatDateLayout)
+	to = end.Format(model.StatDateLayout)
+
+	points, err := s.bookStatRepo.Series(ctx, bookId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]*model.DailySeriesPoint, len(points))
+	for _, point := range points {
+		byDate[point.Date] = point
+	}
+
+	items := make([]*v1.AnalyticsPoint, 0)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(model.StatDateLayout)
+		item := &v1.AnalyticsPoint{Date: date}
+		if point, ok := byDate[date]; ok {
+			item.Views = point.Views
+			item.Downloads = point.Downloads
+			item.UniqueVisitors = point.UniqueVisitors
+		}
+		items = append(items, item)
+	}
+
+	return &v1.AnalyticsSeriesResponse{
+		BookId: bookId,
+		From:   from,
+		To:     to,
+		Points: items,
+	}, nil
+}
+
+// parseDateRange 解析日期区间，默认最近 30 天
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	now := time.Now()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if to != "" {
+		t, err := time.ParseInLocation(model.StatDateLayout, to, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, v1.ErrBadRequest
+		}
+		end = t
+	}
+
+	start := end.AddDate(0, 0, -29)
+	if from != "" {
+		t, err := time.ParseInLocation(model.StatDateLayout, from, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, v1.ErrBadRequest
+		}
+		start = t
+	}
+
+	if start.After(end) || end.Sub(start) >= maxAnalyticsDays*24*time.Hour {
+		return time.Time{}, time.Time{}, v1.ErrBadRequest
+	}
+	return start, end, nil
+}
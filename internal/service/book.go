@@ -5,29 +5,42 @@ import (
 	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/internal/repository"
+	"novel-site-backend/pkg/hll"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type BookService interface {
 	CreateBook(ctx context.Context, req *v1.CreateBookRequest) error
-	UpdateBook(ctx context.Context, id uint, req *v1.UpdateBookRequest) error
-	DeleteBook(ctx context.Context, id uint) error
-	GetBook(ctx context.Context, id uint) (*v1.GetBookResponse, error)
+	UpdateBook(ctx context.Context, id uint, version uint, req *v1.UpdateBookRequest) error
+	DeleteBook(ctx context.Context, id uint, version uint) error
+	GetBook(ctx context.Context, id uint, clientIP string) (*v1.GetBookResponse, error)
 	ListBooks(ctx context.Context, req *v1.ListBooksRequest) (*v1.ListBooksResponse, error)
 	GetAllSorts(ctx context.Context) ([]string, error)
 	QuickSearch(ctx context.Context, keyword string) (*v1.QuickSearchResponse, error)
+	DownloadBook(ctx context.Context, id uint, clientIP string) (*v1.DownloadBookResponse, error)
+	FlushCounters(ctx context.Context) error
 }
 
 type bookService struct {
-	bookRepo repository.BookRepository
+	bookRepo     repository.BookRepository
+	bookStatRepo repository.BookStatRepository
+	bookCounter  repository.BookCounter
 	*Service
 }
 
-func NewBookService(service *Service, bookRepo repository.BookRepository) BookService {
+func NewBookService(
+	service *Service,
+	bookRepo repository.BookRepository,
+	bookStatRepo repository.BookStatRepository,
+	bookCounter repository.BookCounter,
+) BookService {
 	return &bookService{
-		Service:  service,
-		bookRepo: bookRepo,
+		Service:      service,
+		bookRepo:     bookRepo,
+		bookStatRepo: bookStatRepo,
+		bookCounter:  bookCounter,
 	}
 }
 
@@ -60,11 +73,14 @@ func (s *bookService) CreateBook(ctx context.Context, req *v1.CreateBookRequest)
 	return s.bookRepo.Create(ctx, book)
 }
 
-func (s *bookService) UpdateBook(ctx context.Context, id uint, req *v1.UpdateBookRequest) error {
+func (s *bookService) UpdateBook(ctx context.Context, id uint, version uint, req *v1.UpdateBookRequest) error {
 	book, err := s.bookRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
+	if book.Version != version {
+		return v1.ErrBookVersionConflict
+	}
 
 	book.Title = req.Title
 	book.Author = req.Author
@@ -77,22 +93,28 @@ func (s *bookService) UpdateBook(ctx context.Context, id uint, req *v1.UpdateBoo
 	return s.bookRepo.Update(ctx, book)
 }
 
-func (s *bookService) DeleteBook(ctx context.Context, id uint) error {
-	return s.bookRepo.Delete(ctx, id)
+func (s *bookService) DeleteBook(ctx context.Context, id uint, version uint) error {
+	if _, err := s.bookRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.bookRepo.Delete(ctx, id, version)
 }
 
-func (s *bookService) GetBook(ctx context.Context, id uint) (*v1.GetBookResponse, error) {
+func (s *bookService) GetBook(ctx context.Context, id uint, clientIP string) (*v1.GetBookResponse, error) {
 	book, err := s.bookRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	// 被举报隐藏的图书对前台不可见
+	if book.Hidden {
+		return nil, v1.ErrNotFound
+	}
 
-	// 异步增加热度值
-	go func() {
-		if err := s.bookRepo.IncrementHotValue(context.Background(), id); err != nil {
-			s.logger.Error("increment hot value failed", zap.Error(err))
-		}
-	}()
+	// 浏览量先写入计数缓冲区，由 FlushCounters 批量写回
+	if err := s.bookCounter.Incr(ctx, repository.CounterViews, id, 1); err != nil {
+		s.logger.WithContext(ctx).Error("increment hot value failed", zap.Error(err))
+	}
+	s.addVisitor(ctx, id, clientIP)
 
 	return &v1.GetBookResponse{
 		Id:          book.Id,
@@ -110,7 +132,12 @@ func (s *bookService) GetBook(ctx context.Context, id uint) (*v1.GetBookResponse
 		Type:        book.Type,
 		Tag:         book.Tag,
 		CreatedAt:   book.CreatedAt,
+		UpdatedAt:   book.UpdatedAt,
 		HotValue:    book.HotValue,
+		Downloads:   book.Downloads,
+		RatingScore: book.RatingScore,
+		RatingCount: book.RatingCount,
+		Version:     book.Version,
 	}, nil
 }
 
@@ -123,15 +150,18 @@ func (s *bookService) ListBooks(ctx context.Context, req *v1.ListBooksRequest) (
 	var items []*v1.BookItem
 	for _, book := range books {
 		items = append(items, &v1.BookItem{
-			Id:        book.Id,
-			Title:     book.Title,
-			Author:    book.Author,
-			Cover:     book.Cover,
-			Intro:     book.Intro,
-			Sort:      book.Sort,
-			Type:      book.Type,
-			Tag:       book.Tag,
-			CreatedAt: book.CreatedAt,
+			Id:          book.Id,
+			Title:       book.Title,
+			Author:      book.Author,
+			Cover:       book.Cover,
+			Intro:       book.Intro,
+			Sort:        book.Sort,
+			Type:        book.Type,
+			Tag:         book.Tag,
+			HotValue:    book.HotValue,
+			RatingScore: book.RatingScore,
+			RatingCount: book.RatingCount,
+			CreatedAt:   book.CreatedAt,
 		})
 	}
 
@@ -165,3 +195,141 @@ func (s *bookService) QuickSearch(ctx context.Context, keyword string) (*v1.Quic
 		Items: items,
 	}, nil
 }
+
+// DownloadBook 记录一次下载并返回文件地址
+func (s *bookService) DownloadBook(ctx context.Context, id uint, clientIP string) (*v1.DownloadBookResponse, error) {
+	book, err := s.bookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// 被举报隐藏的图书对前台不可见
+	if book.Hidden {
+		return nil, v1.ErrNotFound
+	}
+
+	if err := s.bookCounter.Incr(ctx, repository.CounterDownloads, id, 1); err != nil {
+		s.logger.WithContext(ctx).Error("increment downloads failed", zap.Error(err))
+	}
+	s.addVisitor(ctx, id, clientIP)
+
+	return &v1.DownloadBookResponse{
+		FileURL: book.FileURL,
+	}, nil
+}
+
+// addVisitor 记录访客，只保存客户端IP的散列值
+func (s *bookService) addVisitor(ctx context.Context, id uint, clientIP string) {
+	if clientIP == "" {
+		return
+	}
+	if err := s.bookCounter.AddVisitor(ctx, id, hll.Hash(clientIP)); err != nil {
+		s.logger.WithContext(ctx).Error("add visitor failed", zap.Error(err))
+	}
+}
+
+// FlushCounters 将计数缓冲区中的增量批量写回数据库
+// 浏览量计入热度值，下载量计入下载数，二者同时累加到当天的每日统计
+// 访客合并进当天的独立访客草图
+// 写回失败的增量会重新放回缓冲区，等待下一次写回
+func (s *bookService) FlushCounters(ctx context.Context) error {
+	var lastErr error
+	for _, kind := range []repository.CounterKind{repository.CounterViews, repository.CounterDownloads} {
+		if err := s.flushCounter(ctx, kind); err != nil {
+			lastErr = err
+		}
+	}
+	if err := s.flushVisitors(ctx); err != nil {
+		lastErr = err
+	}
+	return lastErr
+}
+
+func (s *bookService) flushVisitors(ctx context.Context) error {
+	visitors, err := s.bookCounter.DrainVisitors(ctx)
+	if err != nil {
+		return err
+	}
+	if len(visitors) == 0 {
+		return nil
+	}
+
+	date := time.Now().Format(model.StatDateLayout)
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		site := hll.New()
+		for id, hashes := range visitors {
+			sketch := hll.New()
+			for _, hash := range hashes {
+				sketch.Add(hash)
+			}
+			site.Merge(sketch)
+			if err := s.mergeSketch(ctx, id, date, sketch); err != nil {
+				return err
+			}
+		}
+		// 图书ID为 0 的草图记录全站独立访客
+		return s.mergeSketch(ctx, 0, date, site)
+	})
+	if err != nil {
+		s.logger.Error("flush visitors failed", zap.Error(err))
+		for id, hashes := range visitors {
+			for _, hash := range hashes {
+				if err := s.bookCounter.AddVisitor(ctx, id, hash); err != nil {
+					s.logger.Error("restore visitor failed", zap.Uint("book_id", id), zap.Error(err))
+				}
+			}
+		}
+	}
+	return err
+}
+
+// mergeSketch 将草图合并进数据库中某天的访客草图并更新估计值
+func (s *bookService) mergeSketch(ctx context.Context, bookId uint, date string, sketch *hll.Sketch) error {
+	stored, err := s.bookStatRepo.GetSketchForUpdate(ctx, bookId, date)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		stored = &model.VisitorSketch{BookId: bookId, Date: date}
+	}
+
+	merged := hll.FromBytes(stored.Registers)
+	merged.Merge(sketch)
+	stored.Registers = merged.Bytes()
+	stored.Estimate = int64(merged.Estimate())
+	return s.bookStatRepo.SaveSketch(ctx, stored)
+}
+
+func (s *bookService) flushCounter(ctx context.Context, kind repository.CounterKind) error {
+	counts, err := s.bookCounter.Drain(ctx, kind)
+	if err != nil {
+		return err
+	}
+
+	date := time.Now().Format(model.StatDateLayout)
+	var lastErr error
+	for id, delta := range counts {
+		if delta == 0 {
+			continue
+		}
+		err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+			if kind == repository.CounterDownloads {
+				if err := s.bookRepo.IncrementDownloads(ctx, id, delta); err != nil {
+					return err
+				}
+				return s.bookStatRepo.IncrDaily(ctx, id, date, 0, delta)
+			}
+			if err := s.bookRepo.IncrementHotValue(ctx, id, delta); err != nil {
+				return err
+			}
+			return s.bookStatRepo.IncrDaily(ctx, id, date, delta, 0)
+		})
+		if err != nil {
+			lastErr = err
+			s.logger.Error("flush counter failed", zap.String("kind", string(kind)), zap.Uint("book_id", id), zap.Int64("delta", delta), zap.Error(err))
+			if err := s.bookCounter.Incr(ctx, kind, id, delta); err != nil {
+				s.logger.Error("restore counter failed", zap.String("kind", string(kind)), zap.Uint("book_id", id), zap.Int64("delta", delta), zap.Error(err))
+			}
+		}
+	}
+	return lastErr
+}
@@ -2,127 +2,634 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
 	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 type BookRatingService interface {
-	CreateBookRating(ctx context.Context, req *v1.CreateBookRatingRequest) error
+	CreateBookRating(ctx context.Context, req *v1.CreateBookRatingRequest) (*v1.CreateBookRatingResponse, error)
 	UpdateBookRating(ctx context.Context, id uint, req *v1.UpdateBookRatingRequest) error
 	DeleteBookRating(ctx context.Context, id uint) error
 	GetBookRating(ctx context.Context, bookId uint) (*v1.GetBookRatingResponse, error)
-	ListBookRatings(ctx context.Context, bookId uint, page, pageSize int) (*v1.ListBookRatingsResponse, error)
+	ListBookReviews(ctx context.Context, bookId uint, req *v1.ListBookReviewsRequest) (*v1.ListBookReviewsResponse, error)
+	GetRatingTrend(ctx context.Context, bookId uint, req *v1.GetRatingTrendRequest) (*v1.GetRatingTrendResponse, error)
+	RefreshRatingScores(ctx context.Context) error
+	RefreshBookScore(ctx context.Context, bookId uint) error
+	RebuildRatingStats(ctx context.Context) error
+	ModerateReviews(ctx context.Context, req *v1.ModerateReviewsRequest) (*v1.ModerateReviewsResponse, error)
+	SetRatingStatus(ctx context.Context, ids []uint, status string) (int64, error)
 }
 
 type bookRatingService struct {
-	bookRatingRepo repository.BookRatingRepository
-	ratingTypeRepo repository.RatingTypeRepository
+	bookRepo           repository.BookRepository
+	bookRatingRepo     repository.BookRatingRepository
+	bookRatingStatRepo repository.BookRatingStatRepository
+	ratingTypeRepo     repository.RatingTypeRepository
+	moderationService  ModerationService
 	*Service
+
+	maxPerIP    int64   // 同一IP对同一本书的最大评分数
+	priorMean   float64 // 贝叶斯评分的先验平均等级
+	priorWeight float64 // 贝叶斯评分的先验权重，相当于预设的评分数
 }
 
-func NewBookRatingService(service *Service, bookRatingRepo repository.BookRatingRepository, ratingTypeRepo repository.RatingTypeRepository) BookRatingService {
-	return &bookRatingService{
-		Service:        service,
-		bookRatingRepo: bookRatingRepo,
-		ratingTypeRepo: ratingTypeRepo,
+func NewBookRatingService(
+	service *Service,
+	conf *viper.Viper,
+	bookRepo repository.BookRepository,
+	bookRatingRepo repository.BookRatingRepository,
+	bookRatingStatRepo repository.BookRatingStatRepository,
+	ratingTypeRepo repository.RatingTypeRepository,
+	moderationService ModerationService,
+) BookRatingService {
+	s := &bookRatingService{
+		Service:            service,
+		bookRepo:           bookRepo,
+		bookRatingRepo:     bookRatingRepo,
+		bookRatingStatRepo: bookRatingStatRepo,
+		ratingTypeRepo:     ratingTypeRepo,
+		moderationService:  moderationService,
+		maxPerIP:           conf.GetInt64("rating.max_per_ip"),
+		priorMean:          conf.GetFloat64("rating.bayesian.prior_mean"),
+		priorWeight:        conf.GetFloat64("rating.bayesian.prior_weight"),
+	}
+	if s.priorWeight < 0 {
+		s.priorWeight = 0
 	}
+	return s
 }
 
-func (s *bookRatingService) CreateBookRating(ctx context.Context, req *v1.CreateBookRatingRequest) error {
-	rating := &model.BookRating{
-		BookId:  req.BookId,
-		Id:      req.RatingTypeId,
-		Comment: req.Comment,
-		IP:      req.IP,
+// CreateBookRating 创建书籍评分
+// 每个评分者（登录用户或匿名访客）对同一本书只保留一条评分，重复提交视为更新
+// 新评分受同一IP评分数上限限制，评论内容经敏感词检查后决定是否需要审核
+func (s *bookRatingService) CreateBookRating(ctx context.Context, req *v1.CreateBookRatingRequest) (*v1.CreateBookRatingResponse, error) {
+	comment, status, err := s.moderationService.ScreenComment(ctx, req.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *v1.CreateBookRatingResponse
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		existing, err := s.bookRatingRepo.FindByVoter(ctx, req.BookId, req.UserId, req.VisitorId)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			before := *existing
+			existing.RatingTypeId = req.RatingTypeId
+			existing.Comment = comment
+			existing.Status = status
+			existing.IP = req.IP
+			if err := s.bookRatingRepo.Update(ctx, existing); err != nil {
+				return err
+			}
+			resp = &v1.CreateBookRatingResponse{Id: existing.Id, Updated: true, Status: status}
+			return s.applyRatingChange(ctx, &before, existing)
+		}
+
+		if req.IP != "" && s.maxPerIP > 0 {
+			count, err := s.bookRatingRepo.CountByIP(ctx, req.BookId, req.IP)
+			if err != nil {
+				return err
+			}
+			if count >= s.maxPerIP {
+				return v1.ErrRatingLimitExceeded
+			}
+		}
+
+		rating := &model.BookRating{
+			BookId:       req.BookId,
+			RatingTypeId: req.RatingTypeId,
+			Comment:      comment,
+			IP:           req.IP,
+			UserId:       req.UserId,
+			VisitorId:    req.VisitorId,
+			Status:       status,
+		}
+		if voter := voterKey(req.UserId, req.VisitorId); voter != "" {
+			rating.VoterKey = &voter
+		}
+		if req.VisitorId != "" && !req.VisitorSince.IsZero() {
+			rating.VisitorSince = &req.VisitorSince
+		}
+		if err := s.bookRatingRepo.Create(ctx, rating); err != nil {
+			return err
+		}
+		resp = &v1.CreateBookRatingResponse{Id: rating.Id, Status: status}
+		return s.applyRatingChange(ctx, nil, rating)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return s.bookRatingRepo.Create(ctx, rating)
+	return resp, nil
 }
 
 func (s *bookRatingService) UpdateBookRating(ctx context.Context, id uint, req *v1.UpdateBookRatingRequest) error {
-	rating, err := s.bookRatingRepo.GetByID(ctx, id)
+	comment, status, err := s.moderationService.ScreenComment(ctx, req.Comment)
 	if err != nil {
 		return err
 	}
 
-	rating.Id = req.RatingTypeID
-	rating.Comment = req.Comment
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		rating, err := s.bookRatingRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		before := *rating
+		rating.RatingTypeId = req.RatingTypeID
+		rating.Comment = comment
+		rating.Status = status
 
-	return s.bookRatingRepo.Update(ctx, rating)
+		if err := s.bookRatingRepo.Update(ctx, rating); err != nil {
+			return err
+		}
+		return s.applyRatingChange(ctx, &before, rating)
+	})
 }
 
 func (s *bookRatingService) DeleteBookRating(ctx context.Context, id uint) error {
-	return s.bookRatingRepo.Delete(ctx, id)
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		rating, err := s.bookRatingRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.bookRatingRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.applyRatingChange(ctx, rating, nil)
+	})
 }
 
+// GetBookRating 获取书籍评分统计，评分数来自汇总表
 func (s *bookRatingService) GetBookRating(ctx context.Context, bookId uint) (*v1.GetBookRatingResponse, error) {
-	// 获取评分统计
-	stats, total, err := s.bookRatingRepo.GetRatingStats(ctx, bookId)
-	if err != nil {
-		return nil, err
-	}
-
-	// 获取所有评分类型
-	ratingTypes, _, err := s.ratingTypeRepo.List(ctx, 1, 100) // 假设评分类型不会超过100个
+	stats, err := s.bookRatingStatRepo.ListByBook(ctx, bookId)
 	if err != nil {
 		return nil, err
 	}
 
-	// 构建评分类型统计map
-	statsMap := make(map[uint]int64)
+	var total int64
 	for _, stat := range stats {
-		statsMap[stat.RatingTypeID] = stat.Count
+		total += stat.Count
 	}
+	average, score := s.scoreRatings(stats)
 
 	// 构建响应
-	ratingTypeStats := make([]*v1.RatingTypeWithCount, 0)
-	for _, rt := range ratingTypes {
-		count := statsMap[rt.Id]
+	ratingTypeStats := make([]*v1.RatingTypeWithCount, 0, len(stats))
+	for _, stat := range stats {
 		percentage := float64(0)
 		if total > 0 {
-			percentage = float64(count) / float64(total) * 100
+			percentage = float64(stat.Count) / float64(total) * 100
 		}
 
 		ratingTypeStats = append(ratingTypeStats, &v1.RatingTypeWithCount{
-			Id:          rt.Id,
-			Name:        rt.Name,
-			Description: rt.Description,
-			Level:       rt.Level,
-			Count:       count,
+			Id:          stat.RatingTypeId,
+			Name:        stat.Name,
+			Description: stat.Description,
+			Level:       stat.Level,
+			Count:       stat.Count,
 			Percentage:  percentage,
 		})
 	}
 
 	return &v1.GetBookRatingResponse{
 		Stats: &v1.BookRatingStats{
-			BookId:       bookId,
-			TotalRatings: total,
-			RatingTypes:  ratingTypeStats,
+			BookId:        bookId,
+			TotalRatings:  total,
+			AverageLevel:  average,
+			BayesianScore: score,
+			RatingTypes:   ratingTypeStats,
 		},
 	}, nil
 }
 
-func (s *bookRatingService) ListBookRatings(ctx context.Context, bookId uint, page, pageSize int) (*v1.ListBookRatingsResponse, error) {
-	ratings, total, err := s.bookRatingRepo.ListByBookID(ctx, bookId, page, pageSize)
+// ListBookReviews 获取书籍的公开评论列表，只返回审核通过的评论，IP 打码后返回
+func (s *bookRatingService) ListBookReviews(ctx context.Context, bookId uint, req *v1.ListBookReviewsRequest) (*v1.ListBookReviewsResponse, error) {
+	q := &repository.ReviewFeedQuery{
+		BookId:       bookId,
+		RatingTypeId: req.RatingTypeId,
+		Limit:        req.Limit,
+	}
+	switch req.Order {
+	case "", v1.ReviewOrderNewest:
+	case v1.ReviewOrderLiked:
+		q.OrderByLikes = true
+	default:
+		return nil, v1.ErrBadRequest
+	}
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	if q.Limit > 50 {
+		q.Limit = 50
+	}
+	if req.Cursor != "" {
+		likes, id, ok := decodeReviewCursor(req.Cursor)
+		if !ok {
+			return nil, v1.ErrBadRequest
+		}
+		q.AfterLikes, q.AfterId = likes, id
+	}
+
+	// 多取一条用于判断是否还有下一页
+	limit := q.Limit
+	q.Limit++
+	ratings, err := s.bookRatingRepo.ListFeed(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 
-	var items []*v1.BookRatingResponse
+	resp := &v1.ListBookReviewsResponse{
+		Items: make([]*v1.BookReviewItem, 0, len(ratings)),
+	}
+	if len(ratings) > limit {
+		ratings = ratings[:limit]
+		last := ratings[len(ratings)-1]
+		resp.NextCursor = encodeReviewCursor(last.LikeCount, last.Id)
+	}
 	for _, rating := range ratings {
-		items = append(items, &v1.BookRatingResponse{
+		resp.Items = append(resp.Items, &v1.BookReviewItem{
 			Id:           rating.Id,
 			BookId:       rating.BookId,
-			RatingTypeId: rating.Id,
+			RatingTypeId: rating.RatingTypeId,
 			Comment:      rating.Comment,
-			IP:           rating.IP,
+			IP:           maskIP(rating.IP),
+			LikeCount:    rating.LikeCount,
+			ReplyCount:   rating.ReplyCount,
 			CreatedAt:    rating.CreatedAt,
-			UpdatedAt:    rating.UpdatedAt,
 		})
 	}
+	return resp, nil
+}
 
-	return &v1.ListBookRatingsResponse{
-		Total: total,
-		Items: items,
-	}, nil
+// GetRatingTrend 按天/周/月统计某本书各评分类型的评分数，用于观察口碑变化
+// 数据库只按天分组，周和月在此基础上汇总，避免依赖各数据库不同的日期函数
+func (s *bookRatingService) GetRatingTrend(ctx context.Context, bookId uint, req *v1.GetRatingTrendRequest) (*v1.GetRatingTrendResponse, error) {
+	interval := req.Interval
+	if interval == "" {
+		interval = trendIntervalDay
+	}
+	start, end, err := parseTrendRange(interval, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := s.bookRepo.GetByID(ctx, bookId)
+	if err != nil {
+		return nil, err
+	}
+	if book.Hidden {
+		return nil, v1.ErrNotFound
+	}
+
+	ratingTypes, _, err := s.ratingTypeRepo.List(ctx, 1, 100) // 假设评分类型不会超过100个
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.bookRatingRepo.DailyCounts(ctx, bookId, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &v1.GetRatingTrendResponse{
+		BookId:      bookId,
+		Interval:    interval,
+		From:        start.Format(model.StatDateLayout),
+		To:          end.Format(model.StatDateLayout),
+		RatingTypes: make([]*v1.RatingTypeResponse, 0, len(ratingTypes)),
+		Points:      make([]*v1.RatingTrendPoint, 0),
+	}
+	typeIndex := make(map[uint]int, len(ratingTypes))
+	for i, rt := range ratingTypes {
+		typeIndex[rt.Id] = i
+		resp.RatingTypes = append(resp.RatingTypes, toRatingTypeResponse(rt))
+	}
+
+	points := make(map[string]*v1.RatingTrendPoint)
+	for day := start; !day.After(end); day = nextTrendBucket(day, interval) {
+		point := &v1.RatingTrendPoint{
+			Start:  day.Format(model.StatDateLayout),
+			Counts: make([]*v1.RatingTypeCount, 0, len(ratingTypes)),
+		}
+		for _, rt := range ratingTypes {
+			point.Counts = append(point.Counts, &v1.RatingTypeCount{RatingTypeId: rt.Id})
+		}
+		points[point.Start] = point
+		resp.Points = append(resp.Points, point)
+	}
+
+	for _, count := range counts {
+		day, err := time.ParseInLocation(model.StatDateLayout, count.Date, start.Location())
+		if err != nil {
+			return nil, fmt.Errorf("parse rating date %q: %w", count.Date, err)
+		}
+		point, ok := points[trendBucket(day, interval).Format(model.StatDateLayout)]
+		if !ok {
+			continue
+		}
+		i, ok := typeIndex[count.RatingTypeId]
+		if !ok {
+			continue
+		}
+		point.Counts[i].Count += count.Count
+		point.Total += count.Count
+	}
+	return resp, nil
+}
+
+// RefreshRatingScores 按评分汇总表重新计算每本书的贝叶斯评分，用于修正先验参数或评分类型等级调整后的数据
+func (s *bookRatingService) RefreshRatingScores(ctx context.Context) error {
+	ratingTypes, _, err := s.ratingTypeRepo.List(ctx, 1, 100) // 假设评分类型不会超过100个
+	if err != nil {
+		return err
+	}
+	levels := make(map[uint]int, len(ratingTypes))
+	for _, rt := range ratingTypes {
+		levels[rt.Id] = rt.Level
+	}
+
+	stats, err := s.bookRatingStatRepo.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	byBook := make(map[uint][]*model.RatingTypeStat)
+	for _, stat := range stats {
+		level, ok := levels[stat.RatingTypeId]
+		if !ok {
+			continue
+		}
+		byBook[stat.BookId] = append(byBook[stat.BookId], &model.RatingTypeStat{
+			RatingTypeId: stat.RatingTypeId,
+			Level:        level,
+			Count:        stat.Count,
+		})
+	}
+
+	scores := make(map[uint]*model.RatingScore, len(byBook))
+	for bookId, bookStats := range byBook {
+		scores[bookId] = s.ratingScore(bookStats)
+	}
+
+	if err := s.bookRepo.UpdateRatingScores(ctx, scores); err != nil {
+		return err
+	}
+	s.logger.Info("rating scores refreshed", zap.Int("books", len(scores)))
+	return nil
+}
+
+// RebuildRatingStats 按评分表全量重建评分汇总表，并据此重算全部图书的贝叶斯评分
+func (s *bookRatingService) RebuildRatingStats(ctx context.Context) error {
+	rows, err := s.bookRatingStatRepo.Rebuild(ctx)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("rating stats rebuilt", zap.Int64("rows", rows))
+	return s.RefreshRatingScores(ctx)
+}
+
+// ModerateReviews 批量通过或拒绝评论，评分随审核状态计入或移出评分统计
+func (s *bookRatingService) ModerateReviews(ctx context.Context, req *v1.ModerateReviewsRequest) (*v1.ModerateReviewsResponse, error) {
+	updated, err := s.SetRatingStatus(ctx, req.Ids, req.Status)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ModerateReviewsResponse{Updated: updated}, nil
+}
+
+// SetRatingStatus 批量修改评分的审核状态，同步调整评分汇总和贝叶斯评分，返回实际修改的条数
+func (s *bookRatingService) SetRatingStatus(ctx context.Context, ids []uint, status string) (int64, error) {
+	var updated int64
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		ratings, err := s.bookRatingRepo.GetByIds(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		changedIds := make([]uint, 0, len(ratings))
+		books := make(map[uint]bool)
+		for _, rating := range ratings {
+			if rating.DeletedAt.Valid || rating.Status == status {
+				continue
+			}
+			changedIds = append(changedIds, rating.Id)
+			before := *rating
+			rating.Status = status
+			changed, err := s.adjustRatingStat(ctx, &before, rating)
+			if err != nil {
+				return err
+			}
+			if changed {
+				books[rating.BookId] = true
+			}
+		}
+		if len(changedIds) == 0 {
+			return nil
+		}
+
+		updated, err = s.bookRatingRepo.UpdateStatus(ctx, changedIds, status)
+		if err != nil {
+			return err
+		}
+		for bookId := range books {
+			if err := s.RefreshBookScore(ctx, bookId); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// applyRatingChange 按评分修改前后的状态调整评分汇总，汇总有变化时重算该书的贝叶斯评分
+// 新建评分时 before 为 nil，删除评分时 after 为 nil
+func (s *bookRatingService) applyRatingChange(ctx context.Context, before, after *model.BookRating) error {
+	changed, err := s.adjustRatingStat(ctx, before, after)
+	if err != nil || !changed {
+		return err
+	}
+	if after != nil {
+		return s.RefreshBookScore(ctx, after.BookId)
+	}
+	return s.RefreshBookScore(ctx, before.BookId)
+}
+
+// adjustRatingStat 按评分修改前后是否计入统计调整评分汇总表，返回汇总是否有变化
+func (s *bookRatingService) adjustRatingStat(ctx context.Context, before, after *model.BookRating) (bool, error) {
+	oldCounted := before != nil && before.Counted()
+	newCounted := after != nil && after.Counted()
+	if oldCounted && newCounted && before.RatingTypeId == after.RatingTypeId {
+		return false, nil
+	}
+	if oldCounted {
+		if err := s.bookRatingStatRepo.Incr(ctx, before.BookId, before.RatingTypeId, -1); err != nil {
+			return false, err
+		}
+	}
+	if newCounted {
+		if err := s.bookRatingStatRepo.Incr(ctx, after.BookId, after.RatingTypeId, 1); err != nil {
+			return false, err
+		}
+	}
+	return oldCounted || newCounted, nil
+}
+
+// RefreshBookScore 按评分汇总表重新计算某本书的贝叶斯评分
+func (s *bookRatingService) RefreshBookScore(ctx context.Context, bookId uint) error {
+	stats, err := s.bookRatingStatRepo.ListByBook(ctx, bookId)
+	if err != nil {
+		return err
+	}
+	return s.bookRepo.UpdateRatingScore(ctx, bookId, s.ratingScore(stats))
+}
+
+func (s *bookRatingService) ratingScore(stats []*model.RatingTypeStat) *model.RatingScore {
+	var count int64
+	for _, stat := range stats {
+		count += stat.Count
+	}
+	_, score := s.scoreRatings(stats)
+	return &model.RatingScore{Score: score, Count: count}
+}
+
+// scoreRatings 计算按评分等级加权的平均等级和贝叶斯评分
+// 贝叶斯评分 = (C*m + Σlevel) / (C + n)，m 为先验平均等级，C 为先验权重，n 为评分数
+// 评分数少的书会被拉向先验平均等级，避免一两条高分评分的书排在大量好评的书之前
+// 没有评分时两者均为 0
+func (s *bookRatingService) scoreRatings(stats []*model.RatingTypeStat) (float64, float64) {
+	var n, sum float64
+	for _, stat := range stats {
+		n += float64(stat.Count)
+		sum += float64(stat.Level) * float64(stat.Count)
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return sum / n, (s.priorWeight*s.priorMean + sum) / (s.priorWeight + n)
+}
+
+// 评分趋势的分桶粒度
+const (
+	trendIntervalDay   = "day"
+	trendIntervalWeek  = "week"
+	trendIntervalMonth = "month"
+)
+
+// maxTrendPoints 单次查询评分趋势允许的最大桶数
+const maxTrendPoints = 366
+
+// parseTrendRange 解析评分趋势的日期区间，起始日期对齐到所在桶的起始日期
+// 默认统计最近 30 天、12 周或 12 个月
+func parseTrendRange(interval, from, to string) (time.Time, time.Time, error) {
+	now := time.Now()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if to != "" {
+		t, err := time.ParseInLocation(model.StatDateLayout, to, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, v1.ErrBadRequest
+		}
+		end = t
+	}
+
+	var start time.Time
+	if from != "" {
+		t, err := time.ParseInLocation(model.StatDateLayout, from, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, v1.ErrBadRequest
+		}
+		start = t
+	} else {
+		switch interval {
+		case trendIntervalWeek:
+			start = end.AddDate(0, 0, -7*11)
+		case trendIntervalMonth:
+			start = end.AddDate(0, -11, 0)
+		default:
+			start = end.AddDate(0, 0, -29)
+		}
+	}
+	start = trendBucket(start, interval)
+
+	if start.After(end) {
+		return time.Time{}, time.Time{}, v1.ErrBadRequest
+	}
+	points := 0
+	for day := start; !day.After(end); day = nextTrendBucket(day, interval) {
+		if points++; points > maxTrendPoints {
+			return time.Time{}, time.Time{}, v1.ErrBadRequest
+		}
+	}
+	return start, end, nil
+}
+
+// trendBucket 返回某天所在桶的起始日期，周从周一开始
+func trendBucket(day time.Time, interval string) time.Time {
+	switch interval {
+	case trendIntervalWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case trendIntervalMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	default:
+		return day
+	}
+}
+
+// nextTrendBucket 返回下一个桶的起始日期，bucket 须为桶的起始日期
+func nextTrendBucket(bucket time.Time, interval string) time.Time {
+	switch interval {
+	case trendIntervalWeek:
+		return bucket.AddDate(0, 0, 7)
+	case trendIntervalMonth:
+		return bucket.AddDate(0, 1, 0)
+	default:
+		return bucket.AddDate(0, 0, 1)
+	}
+}
+
+// encodeReviewCursor 把 (点赞数, ID) 编码为不透明的游标
+func encodeReviewCursor(likes int64, id uint) string {
+	raw := strconv.FormatInt(likes, 10) + "." + strconv.FormatUint(uint64(id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeReviewCursor(cursor string) (int64, uint, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, false
+	}
+	likesStr, idStr, found := strings.Cut(string(raw), ".")
+	if !found {
+		return 0, 0, false
+	}
+	likes, err := strconv.ParseInt(likesStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil || id == 0 {
+		return 0, 0, false
+	}
+	return likes, uint(id), true
+}
+
+// maskIP 隐藏IP的后半部分，IPv4 保留前两段，IPv6 保留前两组
+func maskIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.*.*", v4[0], v4[1])
+	}
+	return fmt.Sprintf("%x:%x::*", uint16(parsed[0])<<8|uint16(parsed[1]), uint16(parsed[2])<<8|uint16(parsed[3]))
 }
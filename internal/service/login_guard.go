@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// LoginLockedError 账号或 IP 被限制登录时返回，errors.Is(err, v1.ErrLoginLocked) 成立
+type LoginLockedError struct {
+	RetryAfter time.Duration // 需要等待的时长
+}
+
+func (e *LoginLockedError) Error() string {
+	return v1.ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return v1.ErrLoginLocked
+}
+
+// LoginGuardService 登录防暴力破解，按账号和 IP 统计连续失败次数
+// 账号连续失败 backoff_after 次后每次尝试需等待指数增长的时间，达到 max_failures 次后锁定 lockout
+// 同一 IP 在 failure_window 内失败达到 ip_max_failures 次后锁定 ip_lockout
+type LoginGuardService interface {
+	Check(ctx context.Context, account, userId, ip string) error
+	RecordFailure(ctx context.Context, account, userId, ip string) (*time.Time, error)
+	RecordSuccess(ctx context.Context, account, userId, ip string) error
+	ResetAccount(ctx context.Context, userId string) error
+	ListLoginAttempts(ctx context.Context, req *v1.ListLoginAttemptsRequest) (*v1.ListLoginAttemptsResponse, error)
+	CleanupAttempts(ctx context.Context) (int64, error)
+}
+
+type loginGuardService struct {
+	loginAttemptRepo repository.LoginAttemptRepository
+	*Service
+
+	failureWindow time.Duration // 最后一次失败超过该时长后计数清零
+	backoffAfter  int           // 连续失败该次数后开始退避，0 表示不退避
+	backoffBase   time.Duration // 首次退避的等待时长，之后每次失败翻倍
+	backoffMax    time.Duration // 退避等待的上限
+	maxFailures   int           // 账号连续失败该次数后锁定，0 表示不锁定
+	lockout       time.Duration // 账号锁定时长
+	ipMaxFailures int           // 同一 IP 失败该次数后锁定，0 表示不锁定
+	ipLockout     time.Duration // IP 锁定时长
+	retention     time.Duration // 登录记录保留时长
+}
+
+func NewLoginGuardService(
+	service *Service,
+	conf *viper.Viper,
+	loginAttemptRepo repository.LoginAttemptRepository,
+) LoginGuardService {
+	s := &loginGuardService{
+		Service:          service,
+		loginAttemptRepo: loginAttemptRepo,
+		failureWindow:    conf.GetDuration("login.failure_window"),
+		backoffAfter:     conf.GetInt("login.backoff_after"),
+		backoffBase:      conf.GetDuration("login.backoff_base"),
+		backoffMax:       conf.GetDuration("login.backoff_max"),
+		maxFailures:      conf.GetInt("login.max_failures"),
+		lockout:          conf.GetDuration("login.lockout"),
+		ipMaxFailures:    conf.GetInt("login.ip_max_failures"),
+		ipLockout:        conf.GetDuration("login.ip_lockout"),
+		retention:        conf.GetDuration("login.attempt_retention"),
+	}
+	if s.failureWindow <= 0 {
+		s.failureWindow = 15 * time.Minute
+	}
+	if s.backoffBase <= 0 {
+		s.backoffBase = time.Second
+	}
+	if s.backoffMax <= 0 {
+		s.backoffMax = time.Minute
+	}
+	if s.lockout <= 0 {
+		s.lockout = 15 * time.Minute
+	}
+	if s.ipLockout <= 0 {
+		s.ipLockout = 15 * time.Minute
+	}
+	if s.retention <= 0 {
+		s.retention = 90 * 24 * time.Hour
+	}
+	return s
+}
+
+// Check 检查账号和 IP 当前是否允许尝试登录，被限制时记录本次尝试并返回 *LoginLockedError
+func (s *loginGuardService) Check(ctx context.Context, account, userId, ip string) error {
+	accountKey, ipKey := throttleKey(account, userId), "ip:"+ip
+	throttles, err := s.loginAttemptRepo.GetThrottles(ctx, []string{accountKey, ipKey})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	if t := throttles[accountKey]; t != nil {
+		wait = s.accountWait(t, now)
+	}
+	if t := throttles[ipKey]; t != nil && t.LockedUntil != nil && t.LockedUntil.After(now) {
+		if d := t.LockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	if err := s.record(ctx, account, userId, ip, model.LoginResultLocked); err != nil {
+		return err
+	}
+	return &LoginLockedError{RetryAfter: wait}
+}
+
+// RecordFailure 记录一次失败的登录，本次失败导致账号被锁定时返回锁定截止时间
+func (s *loginGuardService) RecordFailure(ctx context.Context, account, userId, ip string) (*time.Time, error) {
+	now := time.Now()
+	var lockedUntil *time.Time
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.record(ctx, account, userId, ip, model.LoginResultInvalidCredentials); err != nil {
+			return err
+		}
+		_, err := s.loginAttemptRepo.RecordFailure(ctx, throttleKey(account, userId), now, s.failureWindow, func(t *model.LoginThrottle) {
+			if s.maxFailures > 0 && t.Failures >= s.maxFailures {
+				until := now.Add(s.lockout)
+				t.LockedUntil = &until
+				t.Failures = 0
+				lockedUntil = &until
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if ip == "" {
+			return nil
+		}
+		_, err = s.loginAttemptRepo.RecordFailure(ctx, "ip:"+ip, now, s.failureWindow, func(t *model.LoginThrottle) {
+			if s.ipMaxFailures > 0 && t.Failures >= s.ipMaxFailures {
+				until := now.Add(s.ipLockout)
+				t.LockedUntil = &until
+				t.Failures = 0
+			}
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lockedUntil, nil
+}
+
+// RecordSuccess 记录一次成功的登录并清除账号的失败计数，IP 的计数不清除，避免攻击者用自己的账号登录来重置
+func (s *loginGuardService) RecordSuccess(ctx context.Context, account, userId, ip string) error {
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.record(ctx, account, userId, ip, model.LoginResultSuccess); err != nil {
+			return err
+		}
+		return s.loginAttemptRepo.DeleteThrottle(ctx, throttleKey(account, userId))
+	})
+}
+
+// ResetAccount 解除账号的锁定和失败计数，例如用户通过邮件重置了密码
+func (s *loginGuardService) ResetAccount(ctx context.Context, userId string) error {
+	return s.loginAttemptRepo.DeleteThrottle(ctx, throttleKey("", userId))
+}
+
+func (s *loginGuardService) ListLoginAttempts(ctx context.Context, req *v1.ListLoginAttemptsRequest) (*v1.ListLoginAttemptsResponse, error) {
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	attempts, total, err := s.loginAttemptRepo.List(ctx, repository.LoginAttemptFilter{
+		Account: req.Account,
+		UserId:  req.UserId,
+		IP:      req.IP,
+		Result:  req.Result,
+	}, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*v1.LoginAttemptItem, 0, len(attempts))
+	for _, a := range attempts {
+		items = append(items, &v1.LoginAttemptItem{
+			Id:        a.Id,
+			Account:   a.Account,
+			UserId:    a.UserId,
+			IP:        a.IP,
+			Result:    a.Result,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	return &v1.ListLoginAttemptsResponse{Total: total, Items: items}, nil
+}
+
+// CleanupAttempts 删除超过保留时长的登录记录和过期的失败计数
+func (s *loginGuardService) CleanupAttempts(ctx context.Context) (int64, error) {
+	return s.loginAttemptRepo.DeleteBefore(ctx, time.Now().Add(-s.retention))
+}
+
+// accountWait 账号还需等待多久才能再次尝试：锁定中等到锁定结束，退避中等到退避结束
+func (s *loginGuardService) accountWait(t *model.LoginThrottle, now time.Time) time.Duration {
+	if t.LockedUntil != nil && t.LockedUntil.After(now) {
+		return t.LockedUntil.Sub(now)
+	}
+	if s.backoffAfter <= 0 || t.Failures < s.backoffAfter || now.Sub(t.LastFailedAt) > s.failureWindow {
+		return 0
+	}
+	delay := s.backoffMax
+	if shift := t.Failures - s.backoffAfter; shift < 30 {
+		if d := s.backoffBase << shift; d < delay {
+			delay = d
+		}
+	}
+	return t.LastFailedAt.Add(delay).Sub(now)
+}
+
+func (s *loginGuardService) record(ctx context.Context, account, userId, ip, result string) error {
+	return s.loginAttemptRepo.Create(ctx, &model.LoginAttempt{
+		Account: account,
+		UserId:  userId,
+		IP:      ip,
+		Result:  result,
+	})
+}
+
+// throttleKey 账号失败计数的 Key，账号存在时按用户ID统计，使用户名和邮箱登录共用一个计数
+func throttleKey(account, userId string) string {
+	if userId != "" {
+		return "user:" + userId
+	}
+	return "account:" + account
+}
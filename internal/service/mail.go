@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/spf13/viper"
+	"novel-site-backend/pkg/mailer"
+)
+
+//go:embed templates/mail/*.tmpl
+var defaultMailTemplates embed.FS
+
+// 邮件模板名，对应模板目录下的 <name>.tmpl
+const (
+	mailVerifyEmail   = "verify_email"
+	mailResetPassword = "reset_password"
+	mailAccountLocked = "account_locked"
+)
+
+// mailTemplates 邮件模板，每个模板文件定义 subject、text、html 三部分
+// text 和 subject 按纯文本渲染，html 部分会对数据做 HTML 转义
+type mailTemplates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// newMailTemplates 加载 mail.template_dir 下的模板，未配置时使用内置模板
+func newMailTemplates(conf *viper.Viper) (*mailTemplates, error) {
+	var fsys fs.FS
+	if dir := conf.GetString("mail.template_dir"); dir != "" {
+		fsys = os.DirFS(dir)
+	} else {
+		sub, err := fs.Sub(defaultMailTemplates, "templates/mail")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+
+	t := &mailTemplates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	for _, name := range []string{mailVerifyEmail, mailResetPassword, mailAccountLocked} {
+		file := name + ".tmpl"
+		text, err := texttemplate.ParseFS(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		html, err := htmltemplate.ParseFS(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		t.text[name] = text
+		t.html[name] = html
+	}
+	return t, nil
+}
+
+// render 渲染邮件，模板中没有 html 部分时只发送纯文本
+func (t *mailTemplates) render(name, to string, data interface{}) (*mailer.Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.text[name].ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := t.text[name].ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, err
+	}
+	if t.html[name].Lookup("html") != nil {
+		if err := t.html[name].ExecuteTemplate(&html, "html", data); err != nil {
+			return nil, err
+		}
+	}
+	return &mailer.Message{
+		To:      []string{to},
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()),
+		HTML:    strings.TrimSpace(html.String()),
+	}, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"novel-site-backend/pkg/log"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// MailQueue 后台邮件任务队列，由固定数量的 worker 依次执行，队列已满或已关闭时丢弃新任务
+type MailQueue interface {
+	// Enqueue 提交任务，task 收到的 ctx 带单个任务的超时，返回 false 表示任务被丢弃
+	Enqueue(task func(ctx context.Context)) bool
+	// Run 启动 worker 并阻塞到 Close 后队列中的任务全部执行完
+	Run()
+	// Close 停止接收新任务，等待已提交的任务执行完或 ctx 结束
+	Close(ctx context.Context) error
+}
+
+func NewMailQueue(logger *log.Logger, conf *viper.Viper) MailQueue {
+	size := conf.GetInt("mail.queue.size")
+	if size <= 0 {
+		size = 100
+	}
+	workers := conf.GetInt("mail.queue.workers")
+	if workers <= 0 {
+		workers = 2
+	}
+	timeout := conf.GetDuration("mail.queue.timeout")
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	return &mailQueue{
+		logger:  logger,
+		tasks:   make(chan func(ctx context.Context), size),
+		workers: workers,
+		timeout: timeout,
+		done:    make(chan struct{}),
+	}
+}
+
+type mailQueue struct {
+	logger  *log.Logger
+	tasks   chan func(ctx context.Context)
+	workers int
+	timeout time.Duration
+
+	mu      sync.RWMutex
+	closed  bool
+	done    chan struct{}
+	started sync.Once
+}
+
+func (q *mailQueue) Enqueue(task func(ctx context.Context)) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.logger.Warn("邮件队列已关闭，丢弃任务")
+		return false
+	}
+	select {
+	case q.tasks <- task:
+		return true
+	default:
+		q.logger.Warn("邮件队列已满，丢弃任务", zap.Int("队列长度", cap(q.tasks)))
+		return false
+	}
+}
+
+func (q *mailQueue) Run() {
+	q.started.Do(func() {
+		defer close(q.done)
+		var wg sync.WaitGroup
+		wg.Add(q.workers)
+		for i := 0; i < q.workers; i++ {
+			go func() {
+				defer wg.Done()
+				for task := range q.tasks {
+					q.exec(task)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func (q *mailQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.tasks)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// exec 执行单个任务，任务 panic 时只记录日志，不影响 worker 继续执行
+func (q *mailQueue) exec(task func(ctx context.Context)) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			q.logger.Error("邮件任务异常", zap.Any("panic", r))
+		}
+	}()
+	task(ctx)
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"novel-site-backend/pkg/wordfilter"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+type ModerationService interface {
+	ScreenComment(ctx context.Context, comment string) (string, string, error)
+	ListReviewQueue(ctx context.Context, req *v1.ListReviewQueueRequest) (*v1.ListReviewQueueResponse, error)
+	ListSensitiveWords(ctx context.Context, page, pageSize int) (*v1.ListSensitiveWordsResponse, error)
+	AddSensitiveWords(ctx context.Context, req *v1.AddSensitiveWordsRequest) error
+	DeleteSensitiveWord(ctx context.Context, id uint) error
+}
+
+// wordList 编译好的敏感词库，actions 与构造过滤器时的词序一一对应
+type wordList struct {
+	filter    *wordfilter.Filter
+	actions   []string
+	count     int64
+	updatedAt time.Time
+}
+
+type moderationService struct {
+	bookRatingRepo    repository.BookRatingRepository
+	sensitiveWordRepo repository.SensitiveWordRepository
+	*Service
+
+	preModerate    bool          // 所有评论先进入审核队列
+	reloadInterval time.Duration // 检查词库是否被修改的间隔
+
+	words     atomic.Pointer[wordList]
+	checkedAt atomic.Int64 // 上次检查词库的时间(UnixNano)
+	reloadMu  sync.Mutex
+}
+
+func NewModerationService(
+	service *Service,
+	conf *viper.Viper,
+	bookRatingRepo repository.BookRatingRepository,
+	sensitiveWordRepo repository.SensitiveWordRepository,
+) ModerationService {
+	s := &moderationService{
+		Service:           service,
+		bookRatingRepo:    bookRatingRepo,
+		sensitiveWordRepo: sensitiveWordRepo,
+		preModerate:       conf.GetBool("moderation.pre_moderate"),
+		reloadInterval:    conf.GetDuration("moderation.reload_interval"),
+	}
+	if s.reloadInterval <= 0 {
+		s.reloadInterval = 30 * time.Second
+	}
+	return s
+}
+
+// ScreenComment 用敏感词库检查评论，返回处理后的评论和审核状态
+// 命中 reject 词时返回 ErrCommentRejected，命中 review 词时进入待审核，命中 mask 词的部分替换为 *
+func (s *moderationService) ScreenComment(ctx context.Context, comment string) (string, string, error) {
+	status := model.RatingStatusApproved
+	if strings.TrimSpace(comment) == "" {
+		return comment, status, nil
+	}
+	if s.preModerate {
+		status = model.RatingStatusPending
+	}
+
+	words, err := s.wordList(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	var masks []wordfilter.Match
+	for _, m := range words.filter.FindAll(comment) {
+		switch words.actions[m.Index] {
+		case model.SensitiveActionReject:
+			return "", "", v1.ErrCommentRejected
+		case model.SensitiveActionReview:
+			status = model.RatingStatusPending
+		default:
+			masks = append(masks, m)
+		}
+	}
+	return wordfilter.Mask(comment, masks, '*'), status, nil
+}
+
+// ListReviewQueue 按审核状态获取评论，默认获取待审核队列
+func (s *moderationService) ListReviewQueue(ctx context.Context, req *v1.ListReviewQueueRequest) (*v1.ListReviewQueueResponse, error) {
+	status, page, pageSize, ok := parseQueueRequest(req)
+	if !ok {
+		return nil, v1.ErrBadRequest
+	}
+
+	ratings, total, err := s.bookRatingRepo.ListByStatus(ctx, status, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*v1.BookRatingResponse, 0, len(ratings))
+	for _, rating := range ratings {
+		items = append(items, &v1.BookRatingResponse{
+			Id:           rating.Id,
+			BookId:       rating.BookId,
+			RatingTypeId: rating.RatingTypeId,
+			Comment:      rating.Comment,
+			IP:           rating.IP,
+			Status:       rating.Status,
+			CreatedAt:    rating.CreatedAt,
+			UpdatedAt:    rating.UpdatedAt,
+		})
+	}
+
+	return &v1.ListReviewQueueResponse{
+		Total: total,
+		Items: items,
+	}, nil
+}
+
+func (s *moderationService) ListSensitiveWords(ctx context.Context, page, pageSize int) (*v1.ListSensitiveWordsResponse, error) {
+	words, total, err := s.sensitiveWordRepo.List(ctx, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*v1.SensitiveWordResponse, 0, len(words))
+	for _, word := range words {
+		items = append(items, &v1.SensitiveWordResponse{
+			Id:        word.Id,
+			Word:      word.Word,
+			Action:    word.Action,
+			CreatedAt: word.CreatedAt,
+			UpdatedAt: word.UpdatedAt,
+		})
+	}
+
+	return &v1.ListSensitiveWordsResponse{
+		Total: total,
+		Items: items,
+	}, nil
+}
+
+// AddSensitiveWords 批量添加敏感词，完成后立即重新加载词库
+func (s *moderationService) AddSensitiveWords(ctx context.Context, req *v1.AddSensitiveWordsRequest) error {
+	seen := make(map[string]bool, len(req.Words))
+	words := make([]*model.SensitiveWord, 0, len(req.Words))
+	for _, word := range req.Words {
+		word = strings.TrimSpace(word)
+		if word == "" || seen[word] {
+			continue
+		}
+		if len([]rune(word)) > 64 {
+			return v1.ErrBadRequest
+		}
+		seen[word] = true
+		words = append(words, &model.SensitiveWord{Word: word, Action: req.Action})
+	}
+	if len(words) == 0 {
+		return v1.ErrBadRequest
+	}
+
+	if err := s.sensitiveWordRepo.Upsert(ctx, words); err != nil {
+		return err
+	}
+	return s.reload(ctx)
+}
+
+// DeleteSensitiveWord 删除敏感词，完成后立即重新加载词库
+func (s *moderationService) DeleteSensitiveWord(ctx context.Context, id uint) error {
+	if err := s.sensitiveWordRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return s.reload(ctx)
+}
+
+// wordList 获取当前词库，距上次检查超过 reloadInterval 时检查词库是否被修改（可能来自其他实例），有修改则重新加载
+func (s *moderationService) wordList(ctx context.Context) (*wordList, error) {
+	words := s.words.Load()
+	if words != nil && time.Since(time.Unix(0, s.checkedAt.Load())) < s.reloadInterval {
+		return words, nil
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	// 等待锁期间可能已被其他请求检查过
+	words = s.words.Load()
+	if words != nil && time.Since(time.Unix(0, s.checkedAt.Load())) < s.reloadInterval {
+		return words, nil
+	}
+
+	count, updatedAt, err := s.sensitiveWordRepo.Version(ctx)
+	if err != nil {
+		if words != nil {
+			// 检查失败时继续使用旧词库
+			s.logger.WithContext(ctx).Warn("sensitive word version check failed", zap.Error(err))
+			return words, nil
+		}
+		return nil, err
+	}
+	s.checkedAt.Store(time.Now().UnixNano())
+	if words != nil && words.count == count && words.updatedAt.Equal(updatedAt) {
+		return words, nil
+	}
+	return s.load(ctx)
+}
+
+// reload 强制重新加载词库
+func (s *moderationService) reload(ctx context.Context) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	_, err := s.load(ctx)
+	return err
+}
+
+// load 从数据库加载并编译词库，调用方需持有 reloadMu
+func (s *moderationService) load(ctx context.Context) (*wordList, error) {
+	words, err := s.sensitiveWordRepo.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &wordList{
+		actions: make([]string, len(words)),
+		count:   int64(len(words)),
+	}
+	texts := make([]string, len(words))
+	for i, word := range words {
+		texts[i] = word.Word
+		list.actions[i] = word.Action
+		if word.UpdatedAt.After(list.updatedAt) {
+			list.updatedAt = word.UpdatedAt
+		}
+	}
+	list.filter = wordfilter.New(texts)
+
+	s.words.Store(list)
+	s.checkedAt.Store(time.Now().UnixNano())
+	s.logger.Info("sensitive words loaded", zap.Int("words", len(words)))
+	return list, nil
+}
+
+// parseQueueRequest 校验审核队列请求，状态默认为待审核
+func parseQueueRequest(req *v1.ListReviewQueueRequest) (string, int, int, bool) {
+	status := req.Status
+	switch status {
+	case "":
+		status = model.RatingStatusPending
+	case model.RatingStatusPending, model.RatingStatusApproved, model.RatingStatusRejected:
+	default:
+		return "", 0, 0, false
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return status, page, pageSize, true
+}
@@ -1,29 +1,508 @@
 package service
 
 import (
-    "context"
+	"context"
+	"errors"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/internal/repository"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
+// UserPermissions 用户拥有的角色和权限编码
+type UserPermissions struct {
+	Roles       []string
+	Permissions map[string]struct{}
+}
+
+// Has 判断是否拥有某个权限，管理员拥有全部权限
+func (p *UserPermissions) Has(code string) bool {
+	if p.IsAdmin() {
+		return true
+	}
+	_, ok := p.Permissions[code]
+	return ok
+}
+
+// IsAdmin 判断是否拥有管理员角色
+func (p *UserPermissions) IsAdmin() bool {
+	for _, role := range p.Roles {
+		if role == model.RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// APIRoute 已注册的接口路由
+type APIRoute struct {
+	Method      string
+	Path        string // gin 的路由模板
+	Name        string
+	Description string
+}
+
+// APIPermissionSyncResult 同步接口权限的结果
+type APIPermissionSyncResult struct {
+	Created int // 新注册的接口数
+	Updated int // 名称、描述等有变化或重新出现的接口数
+	Stale   int // 新标记为过期的接口数
+}
+
 type PermissionService interface {
-	GetPermission(ctx context.Context, id int64) (*model.Permission, error)
+	GetPermission(ctx context.Context, id uint) (*v1.PermissionResponse, error)
+	GetPermissionTree(ctx context.Context) (*v1.PermissionTreeResponse, error)
+	CreatePermission(ctx context.Context, req *v1.CreatePermissionRequest) (*v1.CreatePermissionResponse, error)
+	UpdatePermission(ctx context.Context, id uint, req *v1.UpdatePermissionRequest) error
+	DeletePermission(ctx context.Context, id uint) error
+	SyncAPIPermissions(ctx context.Context, routes []*APIRoute) (*APIPermissionSyncResult, error)
+	GetUserPermissions(ctx context.Context, userId string) (*UserPermissions, error)
+	HasPermission(ctx context.Context, userId string, code string) (bool, error)
+	CheckGrant(ctx context.Context, operatorId string, roleIds []uint, permissionIds []uint) error
+	InvalidateCache(userIds ...string)
 }
+
+// permissionCacheEntry 缓存的用户权限，过期后重新从数据库加载
+type permissionCacheEntry struct {
+	perms     *UserPermissions
+	expiresAt time.Time
+}
+
+type permissionService struct {
+	*Service
+	permissionRepository     repository.PermissionRepository
+	roleRepository           repository.RoleRepository
+	rolePermissionRepository repository.RolePermissionRepository
+
+	cacheTTL time.Duration // 用户权限缓存时长，角色或权限变更后最迟在该时长后生效
+
+	cacheMu sync.RWMutex
+	cache   map[string]*permissionCacheEntry
+}
+
 func NewPermissionService(
-    service *Service,
-    permissionRepository repository.PermissionRepository,
+	service *Service,
+	conf *viper.Viper,
+	permissionRepository repository.PermissionRepository,
+	roleRepository repository.RoleRepository,
+	rolePermissionRepository repository.RolePermissionRepository,
 ) PermissionService {
-	return &permissionService{
-		Service:        service,
-		permissionRepository: permissionRepository,
+	s := &permissionService{
+		Service:                  service,
+		permissionRepository:     permissionRepository,
+		roleRepository:           roleRepository,
+		rolePermissionRepository: rolePermissionRepository,
+		cacheTTL:                 conf.GetDuration("rbac.cache_ttl"),
+		cache:                    make(map[string]*permissionCacheEntry),
+	}
+	if s.cacheTTL <= 0 {
+		s.cacheTTL = time.Minute
 	}
+	return s
 }
 
-type permissionService struct {
-	*Service
-	permissionRepository repository.PermissionRepository
+func (s *permissionService) GetPermission(ctx context.Context, id uint) (*v1.PermissionResponse, error) {
+	permission, err := s.permissionRepository.GetPermission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toPermissionResponse(permission), nil
 }
 
-func (s *permissionService) GetPermission(ctx context.Context, id int64) (*model.Permission, error) {
-	return s.permissionRepository.GetPermission(ctx, id)
+// GetPermissionTree 按 ParentId 把全部权限组装成树，父级不存在的权限作为顶级
+func (s *permissionService) GetPermissionTree(ctx context.Context) (*v1.PermissionTreeResponse, error) {
+	permissions, err := s.permissionRepository.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*v1.PermissionNode, len(permissions))
+	for _, permission := range permissions {
+		nodes[permission.Id] = &v1.PermissionNode{
+			PermissionResponse: toPermissionResponse(permission),
+			Children:           []*v1.PermissionNode{},
+		}
+	}
+	roots := make([]*v1.PermissionNode, 0)
+	for _, permission := range permissions {
+		node := nodes[permission.Id]
+		if parent, ok := nodes[permission.ParentId]; ok && permission.ParentId != permission.Id {
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	return &v1.PermissionTreeResponse{Items: roots}, nil
+}
+
+// CreatePermission 创建权限，编码与已删除的权限相同时恢复该权限
+func (s *permissionService) CreatePermission(ctx context.Context, req *v1.CreatePermissionRequest) (*v1.CreatePermissionResponse, error) {
+	var permission *model.Permission
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		existing, err := s.permissionRepository.GetUnscopedByCode(ctx, req.Code)
+		if err != nil {
+			return err
+		}
+		if existing != nil && !existing.DeletedAt.Valid {
+			return v1.ErrPermissionExists
+		}
+		if err := s.checkParent(ctx, 0, req.ParentId); err != nil {
+			return err
+		}
+
+		permission = existing
+		if permission == nil {
+			permission = &model.Permission{Code: req.Code}
+		}
+		permission.Name = req.Name
+		permission.Type = req.Type
+		permission.ParentId = req.ParentId
+		permission.Path = req.Path
+		permission.Description = req.Description
+		if existing != nil {
+			permission.DeletedAt = gorm.DeletedAt{}
+			return s.permissionRepository.Save(ctx, permission)
+		}
+		return s.permissionRepository.Create(ctx, permission)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CreatePermissionResponse{Id: permission.Id}, nil
+}
+
+func (s *permissionService) UpdatePermission(ctx context.Context, id uint, req *v1.UpdatePermissionRequest) error {
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		permission, err := s.permissionRepository.GetPermission(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.checkParent(ctx, id, req.ParentId); err != nil {
+			return err
+		}
+		// 已有子级的权限不能改为 api 类型
+		if req.Type == model.PermissionTypeAPI && permission.Type != model.PermissionTypeAPI {
+			children, err := s.permissionRepository.CountChildren(ctx, id)
+			if err != nil {
+				return err
+			}
+			if children > 0 {
+				return v1.ErrPermissionHasChildren
+			}
+		}
+
+		permission.Name = req.Name
+		permission.Type = req.Type
+		permission.ParentId = req.ParentId
+		permission.Path = req.Path
+		permission.Description = req.Description
+		return s.permissionRepository.Save(ctx, permission)
+	})
+}
+
+// DeletePermission 删除权限并从所有角色收回，有子级的权限不能删除
+func (s *permissionService) DeletePermission(ctx context.Context, id uint) error {
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.permissionRepository.GetPermission(ctx, id); err != nil {
+			return err
+		}
+		children, err := s.permissionRepository.CountChildren(ctx, id)
+		if err != nil {
+			return err
+		}
+		if children > 0 {
+			return v1.ErrPermissionHasChildren
+		}
+
+		if err := s.rolePermissionRepository.DeleteByPermission(ctx, id); err != nil {
+			return err
+		}
+		return s.permissionRepository.Delete(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.InvalidateCache()
+	return nil
+}
+
+// SyncAPIPermissions 按已注册的路由同步 api 类型的权限
+// 新路由的权限挂在 PermAPIRoot 下；已有的权限更新名称和描述，保留管理员调整过的父级；
+// 不再存在的路由标记为过期，被删除的接口权限不会重新创建
+func (s *permissionService) SyncAPIPermissions(ctx context.Context, routes []*APIRoute) (*APIPermissionSyncResult, error) {
+	parentId, err := s.apiRootId(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.permissionRepository.ListUnscopedByType(ctx, model.PermissionTypeAPI)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]*model.Permission, len(existing))
+	for _, permission := range existing {
+		byCode[permission.Code] = permission
+	}
+
+	result := &APIPermissionSyncResult{}
+	seen := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		code := model.APIPermissionCode(route.Method, route.Path)
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		name := truncateRunes(route.Name, 50)
+		description := truncateRunes(route.Description, 200)
+
+		permission, ok := byCode[code]
+		if !ok {
+			created, err := s.permissionRepository.CreateIfNotExists(ctx, &model.Permission{
+				Name:        name,
+				Code:        code,
+				Type:        model.PermissionTypeAPI,
+				ParentId:    parentId,
+				Path:        route.Path,
+				Method:      route.Method,
+				Description: description,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if created {
+				result.Created++
+			}
+			continue
+		}
+		if permission.DeletedAt.Valid {
+			continue
+		}
+		if permission.Name == name && permission.Description == description &&
+			permission.Path == route.Path && permission.Method == route.Method && !permission.Stale {
+			continue
+		}
+		permission.Name = name
+		permission.Description = description
+		permission.Path = route.Path
+		permission.Method = route.Method
+		permission.Stale = false
+		if err := s.permissionRepository.Save(ctx, permission); err != nil {
+			return nil, err
+		}
+		result.Updated++
+	}
+
+	for _, permission := range existing {
+		if seen[permission.Code] || permission.DeletedAt.Valid || permission.Stale {
+			continue
+		}
+		permission.Stale = true
+		if err := s.permissionRepository.Save(ctx, permission); err != nil {
+			return nil, err
+		}
+		result.Stale++
+	}
+
+	if result.Updated > 0 || result.Stale > 0 {
+		s.InvalidateCache()
+	}
+	return result, nil
+}
+
+// apiRootId 获取接口权限的默认父级，不存在时创建，被删除时挂在顶级
+func (s *permissionService) apiRootId(ctx context.Context) (uint, error) {
+	root, err := s.permissionRepository.GetUnscopedByCode(ctx, model.PermAPIRoot)
+	if err != nil {
+		return 0, err
+	}
+	if root == nil {
+		_, err = s.permissionRepository.CreateIfNotExists(ctx, &model.Permission{
+			Name:        "接口权限",
+			Code:        model.PermAPIRoot,
+			Type:        model.PermissionTypeMenu,
+			Description: "启动时根据路由自动注册的接口",
+		})
+		if err != nil {
+			return 0, err
+		}
+		if root, err = s.permissionRepository.GetUnscopedByCode(ctx, model.PermAPIRoot); err != nil {
+			return 0, err
+		}
+	}
+	if root == nil || root.DeletedAt.Valid || root.Type == model.PermissionTypeAPI {
+		return 0, nil
+	}
+	return root.Id, nil
+}
+
+// checkParent 检查父级存在且可以有子级，id 不为 0 时还要检查不会形成环
+func (s *permissionService) checkParent(ctx context.Context, id, parentId uint) error {
+	if parentId == 0 {
+		return nil
+	}
+	if parentId == id {
+		return v1.ErrInvalidPermissionParent
+	}
+	parent, err := s.permissionRepository.GetPermission(ctx, parentId)
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			return v1.ErrInvalidPermissionParent
+		}
+		return err
+	}
+	// api 类型的权限对应单个接口，只能作为叶子节点
+	if parent.Type == model.PermissionTypeAPI {
+		return v1.ErrInvalidPermissionParent
+	}
+	if id == 0 {
+		return nil
+	}
+
+	permissions, err := s.permissionRepository.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+	parents := make(map[uint]uint, len(permissions))
+	for _, permission := range permissions {
+		parents[permission.Id] = permission.ParentId
+	}
+	for p, depth := parentId, 0; p != 0 && depth <= len(permissions); p, depth = parents[p], depth+1 {
+		if p == id {
+			return v1.ErrInvalidPermissionParent
+		}
+	}
+	return nil
+}
+
+// GetUserPermissions 获取用户的角色和权限，结果按 cache_ttl 缓存
+func (s *permissionService) GetUserPermissions(ctx context.Context, userId string) (*UserPermissions, error) {
+	now := time.Now()
+	s.cacheMu.RLock()
+	entry, ok := s.cache[userId]
+	s.cacheMu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.perms, nil
+	}
+
+	roles, err := s.roleRepository.ListCodesByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.permissionRepository.ListCodesByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	perms := &UserPermissions{
+		Roles:       roles,
+		Permissions: make(map[string]struct{}, len(codes)),
+	}
+	for _, code := range codes {
+		perms.Permissions[code] = struct{}{}
+	}
+
+	s.cacheMu.Lock()
+	// 顺带清理过期的缓存，避免不活跃用户一直占用内存
+	if len(s.cache) >= 1024 {
+		for k, v := range s.cache {
+			if !now.Before(v.expiresAt) {
+				delete(s.cache, k)
+			}
+		}
+	}
+	s.cache[userId] = &permissionCacheEntry{perms: perms, expiresAt: now.Add(s.cacheTTL)}
+	s.cacheMu.Unlock()
+	return perms, nil
+}
+
+func (s *permissionService) HasPermission(ctx context.Context, userId string, code string) (bool, error) {
+	perms, err := s.GetUserPermissions(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return perms.Has(code), nil
+}
+
+// CheckGrant 检查操作人能否授予角色和权限，防止越权
+// 管理员不受限制；其他人不能授予管理员角色，授予的角色包含的权限和直接授予的权限都必须是自己拥有的
+func (s *permissionService) CheckGrant(ctx context.Context, operatorId string, roleIds []uint, permissionIds []uint) error {
+	perms, err := s.GetUserPermissions(ctx, operatorId)
+	if err != nil {
+		return err
+	}
+	if perms.IsAdmin() {
+		return nil
+	}
+
+	ids := append([]uint{}, permissionIds...)
+	if len(roleIds) > 0 {
+		roles, err := s.roleRepository.ListByIds(ctx, roleIds)
+		if err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if role.Code == model.RoleAdmin {
+				return v1.ErrAdminRoleRequired
+			}
+			rolePermissionIds, err := s.rolePermissionRepository.ListPermissionIds(ctx, role.Id)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, rolePermissionIds...)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	permissions, err := s.permissionRepository.ListByIds(ctx, uniqueIds(ids))
+	if err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !perms.Has(permission.Code) {
+			return v1.ErrPermissionNotHeld
+		}
+	}
+	return nil
+}
+
+// InvalidateCache 清除指定用户的权限缓存，不传参数时清除全部
+func (s *permissionService) InvalidateCache(userIds ...string) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	if len(userIds) == 0 {
+		s.cache = make(map[string]*permissionCacheEntry)
+		return
+	}
+	for _, userId := range userIds {
+		delete(s.cache, userId)
+	}
+}
+
+func toPermissionResponse(permission *model.Permission) *v1.PermissionResponse {
+	return &v1.PermissionResponse{
+		Id:          permission.Id,
+		Name:        permission.Name,
+		Code:        permission.Code,
+		Type:        permission.Type,
+		ParentId:    permission.ParentId,
+		Path:        permission.Path,
+		Method:      permission.Method,
+		Stale:       permission.Stale,
+		Description: permission.Description,
+		CreatedAt:   permission.CreatedAt,
+		UpdatedAt:   permission.UpdatedAt,
+	}
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
 }
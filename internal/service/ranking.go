@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"math"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+type RankingService interface {
+	GetRanking(ctx context.Context, board, period string, limit int) (*v1.GetRankingResponse, error)
+	RefreshTrendingScores(ctx context.Context) error
+}
+
+type rankingService struct {
+	bookRepo       repository.BookRepository
+	bookStatRepo   repository.BookStatRepository
+	bookRatingRepo repository.BookRatingRepository
+	*Service
+
+	halfLifeDays   float64 // 热度分半衰期（天）
+	windowDays     int     // 计算热度分时统计的天数
+	downloadWeight float64 // 一次下载折算的浏览量
+	minRatings     int     // 进入好评榜所需的最少评分数
+}
+
+func NewRankingService(
+	service *Service,
+	conf *viper.Viper,
+	bookRepo repository.BookRepository,
+	bookStatRepo repository.BookStatRepository,
+	bookRatingRepo repository.BookRatingRepository,
+) RankingService {
+	s := &rankingService{
+		Service:        service,
+		bookRepo:       bookRepo,
+		bookStatRepo:   bookStatRepo,
+		bookRatingRepo: bookRatingRepo,
+		halfLifeDays:   conf.GetFloat64("ranking.trending.half_life_days"),
+		windowDays:     conf.GetInt("ranking.trending.window_days"),
+		downloadWeight: conf.GetFloat64("ranking.trending.download_weight"),
+		minRatings:     conf.GetInt("ranking.min_ratings"),
+	}
+	if s.halfLifeDays <= 0 {
+		s.halfLifeDays = 3
+	}
+	if s.windowDays <= 0 {
+		s.windowDays = 30
+	}
+	if s.downloadWeight <= 0 {
+		s.downloadWeight = 5
+	}
+	if s.minRatings <= 0 {
+		s.minRatings = 1
+	}
+	return s
+}
+
+// GetRanking 获取榜单
+func (s *rankingService) GetRanking(ctx context.Context, board, period string, limit int) (*v1.GetRankingResponse, error) {
+	days, ok := rankingPeriodDays(period)
+	if !ok {
+		return nil, v1.ErrBadRequest
+	}
+
+	// 周期起始时间，总榜为零值
+	var since time.Time
+	if days > 0 {
+		now := time.Now()
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1-days)
+	}
+	sinceDate := since.Format(model.StatDateLayout)
+
+	var (
+		items []*model.BookRankItem
+		err   error
+	)
+	switch board {
+	case v1.RankingBoardHot:
+		if days > 0 {
+			items, err = s.bookStatRepo.TopSince(ctx, "views", sinceDate, limit)
+		} else {
+			items, err = s.bookRepo.TopBy(ctx, "hot_value", since, limit)
+		}
+	case v1.RankingBoardDownloads:
+		if days > 0 {
+			items, err = s.bookStatRepo.TopSince(ctx, "downloads", sinceDate, limit)
+		} else {
+			items, err = s.bookRepo.TopBy(ctx, "downloads", since, limit)
+		}
+	case v1.RankingBoardRating:
+		if days > 0 {
+			items, err = s.bookRatingRepo.TopRated(ctx, since, s.minRatings, limit)
+		} else {
+			// 总榜直接使用图书上冗余存储的贝叶斯评分
+			items, err = s.bookRepo.TopBy(ctx, "rating_score", since, limit)
+		}
+	case v1.RankingBoardNewest:
+		items, err = s.bookRepo.Newest(ctx, since, limit)
+	default:
+		return nil, v1.ErrBadRequest
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rankingItems := make([]*v1.RankingItem, 0, len(items))
+	for i, item := range items {
+		rankingItems = append(rankingItems, &v1.RankingItem{
+			Rank:      i + 1,
+			Id:        item.BookId,
+			Title:     item.Title,
+			Author:    item.Author,
+			Cover:     item.Cover,
+			Score:     item.Score,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+
+	return &v1.GetRankingResponse{
+		Board:  board,
+		Period: period,
+		Items:  rankingItems,
+	}, nil
+}
+
+// RefreshTrendingScores 根据最近 windowDays 天的每日统计重新计算热度分
+// 每天的浏览量（下载按 downloadWeight 折算）按半衰期指数衰减后累加
+func (s *rankingService) RefreshTrendingScores(ctx context.Context) error {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, 1-s.windowDays).Format(model.StatDateLayout)
+
+	stats, err := s.bookStatRepo.ListSince(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	scores := make(map[uint]float64)
+	for _, stat := range stats {
+		date, err := time.ParseInLocation(model.StatDateLayout, stat.Date, now.Location())
+		if err != nil {
+			s.logger.Warn("invalid stat date", zap.Uint("book_id", stat.BookId), zap.String("date", stat.Date))
+			continue
+		}
+		age := today.Sub(date).Hours() / 24
+		if age < 0 {
+			age = 0
+		}
+		weight := math.Pow(0.5, age/s.halfLifeDays)
+		scores[stat.BookId] += (float64(stat.Views) + float64(stat.Downloads)*s.downloadWeight) * weight
+	}
+
+	if err := s.bookRepo.UpdateTrendingScores(ctx, scores); err != nil {
+		return err
+	}
+	s.logger.Info("trending scores refreshed", zap.Int("books", len(scores)))
+	return nil
+}
+
+// rankingPeriodDays 返回榜单周期覆盖的天数，总榜返回 0
+func rankingPeriodDays(period string) (int, bool) {
+	switch period {
+	case v1.RankingPeriodDaily:
+		return 1, true
+	case v1.RankingPeriodWeekly:
+		return 7, true
+	case v1.RankingPeriodMonthly:
+		return 30, true
+	case v1.RankingPeriodAll:
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// minDuplicateCommentLen 参与重复评论检测的最短评论长度（规范化后的字符数），过短的评论如 "好看" 重复很常见
+const minDuplicateCommentLen = 5
+
+type RatingFraudService interface {
+	DetectFraud(ctx context.Context) (int, error)
+	ListRatingFlags(ctx context.Context, req *v1.ListRatingFlagsRequest) (*v1.ListRatingFlagsResponse, error)
+	RemoveFlaggedRatings(ctx context.Context, operator string, req *v1.ReviewRatingFlagsRequest) (*v1.ReviewRatingFlagsResponse, error)
+	ClearRatingFlags(ctx context.Context, operator string, req *v1.ReviewRatingFlagsRequest) (*v1.ReviewRatingFlagsResponse, error)
+}
+
+type ratingFraudService struct {
+	bookRatingRepo     repository.BookRatingRepository
+	bookRatingStatRepo repository.BookRatingStatRepository
+	ratingFlagRepo     repository.RatingFlagRepository
+	bookRatingService  BookRatingService
+	*Service
+
+	lookback                  time.Duration // 每次检测最近多长时间内的评分
+	subnetThreshold           int           // 同一网段评分数阈值，0 表示不检测，下同
+	duplicateCommentThreshold int           // 相同评论评分数阈值
+	burstWindow               time.Duration // 集中评分的时间窗口
+	burstThreshold            int           // 时间窗口内评分数阈值
+	newVisitorAge             time.Duration // Cookie 签发后多长时间内的访客视为新访客
+	newVisitorThreshold       int           // 新访客评分数阈值
+}
+
+func NewRatingFraudService(
+	service *Service,
+	conf *viper.Viper,
+	bookRatingRepo repository.BookRatingRepository,
+	bookRatingStatRepo repository.BookRatingStatRepository,
+	ratingFlagRepo repository.RatingFlagRepository,
+	bookRatingService BookRatingService,
+) RatingFraudService {
+	s := &ratingFraudService{
+		Service:                   service,
+		bookRatingRepo:            bookRatingRepo,
+		bookRatingStatRepo:        bookRatingStatRepo,
+		ratingFlagRepo:            ratingFlagRepo,
+		bookRatingService:         bookRatingService,
+		lookback:                  conf.GetDuration("fraud.lookback"),
+		subnetThreshold:           conf.GetInt("fraud.subnet_threshold"),
+		duplicateCommentThreshold: conf.GetInt("fraud.duplicate_comment_threshold"),
+		burstWindow:               conf.GetDuration("fraud.burst_window"),
+		burstThreshold:            conf.GetInt("fraud.burst_threshold"),
+		newVisitorAge:             conf.GetDuration("fraud.new_visitor_age"),
+		newVisitorThreshold:       conf.GetInt("fraud.new_visitor_threshold"),
+	}
+	if s.lookback <= 0 {
+		s.lookback = 24 * time.Hour
+	}
+	return s
+}
+
+// DetectFraud 分析最近 lookback 内的评分，按图书找出可疑的评分集群并标记，返回新标记的评分数
+// 被标记的评分从评分汇总中扣除，等待管理员复核；已复核过的评分不会被再次标记
+func (s *ratingFraudService) DetectFraud(ctx context.Context) (int, error) {
+	ratings, err := s.bookRatingRepo.ListSince(ctx, time.Now().Add(-s.lookback))
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]uint, 0, len(ratings))
+	for _, rating := range ratings {
+		ids = append(ids, rating.Id)
+	}
+	reviewed, err := s.ratingFlagRepo.ListRatingIds(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	// 集群按全部评分识别，只标记尚未标记过的评分
+	var bookIds []uint
+	byBook := make(map[uint][]*model.BookRating)
+	for _, rating := range ratings {
+		if _, ok := byBook[rating.BookId]; !ok {
+			bookIds = append(bookIds, rating.BookId)
+		}
+		byBook[rating.BookId] = append(byBook[rating.BookId], rating)
+	}
+
+	flagged := 0
+	for _, bookId := range bookIds {
+		reasons := s.detectBook(byBook[bookId])
+		var suspects []*model.BookRating
+		for _, rating := range byBook[bookId] {
+			if len(reasons[rating.Id]) > 0 && !reviewed[rating.Id] && !rating.Flagged {
+				suspects = append(suspects, rating)
+			}
+		}
+		if len(suspects) == 0 {
+			continue
+		}
+
+		count, err := s.flagRatings(ctx, bookId, suspects, reasons)
+		if err != nil {
+			return flagged, err
+		}
+		flagged += count
+	}
+
+	if flagged > 0 {
+		s.logger.Info("suspicious ratings flagged", zap.Int("ratings", flagged))
+	}
+	return flagged, nil
+}
+
+// ListRatingFlags 按复核状态获取可疑评分，默认获取待复核的评分
+func (s *ratingFraudService) ListRatingFlags(ctx context.Context, req *v1.ListRatingFlagsRequest) (*v1.ListRatingFlagsResponse, error) {
+	status := req.Status
+	if status == "" {
+		status = model.RatingFlagStatusPending
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	flags, total, err := s.ratingFlagRepo.ListByStatus(ctx, status, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	ratingIds := make([]uint, 0, len(flags))
+	for _, flag := range flags {
+		ratingIds = append(ratingIds, flag.RatingId)
+	}
+	ratings, err := s.bookRatingRepo.GetByIds(ctx, ratingIds)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[uint]*model.BookRating, len(ratings))
+	for _, rating := range ratings {
+		byId[rating.Id] = rating
+	}
+
+	items := make([]*v1.RatingFlagItem, 0, len(flags))
+	for _, flag := range flags {
+		item := &v1.RatingFlagItem{
+			Id:        flag.Id,
+			RatingId:  flag.RatingId,
+			BookId:    flag.BookId,
+			Reasons:   strings.Split(flag.Reasons, ","),
+			Status:    flag.Status,
+			Operator:  flag.Operator,
+			CreatedAt: flag.CreatedAt,
+		}
+		if rating, ok := byId[flag.RatingId]; ok {
+			item.RatingTypeId = rating.RatingTypeId
+			item.Comment = rating.Comment
+			item.IP = rating.IP
+			item.UserId = rating.UserId
+			item.VisitorId = rating.VisitorId
+			item.RatingCreatedAt = rating.CreatedAt
+		}
+		items = append(items, item)
+	}
+
+	return &v1.ListRatingFlagsResponse{
+		Total: total,
+		Items: items,
+	}, nil
+}
+
+// RemoveFlaggedRatings 确认评分作弊，批量删除待复核的可疑评分
+// 可疑评分已从评分汇总中扣除，删除时无需再调整汇总
+func (s *ratingFraudService) RemoveFlaggedRatings(ctx context.Context, operator string, req *v1.ReviewRatingFlagsRequest) (*v1.ReviewRatingFlagsResponse, error) {
+	resp := new(v1.ReviewRatingFlagsResponse)
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		flags, err := s.ratingFlagRepo.GetByIds(ctx, req.Ids, model.RatingFlagStatusPending)
+		if err != nil {
+			return err
+		}
+		for _, flag := range flags {
+			if err := s.bookRatingRepo.Delete(ctx, flag.RatingId); err != nil {
+				return err
+			}
+			if err := s.ratingFlagRepo.UpdateStatus(ctx, flag.Id, model.RatingFlagStatusRemoved, operator); err != nil {
+				return err
+			}
+		}
+		resp.Updated = int64(len(flags))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ClearRatingFlags 判定为误判，批量恢复待复核的可疑评分并重新计入评分汇总
+func (s *ratingFraudService) ClearRatingFlags(ctx context.Context, operator string, req *v1.ReviewRatingFlagsRequest) (*v1.ReviewRatingFlagsResponse, error) {
+	resp := new(v1.ReviewRatingFlagsResponse)
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		flags, err := s.ratingFlagRepo.GetByIds(ctx, req.Ids, model.RatingFlagStatusPending)
+		if err != nil {
+			return err
+		}
+
+		books := make(map[uint]bool)
+		for _, flag := range flags {
+			if err := s.ratingFlagRepo.UpdateStatus(ctx, flag.Id, model.RatingFlagStatusCleared, operator); err != nil {
+				return err
+			}
+			rating, err := s.bookRatingRepo.GetByID(ctx, flag.RatingId)
+			if err != nil {
+				// 评分已被评分者删除，只修改复核状态
+				if errors.Is(err, v1.ErrNotFound) {
+					continue
+				}
+				return err
+			}
+			if !rating.Flagged {
+				continue
+			}
+			if err := s.bookRatingRepo.SetFlagged(ctx, rating.Id, false); err != nil {
+				return err
+			}
+			rating.Flagged = false
+			if !rating.Counted() {
+				continue
+			}
+			if err := s.bookRatingStatRepo.Incr(ctx, rating.BookId, rating.RatingTypeId, 1); err != nil {
+				return err
+			}
+			books[rating.BookId] = true
+		}
+
+		for bookId := range books {
+			if err := s.bookRatingService.RefreshBookScore(ctx, bookId); err != nil {
+				return err
+			}
+		}
+		resp.Updated = int64(len(flags))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// flagRatings 在一个事务内标记同一本书的可疑评分，从评分汇总中扣除并重算该书的贝叶斯评分
+func (s *ratingFraudService) flagRatings(ctx context.Context, bookId uint, ratings []*model.BookRating, reasons map[uint][]string) (int, error) {
+	flagged := 0
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		flagged = 0
+		for _, rating := range ratings {
+			created, err := s.ratingFlagRepo.Create(ctx, &model.RatingFlag{
+				RatingId: rating.Id,
+				BookId:   bookId,
+				Reasons:  strings.Join(reasons[rating.Id], ","),
+				Status:   model.RatingFlagStatusPending,
+			})
+			if err != nil {
+				return err
+			}
+			if !created {
+				continue
+			}
+			if err := s.bookRatingRepo.SetFlagged(ctx, rating.Id, true); err != nil {
+				return err
+			}
+			// 待审核和被拒绝的评分本就不计入评分汇总
+			if rating.Counted() {
+				if err := s.bookRatingStatRepo.Incr(ctx, bookId, rating.RatingTypeId, -1); err != nil {
+					return err
+				}
+			}
+			flagged++
+		}
+		if flagged == 0 {
+			return nil
+		}
+		return s.bookRatingService.RefreshBookScore(ctx, bookId)
+	})
+	return flagged, err
+}
+
+// detectBook 对同一本书按创建时间升序的评分逐条规则检测，返回每条可疑评分命中的规则
+func (s *ratingFraudService) detectBook(ratings []*model.BookRating) map[uint][]string {
+	reasons := make(map[uint][]string)
+	mark := func(cluster []*model.BookRating, reason string) {
+		for _, rating := range cluster {
+			if n := len(reasons[rating.Id]); n > 0 && reasons[rating.Id][n-1] == reason {
+				continue
+			}
+			reasons[rating.Id] = append(reasons[rating.Id], reason)
+		}
+	}
+
+	// 同一网段、相同评论内容
+	subnets := make(map[string][]*model.BookRating)
+	comments := make(map[string][]*model.BookRating)
+	var newVisitors []*model.BookRating
+	for _, rating := range ratings {
+		if subnet := subnetKey(rating.IP); subnet != "" {
+			subnets[subnet] = append(subnets[subnet], rating)
+		}
+		if comment := normalizeComment(rating.Comment); len([]rune(comment)) >= minDuplicateCommentLen {
+			comments[comment] = append(comments[comment], rating)
+		}
+		if rating.UserId == "" && rating.VisitorSince != nil && rating.CreatedAt.Sub(*rating.VisitorSince) <= s.newVisitorAge {
+			newVisitors = append(newVisitors, rating)
+		}
+	}
+	if s.subnetThreshold > 0 {
+		for _, cluster := range subnets {
+			if len(cluster) >= s.subnetThreshold {
+				mark(cluster, model.RatingFlagReasonSubnet)
+			}
+		}
+	}
+	if s.duplicateCommentThreshold > 0 {
+		for _, cluster := range comments {
+			if len(cluster) >= s.duplicateCommentThreshold {
+				mark(cluster, model.RatingFlagReasonDuplicateComment)
+			}
+		}
+	}
+
+	// 滑动窗口内的集中评分，相邻窗口重叠的评分只记一次
+	if s.burstThreshold > 0 && s.burstWindow > 0 {
+		start := 0
+		for end := range ratings {
+			for ratings[end].CreatedAt.Sub(ratings[start].CreatedAt) > s.burstWindow {
+				start++
+			}
+			if end-start+1 >= s.burstThreshold {
+				mark(ratings[start:end+1], model.RatingFlagReasonBurst)
+			}
+		}
+	}
+
+	// 单个新访客评分很正常，大量新访客集中评分才可疑
+	if s.newVisitorThreshold > 0 && len(newVisitors) >= s.newVisitorThreshold {
+		mark(newVisitors, model.RatingFlagReasonNewVisitor)
+	}
+	return reasons
+}
+
+// subnetKey 返回IP所在网段，IPv4 取 /24，IPv6 取 /64，无法解析时返回空
+func subnetKey(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
+
+// normalizeComment 规范化评论用于比较，忽略大小写、空白和标点
+func normalizeComment(comment string) string {
+	var b strings.Builder
+	for _, r := range comment {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
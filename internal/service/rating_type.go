@@ -2,56 +2,150 @@ package service
 
 import (
 	"context"
+	"errors"
 	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/internal/repository"
+
+	"go.uber.org/zap"
 )
 
 type RatingTypeService interface {
-	CreateRatingType(ctx context.Context, req *v1.CreateRatingTypeRequest) error
+	CreateRatingType(ctx context.Context, req *v1.CreateRatingTypeRequest) (*v1.CreateRatingTypeResponse, error)
 	UpdateRatingType(ctx context.Context, id uint, req *v1.UpdateRatingTypeRequest) error
-	DeleteRatingType(ctx context.Context, id uint) error
+	DeleteRatingType(ctx context.Context, id uint, req *v1.DeleteRatingTypeRequest) (*v1.DeleteRatingTypeResponse, error)
 	GetRatingType(ctx context.Context, id uint) (*v1.RatingTypeResponse, error)
 	ListRatingTypes(ctx context.Context, page, pageSize int) (*v1.ListRatingTypesResponse, error)
 }
 
 type ratingTypeService struct {
-	ratingTypeRepo repository.RatingTypeRepository
+	ratingTypeRepo     repository.RatingTypeRepository
+	bookRatingRepo     repository.BookRatingRepository
+	bookRatingStatRepo repository.BookRatingStatRepository
+	bookRatingService  BookRatingService
 	*Service
 }
 
-func NewRatingTypeService(service *Service, ratingTypeRepo repository.RatingTypeRepository) RatingTypeService {
+func NewRatingTypeService(
+	service *Service,
+	ratingTypeRepo repository.RatingTypeRepository,
+	bookRatingRepo repository.BookRatingRepository,
+	bookRatingStatRepo repository.BookRatingStatRepository,
+	bookRatingService BookRatingService,
+) RatingTypeService {
 	return &ratingTypeService{
-		Service:        service,
-		ratingTypeRepo: ratingTypeRepo,
+		Service:            service,
+		ratingTypeRepo:     ratingTypeRepo,
+		bookRatingRepo:     bookRatingRepo,
+		bookRatingStatRepo: bookRatingStatRepo,
+		bookRatingService:  bookRatingService,
 	}
 }
 
-// CreateRatingType 创建评分类型
-func (s *ratingTypeService) CreateRatingType(ctx context.Context, req *v1.CreateRatingTypeRequest) error {
-	return s.ratingTypeRepo.Create(ctx, &model.RatingType{
-		Name:        req.Name,
-		Description: req.Description,
+// CreateRatingType 创建评分类型，等级不能与已有评分类型重复
+func (s *ratingTypeService) CreateRatingType(ctx context.Context, req *v1.CreateRatingTypeRequest) (*v1.CreateRatingTypeResponse, error) {
+	rt := &model.RatingType{
+		Name:         req.Name,
+		Description:  req.Description,
+		Level:        req.Level,
+		DisplayOrder: req.DisplayOrder,
+	}
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.checkLevel(ctx, req.Level, 0); err != nil {
+			return err
+		}
+		return s.ratingTypeRepo.Create(ctx, rt)
 	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CreateRatingTypeResponse{Id: rt.Id}, nil
 }
 
-// UpdateRatingType 更新评分类型
+// UpdateRatingType 更新评分类型，等级变化时重算全部图书的贝叶斯评分
 func (s *ratingTypeService) UpdateRatingType(ctx context.Context, id uint, req *v1.UpdateRatingTypeRequest) error {
-	rt, err := s.ratingTypeRepo.GetByID(ctx, id)
-	if err != nil {
-		return err
-	}
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		rt, err := s.ratingTypeRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		levelChanged := rt.Level != req.Level
+		if levelChanged {
+			if err := s.checkLevel(ctx, req.Level, id); err != nil {
+				return err
+			}
+		}
 
-	rt.Name = req.Name
-	rt.Description = req.Description
-	rt.Level = req.Level
+		rt.Name = req.Name
+		rt.Description = req.Description
+		rt.Level = req.Level
+		rt.DisplayOrder = req.DisplayOrder
+		if err := s.ratingTypeRepo.Update(ctx, rt); err != nil {
+			return err
+		}
 
-	return s.ratingTypeRepo.Update(ctx, rt)
+		if !levelChanged {
+			return nil
+		}
+		return s.bookRatingService.RefreshRatingScores(ctx)
+	})
 }
 
 // DeleteRatingType 删除评分类型
-func (s *ratingTypeService) DeleteRatingType(ctx context.Context, id uint) error {
-	return s.ratingTypeRepo.Delete(ctx, id)
+// 已有评分时必须指定 MigrateTo，评分和评分汇总会在同一事务内迁移到目标评分类型，并重算全部图书的贝叶斯评分
+func (s *ratingTypeService) DeleteRatingType(ctx context.Context, id uint, req *v1.DeleteRatingTypeRequest) (*v1.DeleteRatingTypeResponse, error) {
+	resp := new(v1.DeleteRatingTypeResponse)
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.ratingTypeRepo.GetByID(ctx, id); err != nil {
+			return err
+		}
+		count, err := s.bookRatingRepo.CountByRatingType(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			if req.MigrateTo == 0 {
+				return v1.ErrRatingTypeMigrationRequired
+			}
+			if req.MigrateTo == id {
+				return v1.ErrBadRequest
+			}
+			if _, err := s.ratingTypeRepo.GetByID(ctx, req.MigrateTo); err != nil {
+				if errors.Is(err, v1.ErrNotFound) {
+					return v1.ErrBadRequest
+				}
+				return err
+			}
+
+			resp.Migrated, err = s.bookRatingRepo.MigrateRatingType(ctx, id, req.MigrateTo)
+			if err != nil {
+				return err
+			}
+			if err := s.bookRatingStatRepo.MoveType(ctx, id, req.MigrateTo); err != nil {
+				return err
+			}
+		}
+
+		if err := s.ratingTypeRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		return s.bookRatingService.RefreshRatingScores(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Migrated > 0 {
+		s.logger.WithContext(ctx).Info("rating type deleted",
+			zap.Uint("id", id),
+			zap.Uint("migrate_to", req.MigrateTo),
+			zap.Int64("migrated", resp.Migrated),
+		)
+	}
+	return resp, nil
 }
 
 // GetRatingType 获取评分类型详情
@@ -60,16 +154,10 @@ func (s *ratingTypeService) GetRatingType(ctx context.Context, id uint) (*v1.Rat
 	if err != nil {
 		return nil, err
 	}
-	return &v1.RatingTypeResponse{
-		Id:          ratingType.Id,
-		Name:        ratingType.Name,
-		Description: ratingType.Description,
-		CreatedAt:   ratingType.CreatedAt,
-		UpdatedAt:   ratingType.UpdatedAt,
-	}, nil
+	return toRatingTypeResponse(ratingType), nil
 }
 
-// ListRatingTypes 获取评分类型列表
+// ListRatingTypes 获取评分类型列表，按展示顺序排列
 func (s *ratingTypeService) ListRatingTypes(ctx context.Context, page, pageSize int) (*v1.ListRatingTypesResponse, error) {
 	ratingTypes, total, err := s.ratingTypeRepo.List(ctx, page, pageSize)
 	if err != nil {
@@ -78,13 +166,7 @@ func (s *ratingTypeService) ListRatingTypes(ctx context.Context, page, pageSize
 
 	var items []*v1.RatingTypeResponse
 	for _, rt := range ratingTypes {
-		items = append(items, &v1.RatingTypeResponse{
-			Id:          rt.Id,
-			Name:        rt.Name,
-			Description: rt.Description,
-			CreatedAt:   rt.CreatedAt,
-			UpdatedAt:   rt.UpdatedAt,
-		})
+		items = append(items, toRatingTypeResponse(rt))
 	}
 
 	return &v1.ListRatingTypesResponse{
@@ -92,3 +174,27 @@ func (s *ratingTypeService) ListRatingTypes(ctx context.Context, page, pageSize
 		Items: items,
 	}, nil
 }
+
+// checkLevel 检查等级是否已被其他评分类型使用
+func (s *ratingTypeService) checkLevel(ctx context.Context, level int, excludeId uint) error {
+	exists, err := s.ratingTypeRepo.ExistsLevel(ctx, level, excludeId)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return v1.ErrRatingLevelExists
+	}
+	return nil
+}
+
+func toRatingTypeResponse(rt *model.RatingType) *v1.RatingTypeResponse {
+	return &v1.RatingTypeResponse{
+		Id:           rt.Id,
+		Name:         rt.Name,
+		Description:  rt.Description,
+		Level:        rt.Level,
+		DisplayOrder: rt.DisplayOrder,
+		CreatedAt:    rt.CreatedAt,
+		UpdatedAt:    rt.UpdatedAt,
+	}
+}
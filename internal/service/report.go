@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+type ReportService interface {
+	CreateReport(ctx context.Context, req *v1.CreateReportRequest) (*v1.CreateReportResponse, error)
+	ListReportTargets(ctx context.Context, req *v1.ListReportTargetsRequest) (*v1.ListReportTargetsResponse, error)
+	GetReportTarget(ctx context.Context, targetType string, targetId uint) (*v1.GetReportTargetResponse, error)
+	ResolveReports(ctx context.Context, targetType string, targetId uint, operator string, req *v1.ResolveReportsRequest) (*v1.ResolveReportsResponse, error)
+}
+
+type reportService struct {
+	reportRepo     repository.ReportRepository
+	bookRepo       repository.BookRepository
+	bookRatingRepo repository.BookRatingRepository
+	*Service
+
+	bookRatingService BookRatingService
+
+	autoHideThreshold int64         // 自动隐藏对象所需的举报人数，为 0 时不自动隐藏
+	ipLimit           int64         // 同一IP在 ipLimitWindow 内最多可提交的举报数，为 0 时不限制
+	ipLimitWindow     time.Duration // 统计IP举报数的时间窗口
+}
+
+func NewReportService(
+	service *Service,
+	conf *viper.Viper,
+	reportRepo repository.ReportRepository,
+	bookRepo repository.BookRepository,
+	bookRatingRepo repository.BookRatingRepository,
+	bookRatingService BookRatingService,
+) ReportService {
+	s := &reportService{
+		Service:           service,
+		reportRepo:        reportRepo,
+		bookRepo:          bookRepo,
+		bookRatingRepo:    bookRatingRepo,
+		bookRatingService: bookRatingService,
+		autoHideThreshold: conf.GetInt64("report.auto_hide_threshold"),
+		ipLimit:           conf.GetInt64("report.ip_limit"),
+		ipLimitWindow:     conf.GetDuration("report.ip_limit_window"),
+	}
+	if s.ipLimitWindow <= 0 {
+		s.ipLimitWindow = time.Hour
+	}
+	return s
+}
+
+// CreateReport 创建举报，同一对象待处理的举报人数达到阈值时自动隐藏该对象
+// 同一IP在时间窗口内的举报数超过上限时拒绝举报
+// 章节暂无独立的数据，只记录举报，不自动隐藏
+func (s *reportService) CreateReport(ctx context.Context, req *v1.CreateReportRequest) (*v1.CreateReportResponse, error) {
+	reporter := voterKey(req.UserId, req.VisitorId)
+	if reporter == "" {
+		return nil, v1.ErrBadRequest
+	}
+
+	report := &model.Report{
+		TargetType: req.TargetType,
+		TargetId:   req.TargetId,
+		Reporter:   reporter,
+		Reason:     req.Reason,
+		Detail:     req.Detail,
+		IP:         req.IP,
+		Status:     model.ReportStatusOpen,
+	}
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.checkTargetVisible(ctx, req.TargetType, req.TargetId); err != nil {
+			return err
+		}
+		if req.IP != "" && s.ipLimit > 0 {
+			count, err := s.reportRepo.CountByIPSince(ctx, req.IP, time.Now().Add(-s.ipLimitWindow))
+			if err != nil {
+				return err
+			}
+			if count >= s.ipLimit {
+				return v1.ErrReportLimitExceeded
+			}
+		}
+		if err := s.reportRepo.Create(ctx, report); err != nil {
+			return err
+		}
+
+		if s.autoHideThreshold <= 0 || req.TargetType == model.ReportTargetChapter {
+			return nil
+		}
+		count, err := s.reportRepo.CountOpen(ctx, req.TargetType, req.TargetId)
+		if err != nil {
+			return err
+		}
+		if count < s.autoHideThreshold {
+			return nil
+		}
+		if err := s.setTargetVisible(ctx, req.TargetType, req.TargetId, false); err != nil {
+			return err
+		}
+		s.logger.WithContext(ctx).Info("report target auto hidden",
+			zap.String("target_type", req.TargetType), zap.Uint("target_id", req.TargetId), zap.Int64("reports", count))
+		return s.reportRepo.CreateAudit(ctx, &model.ReportAudit{
+			TargetType: req.TargetType,
+			TargetId:   req.TargetId,
+			Action:     model.ReportActionAutoHide,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CreateReportResponse{Id: report.Id}, nil
+}
+
+// ListReportTargets 按举报对象汇总举报，默认获取待处理的举报
+func (s *reportService) ListReportTargets(ctx context.Context, req *v1.ListReportTargetsRequest) (*v1.ListReportTargetsResponse, error) {
+	if req.TargetType != "" && !isReportTarget(req.TargetType) {
+		return nil, v1.ErrBadRequest
+	}
+	status := req.Status
+	switch status {
+	case "":
+		status = model.ReportStatusOpen
+	case model.ReportStatusOpen, model.ReportStatusResolved, model.ReportStatusDismissed:
+	default:
+		return nil, v1.ErrBadRequest
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	targets, total, err := s.reportRepo.ListTargets(ctx, status, req.TargetType, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*v1.ReportTargetItem, 0, len(targets))
+	for _, target := range targets {
+		items = append(items, &v1.ReportTargetItem{
+			TargetType: target.TargetType,
+			TargetId:   target.TargetId,
+			Reports:    target.Reports,
+			LatestAt:   target.LatestAt,
+		})
+	}
+
+	return &v1.ListReportTargetsResponse{
+		Total: total,
+		Items: items,
+	}, nil
+}
+
+// GetReportTarget 获取某个对象的全部举报和处理记录
+func (s *reportService) GetReportTarget(ctx context.Context, targetType string, targetId uint) (*v1.GetReportTargetResponse, error) {
+	if !isReportTarget(targetType) {
+		return nil, v1.ErrBadRequest
+	}
+	reports, err := s.reportRepo.ListByTarget(ctx, targetType, targetId)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, v1.ErrNotFound
+	}
+	audits, err := s.reportRepo.ListAudits(ctx, targetType, targetId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &v1.GetReportTargetResponse{
+		TargetType: targetType,
+		TargetId:   targetId,
+		Reports:    make([]*v1.ReportItem, 0, len(reports)),
+		Audits:     make([]*v1.ReportAuditItem, 0, len(audits)),
+	}
+	for _, report := range reports {
+		resp.Reports = append(resp.Reports, &v1.ReportItem{
+			Id:        report.Id,
+			Reporter:  report.Reporter,
+			Reason:    report.Reason,
+			Detail:    report.Detail,
+			IP:        report.IP,
+			Status:    report.Status,
+			CreatedAt: report.CreatedAt,
+		})
+	}
+	for _, audit := range audits {
+		resp.Audits = append(resp.Audits, &v1.ReportAuditItem{
+			Id:        audit.Id,
+			Action:    audit.Action,
+			Operator:  audit.Operator,
+			Note:      audit.Note,
+			CreatedAt: audit.CreatedAt,
+		})
+	}
+	return resp, nil
+}
+
+// ResolveReports 处理某个对象的全部待处理举报并记录处理记录
+// remove 举报成立，评论改为审核拒绝、图书保持隐藏；dismiss 驳回举报，恢复被自动隐藏的对象
+func (s *reportService) ResolveReports(ctx context.Context, targetType string, targetId uint, operator string, req *v1.ResolveReportsRequest) (*v1.ResolveReportsResponse, error) {
+	if !isReportTarget(targetType) {
+		return nil, v1.ErrBadRequest
+	}
+	status := model.ReportStatusResolved
+	if req.Action == model.ReportActionDismiss {
+		status = model.ReportStatusDismissed
+	}
+
+	var resolved int64
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		resolved, err = s.reportRepo.CloseByTarget(ctx, targetType, targetId, status)
+		if err != nil {
+			return err
+		}
+		if resolved == 0 {
+			return v1.ErrNotFound
+		}
+
+		if req.Action == model.ReportActionDismiss {
+			err = s.setTargetVisible(ctx, targetType, targetId, true)
+		} else {
+			err = s.removeTarget(ctx, targetType, targetId)
+		}
+		if err != nil {
+			return err
+		}
+		return s.reportRepo.CreateAudit(ctx, &model.ReportAudit{
+			TargetType: targetType,
+			TargetId:   targetId,
+			Action:     req.Action,
+			Operator:   operator,
+			Note:       req.Note,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ResolveReportsResponse{Resolved: resolved}, nil
+}
+
+// checkTargetVisible 只允许举报前台可见的对象
+func (s *reportService) checkTargetVisible(ctx context.Context, targetType string, targetId uint) error {
+	switch targetType {
+	case model.ReportTargetBook:
+		book, err := s.bookRepo.GetByID(ctx, targetId)
+		if err != nil {
+			return err
+		}
+		if book.Hidden {
+			return v1.ErrNotFound
+		}
+	case model.ReportTargetReview:
+		rating, err := s.bookRatingRepo.GetByID(ctx, targetId)
+		if err != nil {
+			return err
+		}
+		if rating.Status != model.RatingStatusApproved || rating.Comment == "" {
+			return v1.ErrNotFound
+		}
+	case model.ReportTargetChapter:
+	default:
+		return v1.ErrBadRequest
+	}
+	return nil
+}
+
+// setTargetVisible 隐藏或恢复举报对象
+// 被隐藏的评论回到待审核状态；恢复时只恢复待审核的评论，已被拒绝的评论保持不变
+func (s *reportService) setTargetVisible(ctx context.Context, targetType string, targetId uint, visible bool) error {
+	switch targetType {
+	case model.ReportTargetBook:
+		return s.bookRepo.SetHidden(ctx, targetId, !visible)
+	case model.ReportTargetReview:
+		if !visible {
+			_, err := s.bookRatingService.SetRatingStatus(ctx, []uint{targetId}, model.RatingStatusPending)
+			return err
+		}
+		rating, err := s.bookRatingRepo.GetByID(ctx, targetId)
+		if err != nil {
+			return err
+		}
+		if rating.Status == model.RatingStatusPending {
+			_, err = s.bookRatingService.SetRatingStatus(ctx, []uint{targetId}, model.RatingStatusApproved)
+		}
+		return err
+	}
+	return nil
+}
+
+// removeTarget 下架举报成立的对象
+func (s *reportService) removeTarget(ctx context.Context, targetType string, targetId uint) error {
+	switch targetType {
+	case model.ReportTargetBook:
+		return s.bookRepo.SetHidden(ctx, targetId, true)
+	case model.ReportTargetReview:
+		_, err := s.bookRatingService.SetRatingStatus(ctx, []uint{targetId}, model.RatingStatusRejected)
+		return err
+	}
+	return nil
+}
+
+func isReportTarget(targetType string) bool {
+	switch targetType {
+	case model.ReportTargetBook, model.ReportTargetReview, model.ReportTargetChapter:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// maxThreadReplies 评论详情中最多返回的回复数
+const maxThreadReplies = 500
+
+type ReviewService interface {
+	CreateReply(ctx context.Context, ratingId uint, req *v1.CreateReviewReplyRequest) (*v1.CreateReviewReplyResponse, error)
+	Like(ctx context.Context, ratingId uint, req *v1.ReviewLikeRequest) (*v1.ReviewLikeResponse, error)
+	Unlike(ctx context.Context, ratingId uint, req *v1.ReviewLikeRequest) (*v1.ReviewLikeResponse, error)
+	GetThread(ctx context.Context, ratingId uint) (*v1.GetReviewThreadResponse, error)
+	ListReplyQueue(ctx context.Context, req *v1.ListReviewQueueRequest) (*v1.ListReplyQueueResponse, error)
+	ModerateReplies(ctx context.Context, req *v1.ModerateReviewsRequest) (*v1.ModerateReviewsResponse, error)
+}
+
+type reviewService struct {
+	bookRatingRepo    repository.BookRatingRepository
+	reviewRepo        repository.ReviewRepository
+	moderationService ModerationService
+	*Service
+
+	likeIPLimit       int64         // 同一IP在 likeIPLimitWindow 内最多可点赞数，为 0 时不限制
+	likeIPLimitWindow time.Duration // 统计IP点赞数的时间窗口
+}
+
+func NewReviewService(
+	service *Service,
+	conf *viper.Viper,
+	bookRatingRepo repository.BookRatingRepository,
+	reviewRepo repository.ReviewRepository,
+	moderationService ModerationService,
+) ReviewService {
+	s := &reviewService{
+		Service:           service,
+		bookRatingRepo:    bookRatingRepo,
+		reviewRepo:        reviewRepo,
+		moderationService: moderationService,
+		likeIPLimit:       conf.GetInt64("review.like_ip_limit"),
+		likeIPLimitWindow: conf.GetDuration("review.like_ip_limit_window"),
+	}
+	if s.likeIPLimitWindow <= 0 {
+		s.likeIPLimitWindow = time.Hour
+	}
+	return s
+}
+
+// CreateReply 回复评论，回复内容同样经过敏感词检查
+// 回复最多两层，回复二级回复时挂到其所属的一级回复下
+func (s *reviewService) CreateReply(ctx context.Context, ratingId uint, req *v1.CreateReviewReplyRequest) (*v1.CreateReviewReplyResponse, error) {
+	comment, status, err := s.moderationService.ScreenComment(ctx, req.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &model.ReviewReply{
+		RatingId:  ratingId,
+		Comment:   comment,
+		IP:        req.IP,
+		UserId:    req.UserId,
+		VisitorId: req.VisitorId,
+		Status:    status,
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.getPublicReview(ctx, ratingId); err != nil {
+			return err
+		}
+
+		if req.ParentId > 0 {
+			parent, err := s.reviewRepo.GetReply(ctx, req.ParentId)
+			if err != nil {
+				return err
+			}
+			if parent.RatingId != ratingId || parent.Status != model.RatingStatusApproved {
+				return v1.ErrBadRequest
+			}
+			reply.ParentId = parent.Id
+			if parent.ParentId > 0 {
+				reply.ParentId = parent.ParentId
+			}
+		}
+
+		if err := s.reviewRepo.CreateReply(ctx, reply); err != nil {
+			return err
+		}
+		if status == model.RatingStatusApproved {
+			return s.bookRatingRepo.IncrCounter(ctx, ratingId, "reply_count", 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CreateReviewReplyResponse{Id: reply.Id, Status: status}, nil
+}
+
+// Like 点赞评论，每个登录用户或匿名访客对同一条评论只计一次点赞
+// 匿名访客清除 Cookie 即可获得新身份，同一IP在时间窗口内的点赞数超过上限时拒绝点赞
+func (s *reviewService) Like(ctx context.Context, ratingId uint, req *v1.ReviewLikeRequest) (*v1.ReviewLikeResponse, error) {
+	return s.toggleLike(ctx, ratingId, req, true)
+}
+
+// Unlike 取消点赞
+func (s *reviewService) Unlike(ctx context.Context, ratingId uint, req *v1.ReviewLikeRequest) (*v1.ReviewLikeResponse, error) {
+	return s.toggleLike(ctx, ratingId, req, false)
+}
+
+func (s *reviewService) toggleLike(ctx context.Context, ratingId uint, req *v1.ReviewLikeRequest, like bool) (*v1.ReviewLikeResponse, error) {
+	voter := voterKey(req.UserId, req.VisitorId)
+	if voter == "" {
+		return nil, v1.ErrBadRequest
+	}
+
+	var resp *v1.ReviewLikeResponse
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.getPublicReview(ctx, ratingId); err != nil {
+			return err
+		}
+
+		var (
+			changed bool
+			err     error
+			delta   int64 = 1
+		)
+		if like {
+			if err := s.checkLikeLimit(ctx, req.IP); err != nil {
+				return err
+			}
+			changed, err = s.reviewRepo.AddLike(ctx, ratingId, voter, req.IP)
+		} else {
+			changed, err = s.reviewRepo.RemoveLike(ctx, ratingId, voter)
+			delta = -1
+		}
+		if err != nil {
+			return err
+		}
+		if changed {
+			if err := s.bookRatingRepo.IncrCounter(ctx, ratingId, "like_count", delta); err != nil {
+				return err
+			}
+		}
+
+		rating, err := s.bookRatingRepo.GetByID(ctx, ratingId)
+		if err != nil {
+			return err
+		}
+		resp = &v1.ReviewLikeResponse{Liked: like, LikeCount: rating.LikeCount}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// checkLikeLimit 检查IP在时间窗口内的点赞数是否已达上限
+func (s *reviewService) checkLikeLimit(ctx context.Context, ip string) error {
+	if ip == "" || s.likeIPLimit <= 0 {
+		return nil
+	}
+	count, err := s.reviewRepo.CountLikesByIPSince(ctx, ip, time.Now().Add(-s.likeIPLimitWindow))
+	if err != nil {
+		return err
+	}
+	if count >= s.likeIPLimit {
+		return v1.ErrLikeLimitExceeded
+	}
+	return nil
+}
+
+// GetThread 获取评论及其审核通过的回复
+func (s *reviewService) GetThread(ctx context.Context, ratingId uint) (*v1.GetReviewThreadResponse, error) {
+	rating, err := s.getPublicReview(ctx, ratingId)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := s.reviewRepo.ListReplies(ctx, ratingId, maxThreadReplies)
+	if err != nil {
+		return nil, err
+	}
+
+	// 一级回复按时间排列，二级回复挂在所属一级回复下；所属一级回复不可见时二级回复也不展示
+	roots := make([]*v1.ReviewReplyItem, 0)
+	byId := make(map[uint]*v1.ReviewReplyItem)
+	for _, reply := range replies {
+		item := &v1.ReviewReplyItem{
+			Id:        reply.Id,
+			ParentId:  reply.ParentId,
+			Comment:   reply.Comment,
+			IP:        maskIP(reply.IP),
+			CreatedAt: reply.CreatedAt,
+		}
+		if reply.ParentId == 0 {
+			roots = append(roots, item)
+			byId[reply.Id] = item
+			continue
+		}
+		if parent, ok := byId[reply.ParentId]; ok {
+			parent.Replies = append(parent.Replies, item)
+		}
+	}
+
+	return &v1.GetReviewThreadResponse{
+		Review: &v1.BookReviewItem{
+			Id:           rating.Id,
+			BookId:       rating.BookId,
+			RatingTypeId: rating.RatingTypeId,
+			Comment:      rating.Comment,
+			IP:           maskIP(rating.IP),
+			LikeCount:    rating.LikeCount,
+			ReplyCount:   rating.ReplyCount,
+			CreatedAt:    rating.CreatedAt,
+		},
+		Replies: roots,
+	}, nil
+}
+
+// ListReplyQueue 按审核状态获取回复，默认获取待审核队列
+func (s *reviewService) ListReplyQueue(ctx context.Context, req *v1.ListReviewQueueRequest) (*v1.ListReplyQueueResponse, error) {
+	status, page, pageSize, ok := parseQueueRequest(req)
+	if !ok {
+		return nil, v1.ErrBadRequest
+	}
+
+	replies, total, err := s.reviewRepo.ListRepliesByStatus(ctx, status, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*v1.ReviewReplyResponse, 0, len(replies))
+	for _, reply := range replies {
+		items = append(items, &v1.ReviewReplyResponse{
+			Id:        reply.Id,
+			RatingId:  reply.RatingId,
+			ParentId:  reply.ParentId,
+			Comment:   reply.Comment,
+			IP:        reply.IP,
+			Status:    reply.Status,
+			CreatedAt: reply.CreatedAt,
+		})
+	}
+
+	return &v1.ListReplyQueueResponse{
+		Total: total,
+		Items: items,
+	}, nil
+}
+
+// ModerateReplies 批量通过或拒绝回复，同时调整评论上的回复数
+func (s *reviewService) ModerateReplies(ctx context.Context, req *v1.ModerateReviewsRequest) (*v1.ModerateReviewsResponse, error) {
+	var updated int64
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		replies, err := s.reviewRepo.GetRepliesByIds(ctx, req.Ids)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]uint, 0, len(replies))
+		deltas := make(map[uint]int64)
+		for _, reply := range replies {
+			if reply.Status == req.Status {
+				continue
+			}
+			ids = append(ids, reply.Id)
+			if reply.Status == model.RatingStatusApproved {
+				deltas[reply.RatingId]--
+			}
+			if req.Status == model.RatingStatusApproved {
+				deltas[reply.RatingId]++
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := s.reviewRepo.UpdateReplyStatus(ctx, ids, req.Status); err != nil {
+			return err
+		}
+		for ratingId, delta := range deltas {
+			if err := s.bookRatingRepo.IncrCounter(ctx, ratingId, "reply_count", delta); err != nil {
+				return err
+			}
+		}
+		updated = int64(len(ids))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ModerateReviewsResponse{Updated: updated}, nil
+}
+
+// getPublicReview 获取公开展示的评论，未审核通过的评论视为不存在
+func (s *reviewService) getPublicReview(ctx context.Context, ratingId uint) (*model.BookRating, error) {
+	rating, err := s.bookRatingRepo.GetByID(ctx, ratingId)
+	if err != nil {
+		return nil, err
+	}
+	if rating.Status != model.RatingStatusApproved || rating.Comment == "" {
+		return nil, v1.ErrNotFound
+	}
+	return rating, nil
+}
+
+// voterKey 点赞者标识，登录用户优先使用用户ID
+func voterKey(userId, visitorId string) string {
+	switch {
+	case userId != "":
+		return "u:" + userId
+	case visitorId != "":
+		return "v:" + visitorId
+	}
+	return ""
+}
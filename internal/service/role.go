@@ -1,29 +1,238 @@
 package service
 
 import (
-    "context"
+	"context"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/internal/repository"
+
+	"gorm.io/gorm"
 )
 
 type RoleService interface {
-	GetRole(ctx context.Context, id int64) (*model.Role, error)
+	GetRole(ctx context.Context, id uint) (*v1.RoleResponse, error)
+	ListRoles(ctx context.Context, page, pageSize int) (*v1.ListRolesResponse, error)
+	CreateRole(ctx context.Context, operatorId string, req *v1.CreateRoleRequest) (*v1.CreateRoleResponse, error)
+	UpdateRole(ctx context.Context, id uint, req *v1.UpdateRoleRequest) error
+	DeleteRole(ctx context.Context, id uint) error
 }
+
 func NewRoleService(
-    service *Service,
-    roleRepository repository.RoleRepository,
+	service *Service,
+	roleRepository repository.RoleRepository,
+	permissionRepository repository.PermissionRepository,
+	rolePermissionRepository repository.RolePermissionRepository,
+	userRoleRepository repository.UserRoleRepository,
+	permissionService PermissionService,
 ) RoleService {
 	return &roleService{
-		Service:        service,
-		roleRepository: roleRepository,
+		Service:                  service,
+		roleRepository:           roleRepository,
+		permissionRepository:     permissionRepository,
+		rolePermissionRepository: rolePermissionRepository,
+		userRoleRepository:       userRoleRepository,
+		permissionService:        permissionService,
 	}
 }
 
 type roleService struct {
 	*Service
-	roleRepository repository.RoleRepository
+	roleRepository           repository.RoleRepository
+	permissionRepository     repository.PermissionRepository
+	rolePermissionRepository repository.RolePermissionRepository
+	userRoleRepository       repository.UserRoleRepository
+	permissionService        PermissionService
+}
+
+// GetRole 获取角色详情，包括角色拥有的权限
+func (s *roleService) GetRole(ctx context.Context, id uint) (*v1.RoleResponse, error) {
+	role, err := s.roleRepository.GetRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.userRoleRepository.CountByRoles(ctx, []uint{id})
+	if err != nil {
+		return nil, err
+	}
+	permissionIds, err := s.rolePermissionRepository.ListPermissionIds(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := toRoleResponse(role, counts[id])
+	resp.PermissionIds = permissionIds
+	return resp, nil
+}
+
+func (s *roleService) ListRoles(ctx context.Context, page, pageSize int) (*v1.ListRolesResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	roles, total, err := s.roleRepository.List(ctx, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	counts := map[uint]int64{}
+	if len(ids) > 0 {
+		if counts, err = s.userRoleRepository.CountByRoles(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]*v1.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		items = append(items, toRoleResponse(role, counts[role.Id]))
+	}
+	return &v1.ListRolesResponse{
+		Total: total,
+		Items: items,
+	}, nil
 }
 
-func (s *roleService) GetRole(ctx context.Context, id int64) (*model.Role, error) {
-	return s.roleRepository.GetRole(ctx, id)
+// CreateRole 创建角色并授予权限，编码与已删除的角色相同时恢复该角色
+// 与 GrantPermissions 相同，非管理员只能授予自己拥有的权限
+func (s *roleService) CreateRole(ctx context.Context, operatorId string, req *v1.CreateRoleRequest) (*v1.CreateRoleResponse, error) {
+	permissionIds := uniqueIds(req.PermissionIds)
+	var role *model.Role
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		existing, err := s.roleRepository.GetUnscopedByCode(ctx, req.Code)
+		if err != nil {
+			return err
+		}
+		var excludeId uint
+		if existing != nil {
+			if !existing.DeletedAt.Valid {
+				return v1.ErrRoleExists
+			}
+			excludeId = existing.Id
+		}
+		exists, err := s.roleRepository.ExistsName(ctx, req.Name, excludeId)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return v1.ErrRoleExists
+		}
+		if err := checkPermissionIds(ctx, s.permissionRepository, permissionIds); err != nil {
+			return err
+		}
+		if err := s.permissionService.CheckGrant(ctx, operatorId, nil, permissionIds); err != nil {
+			return err
+		}
+
+		if existing != nil {
+			role = existing
+			role.Name = req.Name
+			role.Description = req.Description
+			role.DeletedAt = gorm.DeletedAt{}
+			err = s.roleRepository.Save(ctx, role)
+		} else {
+			role = &model.Role{
+				Name:        req.Name,
+				Code:        req.Code,
+				Description: req.Description,
+			}
+			err = s.roleRepository.Create(ctx, role)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(permissionIds) > 0 {
+			_, err = s.rolePermissionRepository.Grant(ctx, role.Id, permissionIds)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 恢复的角色可能仍有用户
+	s.permissionService.InvalidateCache()
+	return &v1.CreateRoleResponse{Id: role.Id}, nil
+}
+
+func (s *roleService) UpdateRole(ctx context.Context, id uint, req *v1.UpdateRoleRequest) error {
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		role, err := s.roleRepository.GetRole(ctx, id)
+		if err != nil {
+			return err
+		}
+		exists, err := s.roleRepository.ExistsName(ctx, req.Name, id)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return v1.ErrRoleExists
+		}
+
+		role.Name = req.Name
+		role.Description = req.Description
+		return s.roleRepository.Save(ctx, role)
+	})
+}
+
+// DeleteRole 删除角色及其权限，仍有用户的角色和管理员角色不能删除
+func (s *roleService) DeleteRole(ctx context.Context, id uint) error {
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		role, err := s.roleRepository.GetRole(ctx, id)
+		if err != nil {
+			return err
+		}
+		if role.Code == model.RoleAdmin {
+			return v1.ErrBuiltinRole
+		}
+		counts, err := s.userRoleRepository.CountByRoles(ctx, []uint{id})
+		if err != nil {
+			return err
+		}
+		if counts[id] > 0 {
+			return v1.ErrRoleInUse
+		}
+
+		if err := s.rolePermissionRepository.DeleteByRole(ctx, id); err != nil {
+			return err
+		}
+		return s.roleRepository.Delete(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.permissionService.InvalidateCache()
+	return nil
+}
+
+func toRoleResponse(role *model.Role, userCount int64) *v1.RoleResponse {
+	return &v1.RoleResponse{
+		Id:          role.Id,
+		Name:        role.Name,
+		Code:        role.Code,
+		Description: role.Description,
+		UserCount:   userCount,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+// uniqueIds 去掉重复的ID，保持原有顺序
+func uniqueIds(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
 }
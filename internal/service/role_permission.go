@@ -1,29 +1,175 @@
 package service
 
 import (
-    "context"
+	"context"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/internal/repository"
 )
 
 type RolePermissionService interface {
-	GetRolePermission(ctx context.Context, id int64) (*model.RolePermission, error)
+	GrantPermissions(ctx context.Context, operatorId string, roleId uint, req *v1.RolePermissionsRequest) (*v1.BatchUpdateResponse, error)
+	RevokePermissions(ctx context.Context, operatorId string, roleId uint, req *v1.RolePermissionsRequest) (*v1.BatchUpdateResponse, error)
+	SetPermissions(ctx context.Context, operatorId string, roleId uint, req *v1.SetRolePermissionsRequest) (*v1.BatchUpdateResponse, error)
 }
+
 func NewRolePermissionService(
-    service *Service,
-    rolePermissionRepository repository.RolePermissionRepository,
+	service *Service,
+	roleRepository repository.RoleRepository,
+	permissionRepository repository.PermissionRepository,
+	rolePermissionRepository repository.RolePermissionRepository,
+	permissionService PermissionService,
 ) RolePermissionService {
 	return &rolePermissionService{
-		Service:        service,
+		Service:                  service,
+		roleRepository:           roleRepository,
+		permissionRepository:     permissionRepository,
 		rolePermissionRepository: rolePermissionRepository,
+		permissionService:        permissionService,
 	}
 }
 
 type rolePermissionService struct {
 	*Service
+	roleRepository           repository.RoleRepository
+	permissionRepository     repository.PermissionRepository
 	rolePermissionRepository repository.RolePermissionRepository
+	permissionService        PermissionService
+}
+
+// GrantPermissions 给角色批量授予权限，已有的权限跳过
+// 只有管理员可以修改管理员角色，其他人只能授予自己拥有的权限
+func (s *rolePermissionService) GrantPermissions(ctx context.Context, operatorId string, roleId uint, req *v1.RolePermissionsRequest) (*v1.BatchUpdateResponse, error) {
+	permissionIds := uniqueIds(req.PermissionIds)
+	var affected int64
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.checkRole(ctx, operatorId, roleId); err != nil {
+			return err
+		}
+		if err := checkPermissionIds(ctx, s.permissionRepository, permissionIds); err != nil {
+			return err
+		}
+		if err := s.permissionService.CheckGrant(ctx, operatorId, nil, permissionIds); err != nil {
+			return err
+		}
+		var err error
+		affected, err = s.rolePermissionRepository.Grant(ctx, roleId, permissionIds)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.permissionService.InvalidateCache()
+	return &v1.BatchUpdateResponse{Affected: affected}, nil
+}
+
+// RevokePermissions 批量收回角色的权限，角色没有的权限跳过，只有管理员可以修改管理员角色
+func (s *rolePermissionService) RevokePermissions(ctx context.Context, operatorId string, roleId uint, req *v1.RolePermissionsRequest) (*v1.BatchUpdateResponse, error) {
+	if err := s.checkRole(ctx, operatorId, roleId); err != nil {
+		return nil, err
+	}
+	affected, err := s.rolePermissionRepository.Revoke(ctx, roleId, uniqueIds(req.PermissionIds))
+	if err != nil {
+		return nil, err
+	}
+
+	s.permissionService.InvalidateCache()
+	return &v1.BatchUpdateResponse{Affected: affected}, nil
+}
+
+// SetPermissions 用给定的权限替换角色现有的权限
+// 只有管理员可以修改管理员角色，其他人新增的权限必须是自己拥有的
+func (s *rolePermissionService) SetPermissions(ctx context.Context, operatorId string, roleId uint, req *v1.SetRolePermissionsRequest) (*v1.BatchUpdateResponse, error) {
+	permissionIds := uniqueIds(req.PermissionIds)
+	var affected int64
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.checkRole(ctx, operatorId, roleId); err != nil {
+			return err
+		}
+		if err := checkPermissionIds(ctx, s.permissionRepository, permissionIds); err != nil {
+			return err
+		}
+
+		existing, err := s.rolePermissionRepository.ListPermissionIds(ctx, roleId)
+		if err != nil {
+			return err
+		}
+		keep := make(map[uint]bool, len(permissionIds))
+		for _, id := range permissionIds {
+			keep[id] = true
+		}
+		had := make(map[uint]bool, len(existing))
+		var revoke []uint
+		for _, id := range existing {
+			had[id] = true
+			if !keep[id] {
+				revoke = append(revoke, id)
+			}
+		}
+		var added []uint
+		for _, id := range permissionIds {
+			if !had[id] {
+				added = append(added, id)
+			}
+		}
+		if err := s.permissionService.CheckGrant(ctx, operatorId, nil, added); err != nil {
+			return err
+		}
+		if len(revoke) > 0 {
+			revoked, err := s.rolePermissionRepository.Revoke(ctx, roleId, revoke)
+			if err != nil {
+				return err
+			}
+			affected += revoked
+		}
+		if len(permissionIds) > 0 {
+			granted, err := s.rolePermissionRepository.Grant(ctx, roleId, permissionIds)
+			if err != nil {
+				return err
+			}
+			affected += granted
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.permissionService.InvalidateCache()
+	return &v1.BatchUpdateResponse{Affected: affected}, nil
+}
+
+// checkRole 检查角色存在，且只有管理员可以修改管理员角色的权限
+func (s *rolePermissionService) checkRole(ctx context.Context, operatorId string, roleId uint) error {
+	role, err := s.roleRepository.GetRole(ctx, roleId)
+	if err != nil {
+		return err
+	}
+	if role.Code != model.RoleAdmin {
+		return nil
+	}
+	perms, err := s.permissionService.GetUserPermissions(ctx, operatorId)
+	if err != nil {
+		return err
+	}
+	if !perms.IsAdmin() {
+		return v1.ErrAdminRoleRequired
+	}
+	return nil
 }
 
-func (s *rolePermissionService) GetRolePermission(ctx context.Context, id int64) (*model.RolePermission, error) {
-	return s.rolePermissionRepository.GetRolePermission(ctx, id)
+// checkPermissionIds 检查权限是否都存在，ids 需已去重
+func checkPermissionIds(ctx context.Context, permissionRepository repository.PermissionRepository, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	permissions, err := permissionRepository.ListByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(permissions) != len(ids) {
+		return v1.ErrBadRequest
+	}
+	return nil
 }
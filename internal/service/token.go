@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"novel-site-backend/pkg/jwt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+type TokenService interface {
+	IssueTokens(ctx context.Context, userId string) (*v1.LoginResponseData, error)
+	Refresh(ctx context.Context, req *v1.RefreshTokenRequest) (*v1.LoginResponseData, error)
+	Logout(ctx context.Context, claims *jwt.MyCustomClaims, req *v1.LogoutRequest) error
+	RevokeUserSessions(ctx context.Context, userId string) error
+	VerifySession(ctx context.Context, claims *jwt.MyCustomClaims) error
+	CleanupExpired(ctx context.Context) (int64, error)
+	InvalidateUser(userId string)
+}
+
+// revocationCacheEntry 缓存的访问令牌吊销状态，过期后重新从数据库加载
+type revocationCacheEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+// userCacheEntry 缓存的用户状态，封禁和解封时立即失效
+type userCacheEntry struct {
+	user      *model.User
+	expiresAt time.Time
+}
+
+type tokenService struct {
+	tokenRepo repository.TokenRepository
+	userRepo  repository.UserRepository
+	*Service
+
+	accessTTL  time.Duration // 访问令牌有效期
+	refreshTTL time.Duration // 刷新令牌有效期，刷新后新令牌重新计算
+	cacheTTL   time.Duration // 吊销状态和用户状态的缓存时长，其他实例的吊销和封禁最迟在该时长后生效
+
+	cacheMu     sync.RWMutex
+	revocations map[string]*revocationCacheEntry
+	users       map[string]*userCacheEntry
+}
+
+func NewTokenService(
+	service *Service,
+	conf *viper.Viper,
+	tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository,
+) TokenService {
+	s := &tokenService{
+		Service:     service,
+		tokenRepo:   tokenRepo,
+		userRepo:    userRepo,
+		accessTTL:   conf.GetDuration("security.jwt.access_ttl"),
+		refreshTTL:  conf.GetDuration("security.jwt.refresh_ttl"),
+		cacheTTL:    conf.GetDuration("security.jwt.revocation_cache_ttl"),
+		revocations: make(map[string]*revocationCacheEntry),
+		users:       make(map[string]*userCacheEntry),
+	}
+	if s.accessTTL <= 0 {
+		s.accessTTL = 15 * time.Minute
+	}
+	if s.refreshTTL <= 0 {
+		s.refreshTTL = 30 * 24 * time.Hour
+	}
+	if s.cacheTTL <= 0 {
+		s.cacheTTL = 10 * time.Second
+	}
+	return s
+}
+
+// IssueTokens 登录成功后签发访问令牌和刷新令牌，开启新的令牌族，用户被封禁时返回 *UserBannedError
+func (s *tokenService) IssueTokens(ctx context.Context, userId string) (*v1.LoginResponseData, error) {
+	if err := s.checkUser(ctx, userId); err != nil {
+		return nil, err
+	}
+	familyId, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, userId, familyId)
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌和刷新令牌，旧刷新令牌随即作废
+// 已作废的刷新令牌被再次使用说明令牌可能已泄露，吊销整个令牌族，持有者需重新登录
+// 用户已被删除时返回 ErrInvalidRefreshToken，被封禁时返回 *UserBannedError
+func (s *tokenService) Refresh(ctx context.Context, req *v1.RefreshTokenRequest) (*v1.LoginResponseData, error) {
+	token, err := s.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			return nil, v1.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	// 封禁时吊销了刷新令牌，先检查封禁让客户端得知被封禁
+	if err := s.checkUser(ctx, token.UserId); err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			return nil, v1.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil {
+		return nil, v1.ErrInvalidRefreshToken
+	}
+	if token.UsedAt != nil {
+		return nil, s.handleReuse(ctx, token, now)
+	}
+	if !token.ExpiresAt.After(now) {
+		return nil, v1.ErrInvalidRefreshToken
+	}
+
+	var data *v1.LoginResponseData
+	reused := false
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		ok, err := s.tokenRepo.MarkRefreshTokenUsed(ctx, token.Id, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// 并发请求已先一步使用了该令牌
+			reused = true
+			return nil
+		}
+		data, err = s.issue(ctx, token.UserId, token.FamilyId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, s.handleReuse(ctx, token, now)
+	}
+	return data, nil
+}
+
+// Logout 注销当前会话，All 为 true 时注销该用户的全部会话
+func (s *tokenService) Logout(ctx context.Context, claims *jwt.MyCustomClaims, req *v1.LogoutRequest) error {
+	if req.All {
+		return s.RevokeUserSessions(ctx, claims.UserId)
+	}
+
+	now := time.Now()
+	token, err := s.tokenRepo.GetRefreshTokenByAccessJti(ctx, claims.ID)
+	if err != nil {
+		if !errors.Is(err, v1.ErrNotFound) {
+			return err
+		}
+		// 没有对应的刷新令牌时只吊销当前访问令牌
+		if err := s.tokenRepo.RevokeAccessTokens(ctx, []*model.RevokedToken{{
+			Jti:       claims.ID,
+			ExpiresAt: claims.ExpiresAt.Time,
+		}}); err != nil {
+			return err
+		}
+		s.cacheRevocation(claims.ID, true, claims.ExpiresAt.Time)
+		return nil
+	}
+	if token.UserId != claims.UserId {
+		return v1.ErrForbidden
+	}
+	return s.revokeFamilies(ctx, []string{token.FamilyId}, now)
+}
+
+// RevokeUserSessions 吊销用户的全部令牌族，已签发的访问令牌立即失效
+func (s *tokenService) RevokeUserSessions(ctx context.Context, userId string) error {
+	now := time.Now()
+	tokens, err := s.tokenRepo.ListActiveByUser(ctx, userId, now)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{})
+	var familyIds []string
+	for _, token := range tokens {
+		if _, ok := seen[token.FamilyId]; ok {
+			continue
+		}
+		seen[token.FamilyId] = struct{}{}
+		familyIds = append(familyIds, token.FamilyId)
+	}
+	return s.revokeFamilies(ctx, familyIds, now)
+}
+
+// VerifySession 检查访问令牌是否已被吊销，未携带令牌ID的旧令牌一律视为无效
+// 吊销状态和用户状态按 revocation_cache_ttl 缓存，本实例吊销的令牌和封禁的用户立即失效
+// 用户已被删除时返回 ErrUnauthorized，被封禁时返回 *UserBannedError
+func (s *tokenService) VerifySession(ctx context.Context, claims *jwt.MyCustomClaims) error {
+	if claims.ID == "" {
+		return v1.ErrUnauthorized
+	}
+	// 先检查封禁，封禁时吊销的令牌也能让客户端得知被封禁
+	user, err := s.getUser(ctx, claims.UserId)
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			return v1.ErrUnauthorized
+		}
+		return err
+	}
+	if err := checkBanned(user, time.Now()); err != nil {
+		return err
+	}
+	revoked, err := s.isRevoked(ctx, claims.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return v1.ErrUnauthorized
+	}
+	return nil
+}
+
+// InvalidateUser 清除用户状态缓存，封禁和解封后调用使其立即生效
+func (s *tokenService) InvalidateUser(userId string) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	delete(s.users, userId)
+}
+
+// getUser 查询用户，优先使用缓存，封禁状态由调用方按当前时间判断
+func (s *tokenService) getUser(ctx context.Context, userId string) (*model.User, error) {
+	now := time.Now()
+	s.cacheMu.RLock()
+	entry, ok := s.users[userId]
+	s.cacheMu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.user, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	if len(s.users) >= 1024 {
+		for k, v := range s.users {
+			if !now.Before(v.expiresAt) {
+				delete(s.users, k)
+			}
+		}
+	}
+	s.users[userId] = &userCacheEntry{user: user, expiresAt: now.Add(s.cacheTTL)}
+	return user, nil
+}
+
+// isRevoked 查询访问令牌是否已被吊销，优先使用缓存
+func (s *tokenService) isRevoked(ctx context.Context, jti string) (bool, error) {
+	now := time.Now()
+	s.cacheMu.RLock()
+	entry, ok := s.revocations[jti]
+	s.cacheMu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.tokenRepo.IsRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+	s.cacheRevocation(jti, revoked, now.Add(s.cacheTTL))
+	return revoked, nil
+}
+
+// cacheRevocation 缓存访问令牌的吊销状态到 expiresAt
+func (s *tokenService) cacheRevocation(jti string, revoked bool, expiresAt time.Time) {
+	now := time.Now()
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	// 顺带清理过期的缓存，避免已过期的令牌一直占用内存
+	if len(s.revocations) >= 1024 {
+		for k, v := range s.revocations {
+			if !now.Before(v.expiresAt) {
+				delete(s.revocations, k)
+			}
+		}
+	}
+	s.revocations[jti] = &revocationCacheEntry{revoked: revoked, expiresAt: expiresAt}
+}
+
+// CleanupExpired 清理已过期的刷新令牌和吊销记录，返回清理的条数
+func (s *tokenService) CleanupExpired(ctx context.Context) (int64, error) {
+	return s.tokenRepo.DeleteExpired(ctx, time.Now())
+}
+
+func (s *tokenService) issue(ctx context.Context, userId, familyId string) (*v1.LoginResponseData, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	accessExpiresAt := now.Add(s.accessTTL)
+	accessToken, err := s.jwt.GenToken(userId, jti, accessExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	err = s.tokenRepo.CreateRefreshToken(ctx, &model.RefreshToken{
+		UserId:          userId,
+		FamilyId:        familyId,
+		TokenHash:       hashToken(refreshToken),
+		AccessJti:       jti,
+		AccessExpiresAt: accessExpiresAt,
+		ExpiresAt:       now.Add(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.LoginResponseData{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL / time.Second),
+	}, nil
+}
+
+// checkUser 检查用户是否存在且未被封禁
+func (s *tokenService) checkUser(ctx context.Context, userId string) error {
+	user, err := s.userRepo.GetByID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return checkBanned(user, time.Now())
+}
+
+// handleReuse 吊销被重复使用的刷新令牌所在的令牌族
+func (s *tokenService) handleReuse(ctx context.Context, token *model.RefreshToken, now time.Time) error {
+	s.logger.WithContext(ctx).Warn("刷新令牌被重复使用，吊销令牌族",
+		zap.String("用户ID", token.UserId),
+		zap.String("令牌族", token.FamilyId))
+	if err := s.revokeFamilies(ctx, []string{token.FamilyId}, now); err != nil {
+		return err
+	}
+	return v1.ErrInvalidRefreshToken
+}
+
+// revokeFamilies 吊销令牌族中的刷新令牌，并把尚未过期的访问令牌加入吊销列表
+func (s *tokenService) revokeFamilies(ctx context.Context, familyIds []string, now time.Time) error {
+	if len(familyIds) == 0 {
+		return nil
+	}
+	var revoked []*model.RevokedToken
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		revoked = nil
+		for _, familyId := range familyIds {
+			tokens, err := s.tokenRepo.ListActiveByFamily(ctx, familyId, now)
+			if err != nil {
+				return err
+			}
+			for _, token := range tokens {
+				if token.AccessExpiresAt.After(now) {
+					revoked = append(revoked, &model.RevokedToken{
+						Jti:       token.AccessJti,
+						ExpiresAt: token.AccessExpiresAt,
+					})
+				}
+			}
+		}
+		if err := s.tokenRepo.RevokeAccessTokens(ctx, revoked); err != nil {
+			return err
+		}
+		return s.tokenRepo.RevokeFamilies(ctx, familyIds, now)
+	})
+	if err != nil {
+		return err
+	}
+	for _, token := range revoked {
+		s.cacheRevocation(token.Jti, true, token.ExpiresAt)
+	}
+	return nil
+}
+
+// randomToken 生成 n 字节的随机令牌，使用 URL 安全的 base64 编码
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken 刷新令牌只保存 SHA-256，数据库泄露时无法直接使用
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"novel-site-backend/pkg/totp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	recoveryCodeCount = 10 // 每次生成的恢复码数量
+	totpSkew          = 1  // 允许前后各一个时间步的时钟误差
+)
+
+type TwoFactorService interface {
+	IsEnabled(ctx context.Context, userId string) (bool, error)
+	Setup(ctx context.Context, userId string) (*v1.TwoFactorSetupResponse, error)
+	Enable(ctx context.Context, userId string, req *v1.EnableTwoFactorRequest) (*v1.RecoveryCodesResponse, error)
+	Disable(ctx context.Context, userId string, req *v1.DisableTwoFactorRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userId string, req *v1.TwoFactorCodeRequest) (*v1.RecoveryCodesResponse, error)
+	StartLogin(ctx context.Context, user *model.User) (*v1.LoginResponseData, error)
+	SetupLogin(ctx context.Context, req *v1.LoginTwoFactorSetupRequest) (*v1.TwoFactorSetupResponse, error)
+	CompleteLogin(ctx context.Context, req *v1.LoginTwoFactorRequest) (*v1.LoginResponseData, error)
+}
+
+type twoFactorService struct {
+	userRepo      repository.UserRepository
+	tokenRepo     repository.TokenRepository
+	twoFactorRepo repository.TwoFactorRepository
+	tokenService  TokenService
+	loginGuard    LoginGuardService
+	*Service
+
+	aead          cipher.AEAD   // 加密保存 TOTP 密钥
+	issuer        string        // 验证器应用中显示的发行方
+	requiredRoles []string      // 必须启用两步验证的角色编码
+	challengeTTL  time.Duration // 密码校验通过后完成两步验证的时限
+}
+
+func NewTwoFactorService(
+	service *Service,
+	conf *viper.Viper,
+	userRepo repository.UserRepository,
+	tokenRepo repository.TokenRepository,
+	twoFactorRepo repository.TwoFactorRepository,
+	tokenService TokenService,
+	loginGuard LoginGuardService,
+) TwoFactorService {
+	key := conf.GetString("security.totp.key")
+	if key == "" {
+		panic("security.totp.key is required, set it with APP_SECURITY_TOTP_KEY")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &twoFactorService{
+		Service:       service,
+		userRepo:      userRepo,
+		tokenRepo:     tokenRepo,
+		twoFactorRepo: twoFactorRepo,
+		tokenService:  tokenService,
+		loginGuard:    loginGuard,
+		aead:          aead,
+		issuer:        conf.GetString("security.totp.issuer"),
+		requiredRoles: conf.GetStringSlice("security.totp.required_roles"),
+		challengeTTL:  conf.GetDuration("security.totp.challenge_ttl"),
+	}
+	if s.issuer == "" {
+		s.issuer = "novel-site-backend"
+	}
+	if s.challengeTTL <= 0 {
+		s.challengeTTL = 5 * time.Minute
+	}
+	return s
+}
+
+func (s *twoFactorService) IsEnabled(ctx context.Context, userId string) (bool, error) {
+	tf, err := s.twoFactorRepo.GetByUser(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return tf != nil && tf.EnabledAt != nil, nil
+}
+
+// Setup 生成新的 TOTP 密钥，输入验证器生成的验证码确认后才启用；重复调用会替换尚未确认的密钥
+func (s *twoFactorService) Setup(ctx context.Context, userId string) (*v1.TwoFactorSetupResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.setup(ctx, user)
+}
+
+// Enable 校验验证码后启用两步验证，返回恢复码，恢复码只在此时展示一次
+func (s *twoFactorService) Enable(ctx context.Context, userId string, req *v1.EnableTwoFactorRequest) (*v1.RecoveryCodesResponse, error) {
+	codes, err := s.enable(ctx, userId, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable 校验密码和验证码后关闭两步验证，所在角色要求两步验证时不能关闭
+// 密码或验证码错误计入登录失败次数，失败过多时返回 *LoginLockedError
+func (s *twoFactorService) Disable(ctx context.Context, userId string, req *v1.DisableTwoFactorRequest) error {
+	user, err := s.userRepo.GetByID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := s.loginGuard.Check(ctx, user.Username, user.UserId, req.IP); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return s.recordFailure(ctx, user, req.IP, v1.ErrInvalidCredentials)
+		}
+		return err
+	}
+	required, err := s.required(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return v1.ErrTwoFactorRequired
+	}
+	if err := s.verifyThrottled(ctx, user, req.Code, req.IP); err != nil {
+		return err
+	}
+	return s.twoFactorRepo.Delete(ctx, userId)
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，原有恢复码全部作废
+// 验证码错误计入登录失败次数，失败过多时返回 *LoginLockedError
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userId string, req *v1.TwoFactorCodeRequest) (*v1.RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.Check(ctx, user.Username, user.UserId, req.IP); err != nil {
+		return nil, err
+	}
+	if err := s.verifyThrottled(ctx, user, req.Code, req.IP); err != nil {
+		return nil, err
+	}
+	codes, err := s.newRecoveryCodes(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// StartLogin 密码校验通过后判断是否需要两步验证，需要时签发登录挑战令牌，不需要时返回 nil
+// 所在角色要求两步验证但尚未启用的用户需先通过 SetupLogin 绑定验证器
+func (s *twoFactorService) StartLogin(ctx context.Context, user *model.User) (*v1.LoginResponseData, error) {
+	enabled, err := s.IsEnabled(ctx, user.UserId)
+	if err != nil {
+		return nil, err
+	}
+	setupRequired := false
+	if !enabled {
+		if setupRequired, err = s.required(ctx, user); err != nil {
+			return nil, err
+		}
+		if !setupRequired {
+			return nil, nil
+		}
+	}
+
+	raw, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	err = s.tokenRepo.CreateUserToken(ctx, &model.UserToken{
+		UserId:    user.UserId,
+		Purpose:   model.UserTokenLoginChallenge,
+		TokenHash: hashToken(raw),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.LoginResponseData{
+		TwoFactorRequired:      true,
+		TwoFactorSetupRequired: setupRequired,
+		ChallengeToken:         raw,
+	}, nil
+}
+
+// SetupLogin 登录时为必须启用两步验证的用户生成 TOTP 密钥
+func (s *twoFactorService) SetupLogin(ctx context.Context, req *v1.LoginTwoFactorSetupRequest) (*v1.TwoFactorSetupResponse, error) {
+	_, user, err := s.getChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.setup(ctx, user)
+}
+
+// CompleteLogin 校验两步验证码后签发令牌，验证码错误计入登录失败次数
+// 登录时绑定验证器的用户在此确认密钥并启用两步验证，响应中返回恢复码
+func (s *twoFactorService) CompleteLogin(ctx context.Context, req *v1.LoginTwoFactorRequest) (*v1.LoginResponseData, error) {
+	challenge, user, err := s.getChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.Check(ctx, user.Username, user.UserId, req.IP); err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.IsEnabled(ctx, user.UserId)
+	if err != nil {
+		return nil, err
+	}
+	var recoveryCodes []string
+	if enabled {
+		err = s.verify(ctx, user.UserId, req.Code)
+	} else {
+		recoveryCodes, err = s.enable(ctx, user.UserId, req.Code)
+	}
+	if errors.Is(err, v1.ErrInvalidTwoFactorCode) {
+		return nil, s.recordFailure(ctx, user, req.IP, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.tokenRepo.MarkUserTokenUsed(ctx, challenge.Id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, v1.ErrInvalidLoginChallenge
+	}
+	// 发出挑战后用户可能已被封禁
+	if err := checkBanned(user, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.RecordSuccess(ctx, user.Username, user.UserId, req.IP); err != nil {
+		return nil, err
+	}
+	data, err := s.tokenService.IssueTokens(ctx, user.UserId)
+	if err != nil {
+		return nil, err
+	}
+	data.RecoveryCodes = recoveryCodes
+	return data, nil
+}
+
+func (s *twoFactorService) setup(ctx context.Context, user *model.User) (*v1.TwoFactorSetupResponse, error) {
+	tf, err := s.twoFactorRepo.GetByUser(ctx, user.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.EnabledAt != nil {
+		return nil, v1.ErrTwoFactorAlreadyEnabled
+	}
+	if tf == nil {
+		tf = &model.UserTwoFactor{UserId: user.UserId}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if tf.Secret, err = s.seal(secret); err != nil {
+		return nil, err
+	}
+	tf.LastStep = 0
+	if err := s.twoFactorRepo.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+	return &v1.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+// enable 使用待确认密钥校验验证码，通过后启用两步验证并生成恢复码
+func (s *twoFactorService) enable(ctx context.Context, userId, code string) ([]string, error) {
+	tf, err := s.twoFactorRepo.GetByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, v1.ErrTwoFactorNotSetup
+	}
+	if tf.EnabledAt != nil {
+		return nil, v1.ErrTwoFactorAlreadyEnabled
+	}
+	secret, err := s.open(tf.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, v1.ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		tf.EnabledAt = &now
+		tf.LastStep = step
+		if err := s.twoFactorRepo.Save(ctx, tf); err != nil {
+			return err
+		}
+		codes, err = s.newRecoveryCodes(ctx, userId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyThrottled 校验验证码，验证码错误计入登录失败次数
+func (s *twoFactorService) verifyThrottled(ctx context.Context, user *model.User, code, ip string) error {
+	err := s.verify(ctx, user.UserId, code)
+	if errors.Is(err, v1.ErrInvalidTwoFactorCode) {
+		return s.recordFailure(ctx, user, ip, err)
+	}
+	return err
+}
+
+// recordFailure 记录一次登录失败，本次失败导致账号被锁定时返回 *LoginLockedError，否则返回 cause
+func (s *twoFactorService) recordFailure(ctx context.Context, user *model.User, ip string, cause error) error {
+	lockedUntil, err := s.loginGuard.RecordFailure(ctx, user.Username, user.UserId, ip)
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		return &LoginLockedError{RetryAfter: time.Until(*lockedUntil)}
+	}
+	return cause
+}
+
+// verify 校验已启用的两步验证，code 可以是验证码或恢复码
+func (s *twoFactorService) verify(ctx context.Context, userId, code string) error {
+	tf, err := s.twoFactorRepo.GetByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if tf == nil || tf.EnabledAt == nil {
+		return v1.ErrTwoFactorNotSetup
+	}
+	secret, err := s.open(tf.Secret)
+	if err != nil {
+		return err
+	}
+
+	if step, ok := totp.Validate(secret, code, time.Now(), totpSkew); ok {
+		advanced, err := s.twoFactorRepo.AdvanceStep(ctx, tf.Id, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return v1.ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := s.twoFactorRepo.UseRecoveryCode(ctx, userId, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return v1.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// required 用户所在角色是否要求启用两步验证
+func (s *twoFactorService) required(ctx context.Context, user *model.User) (bool, error) {
+	if len(s.requiredRoles) == 0 {
+		return false, nil
+	}
+	codes, err := s.userRepo.GetRoleCodes(ctx, user.Id)
+	if err != nil {
+		return false, err
+	}
+	for _, code := range codes {
+		for _, role := range s.requiredRoles {
+			if code == role {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// getChallenge 校验登录挑战令牌，返回令牌及其所属用户
+func (s *twoFactorService) getChallenge(ctx context.Context, raw string) (*model.UserToken, *model.User, error) {
+	challenge, err := s.tokenRepo.GetUserTokenByHash(ctx, model.UserTokenLoginChallenge, hashToken(raw))
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			return nil, nil, v1.ErrInvalidLoginChallenge
+		}
+		return nil, nil, err
+	}
+	if challenge.UsedAt != nil || !challenge.ExpiresAt.After(time.Now()) {
+		return nil, nil, v1.ErrInvalidLoginChallenge
+	}
+	user, err := s.userRepo.GetByID(ctx, challenge.UserId)
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			return nil, nil, v1.ErrInvalidLoginChallenge
+		}
+		return nil, nil, err
+	}
+	return challenge, user, nil
+}
+
+// newRecoveryCodes 生成新的恢复码并替换原有恢复码，返回明文
+func (s *twoFactorService) newRecoveryCodes(ctx context.Context, userId string) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := fmt.Sprintf("%x", b)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 去掉恢复码中的分隔符和空白并转为小写
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+// seal 加密 TOTP 密钥，密文格式为 base64(nonce || ciphertext)
+func (s *twoFactorService) seal(secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (s *twoFactorService) open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < s.aead.NonceSize() {
+		return "", errors.New("totp secret is corrupted")
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
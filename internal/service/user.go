@@ -2,45 +2,114 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
 	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/internal/repository"
+	"novel-site-backend/pkg/mailer"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService interface {
 	Register(ctx context.Context, req *v1.RegisterRequest) error
-	Login(ctx context.Context, req *v1.LoginRequest) (string, error)
+	Login(ctx context.Context, req *v1.LoginRequest) (*v1.LoginResponseData, error)
 	GetProfile(ctx context.Context, userId string) (*v1.GetProfileResponseData, error)
 	UpdateProfile(ctx context.Context, userId string, req *v1.UpdateProfileRequest) error
+	SendVerificationEmail(ctx context.Context, userId string) error
+	VerifyEmail(ctx context.Context, req *v1.VerifyEmailRequest) error
+	ForgotPassword(ctx context.Context, req *v1.ForgotPasswordRequest)
+	ResetPassword(ctx context.Context, req *v1.ResetPasswordRequest) error
 }
 
 func NewUserService(
 	service *Service,
+	conf *viper.Viper,
 	userRepo repository.UserRepository,
+	tokenRepo repository.TokenRepository,
+	tokenService TokenService,
+	loginGuard LoginGuardService,
+	twoFactor TwoFactorService,
+	mailer mailer.Mailer,
+	mailQueue MailQueue,
 ) UserService {
-	return &userService{
-		userRepo: userRepo,
-		Service:  service,
+	templates, err := newMailTemplates(conf)
+	if err != nil {
+		panic(err)
+	}
+	s := &userService{
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		tokenService:   tokenService,
+		loginGuard:     loginGuard,
+		twoFactor:      twoFactor,
+		mailer:         mailer,
+		mailQueue:      mailQueue,
+		templates:      templates,
+		Service:        service,
+		verifyURL:      conf.GetString("mail.verify_url"),
+		resetURL:       conf.GetString("mail.reset_url"),
+		verifyTTL:      conf.GetDuration("mail.verify_ttl"),
+		resetTTL:       conf.GetDuration("mail.reset_ttl"),
+		resendInterval: conf.GetDuration("mail.resend_interval"),
+	}
+	if s.verifyTTL <= 0 {
+		s.verifyTTL = 24 * time.Hour
 	}
+	if s.resetTTL <= 0 {
+		s.resetTTL = 30 * time.Minute
+	}
+	return s
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo     repository.UserRepository
+	tokenRepo    repository.TokenRepository
+	tokenService TokenService
+	loginGuard   LoginGuardService
+	twoFactor    TwoFactorService
+	mailer       mailer.Mailer
+	mailQueue    MailQueue
+	templates    *mailTemplates
 	*Service
+
+	verifyURL      string        // 邮箱验证页面地址，令牌以 token 参数附加
+	resetURL       string        // 重置密码页面地址
+	verifyTTL      time.Duration // 邮箱验证链接有效期
+	resetTTL       time.Duration // 重置密码链接有效期
+	resendInterval time.Duration // 同一用户同类邮件的最短发送间隔
+}
+
+// usernamePattern 用户名只能包含字母、数字和下划线，不含 @ 以便登录时区分用户名和邮箱
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash 用户不存在时用于比对的哈希，使登录耗时与用户存在时一致，避免通过耗时探测用户名
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("novel-site-backend"), bcrypt.DefaultCost)
+	})
+	return dummyHash
 }
 
+// Register 注册用户，用户名(不区分大小写)和邮箱不能与已有用户重复，邮箱统一转为小写保存
 func (s *userService) Register(ctx context.Context, req *v1.RegisterRequest) error {
-	// check username
-	user, err := s.userRepo.GetByEmail(ctx, req.Email)
-	if err != nil {
-		return v1.ErrInternalServerError
-	}
-	if err == nil && user != nil {
-		return v1.ErrEmailAlreadyUse
+	if !usernamePattern.MatchString(req.Username) {
+		return v1.ErrInvalidUsername
 	}
+	email := normalizeEmail(req.Email)
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -51,39 +120,129 @@ func (s *userService) Register(ctx context.Context, req *v1.RegisterRequest) err
 	if err != nil {
 		return err
 	}
-	user = &model.User{
+	user := &model.User{
 		UserId:   userId,
-		Email:    req.Email,
+		Username: req.Username,
+		Nickname: strings.TrimSpace(req.Nickname),
+		Email:    email,
 		Password: string(hashedPassword),
 	}
-	// Transaction demo
 	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
-		// Create a user
-		if err = s.userRepo.Create(ctx, user); err != nil {
+		existing, err := s.userRepo.GetByUsername(ctx, req.Username)
+		if err != nil {
 			return err
 		}
-		// TODO: other repo
-		return nil
+		if existing != nil {
+			return v1.ErrUsernameAlreadyUse
+		}
+		if existing, err = s.userRepo.GetByEmail(ctx, email); err != nil {
+			return err
+		}
+		if existing != nil {
+			return v1.ErrEmailAlreadyUse
+		}
+		return s.userRepo.Create(ctx, user)
 	})
-	return err
-}
+	if err != nil {
+		return err
+	}
 
-func (s *userService) Login(ctx context.Context, req *v1.LoginRequest) (string, error) {
-	user, err := s.userRepo.GetByEmail(ctx, req.Email)
-	if err != nil || user == nil {
-		return "", v1.ErrUnauthorized
+	// 验证邮件发送失败不影响注册，用户可稍后重新发送
+	if err := s.sendUserMail(ctx, user, model.UserTokenVerifyEmail); err != nil {
+		s.logger.WithContext(ctx).Error("发送验证邮件失败", zap.String("用户ID", user.UserId), zap.Error(err))
 	}
+	return nil
+}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+// Login 使用用户名或邮箱登录，用户不存在和密码错误统一返回 ErrInvalidCredentials
+// 连续失败过多时账号或 IP 被暂时限制登录，返回 *LoginLockedError，账号被锁定时邮件通知用户
+// 被封禁的用户密码校验通过后返回 *UserBannedError，不泄露账号是否存在
+// 启用了两步验证或所在角色要求两步验证的用户，密码校验通过后只返回挑战令牌，不签发访问令牌
+func (s *userService) Login(ctx context.Context, req *v1.LoginRequest) (*v1.LoginResponseData, error) {
+	var (
+		user    *model.User
+		userId  string
+		err     error
+		account = req.Account
+	)
+	if strings.Contains(account, "@") {
+		account = normalizeEmail(account)
+		user, err = s.userRepo.GetByEmail(ctx, account)
+	} else {
+		account = strings.ToLower(account)
+		user, err = s.userRepo.GetByUsername(ctx, account)
+	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if user != nil {
+		userId = user.UserId
+	}
+	if err := s.loginGuard.Check(ctx, account, userId, req.IP); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		lockedUntil, err := s.loginGuard.RecordFailure(ctx, account, "", req.IP)
+		if err != nil {
+			return nil, err
+		}
+		// 不存在的账号同样返回锁定，避免通过是否锁定探测账号是否存在
+		if lockedUntil != nil {
+			return nil, &LoginLockedError{RetryAfter: time.Until(*lockedUntil)}
+		}
+		return nil, v1.ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return nil, err
+		}
+		lockedUntil, err := s.loginGuard.RecordFailure(ctx, account, userId, req.IP)
+		if err != nil {
+			return nil, err
+		}
+		if lockedUntil == nil {
+			return nil, v1.ErrInvalidCredentials
+		}
+		s.notifyLocked(ctx, user, *lockedUntil, req.IP)
+		return nil, &LoginLockedError{RetryAfter: time.Until(*lockedUntil)}
 	}
-	token, err := s.jwt.GenToken(user.UserId, time.Now().Add(time.Hour*24*1))
+	if err := checkBanned(user, time.Now()); err != nil {
+		return nil, err
+	}
+	challenge, err := s.twoFactor.StartLogin(ctx, user)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	if challenge != nil {
+		return challenge, nil
+	}
+	if err := s.loginGuard.RecordSuccess(ctx, account, userId, req.IP); err != nil {
+		return nil, err
+	}
+	return s.tokenService.IssueTokens(ctx, user.UserId)
+}
 
-	return token, nil
+// notifyLocked 通过后台邮件队列通知用户账号已被锁定，不等待发送结果，避免响应耗时暴露账号是否存在，失败只记录日志
+func (s *userService) notifyLocked(ctx context.Context, user *model.User, lockedUntil time.Time, ip string) {
+	logger := s.logger.WithContext(ctx)
+	msg, err := s.templates.render(mailAccountLocked, user.Email, map[string]interface{}{
+		"Nickname":    user.Nickname,
+		"LockedUntil": lockedUntil.Format("2006-01-02 15:04:05"),
+		"IP":          ip,
+	})
+	if err != nil {
+		logger.Error("发送账号锁定通知失败", zap.String("用户ID", user.UserId), zap.Error(err))
+		return
+	}
+	userId := user.UserId
+	s.mailQueue.Enqueue(func(ctx context.Context) {
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			logger.Error("发送账号锁定通知失败", zap.String("用户ID", userId), zap.Error(err))
+		}
+	})
 }
 
 func (s *userService) GetProfile(ctx context.Context, userId string) (*v1.GetProfileResponseData, error) {
@@ -91,25 +250,247 @@ func (s *userService) GetProfile(ctx context.Context, userId string) (*v1.GetPro
 	if err != nil {
 		return nil, err
 	}
+	twoFactorEnabled, err := s.twoFactor.IsEnabled(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
 
 	return &v1.GetProfileResponseData{
-		UserId:   user.UserId,
-		Nickname: user.Nickname,
+		UserId:           user.UserId,
+		Username:         user.Username,
+		Nickname:         user.Nickname,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		TwoFactorEnabled: twoFactorEnabled,
+		Avatar:           user.Avatar,
+		Intro:            user.Intro,
+		CreatedAt:        user.CreatedAt,
 	}, nil
 }
 
+// UpdateProfile 更新个人资料，修改邮箱时检查是否已被其他用户使用，新邮箱需要重新验证
 func (s *userService) UpdateProfile(ctx context.Context, userId string, req *v1.UpdateProfileRequest) error {
+	var (
+		user         *model.User
+		emailChanged bool
+	)
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.userRepo.GetByID(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		email := normalizeEmail(req.Email)
+		if email != user.Email {
+			emailChanged = true
+			user.EmailVerifiedAt = nil
+			existing, err := s.userRepo.GetByEmail(ctx, email)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return v1.ErrEmailAlreadyUse
+			}
+		}
+
+		user.Email = email
+		user.Nickname = strings.TrimSpace(req.Nickname)
+		user.Avatar = req.Avatar
+		user.Intro = req.Intro
+		return s.userRepo.Update(ctx, user)
+	})
+	if err != nil || !emailChanged {
+		return err
+	}
+
+	if err := s.sendUserMail(ctx, user, model.UserTokenVerifyEmail); err != nil && !errors.Is(err, v1.ErrMailTooFrequent) {
+		s.logger.WithContext(ctx).Error("发送验证邮件失败", zap.String("用户ID", user.UserId), zap.Error(err))
+	}
+	return nil
+}
+
+// SendVerificationEmail 重新发送邮箱验证邮件
+func (s *userService) SendVerificationEmail(ctx context.Context, userId string) error {
 	user, err := s.userRepo.GetByID(ctx, userId)
 	if err != nil {
 		return err
 	}
+	if user.EmailVerifiedAt != nil {
+		return v1.ErrEmailAlreadyVerified
+	}
+	return s.sendUserMail(ctx, user, model.UserTokenVerifyEmail)
+}
+
+// VerifyEmail 使用邮件中的令牌验证邮箱，发送邮件后邮箱被修改过的令牌无效
+func (s *userService) VerifyEmail(ctx context.Context, req *v1.VerifyEmailRequest) error {
+	now := time.Now()
+	token, user, err := s.getUserToken(ctx, model.UserTokenVerifyEmail, req.Token, now)
+	if err != nil {
+		return err
+	}
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		ok, err := s.tokenRepo.MarkUserTokenUsed(ctx, token.Id, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return v1.ErrInvalidUserToken
+		}
+		user.EmailVerifiedAt = &now
+		return s.userRepo.Update(ctx, user)
+	})
+}
 
-	user.Email = req.Email
-	user.Nickname = req.Nickname
+// ForgotPassword 通过后台邮件队列向邮箱发送重置密码邮件，队列已满时丢弃，失败只记录日志
+// 不等待查询和发送结果，邮箱是否已注册的响应和耗时一致，避免通过该接口探测已注册的邮箱
+func (s *userService) ForgotPassword(ctx context.Context, req *v1.ForgotPasswordRequest) {
+	email := normalizeEmail(req.Email)
+	logger := s.logger.WithContext(ctx)
+	s.mailQueue.Enqueue(func(ctx context.Context) {
+		user, err := s.userRepo.GetByEmail(ctx, email)
+		if err == nil && user != nil {
+			err = s.sendUserMail(ctx, user, model.UserTokenResetPassword)
+		}
+		if err != nil && !errors.Is(err, v1.ErrMailTooFrequent) {
+			logger.Error("发送重置密码邮件失败", zap.String("邮箱", email), zap.Error(err))
+		}
+	})
+}
 
-	if err = s.userRepo.Update(ctx, user); err != nil {
+// ResetPassword 使用邮件中的令牌设置新密码，成功后作废其他重置链接、解除登录锁定并注销全部会话
+func (s *userService) ResetPassword(ctx context.Context, req *v1.ResetPasswordRequest) error {
+	now := time.Now()
+	token, user, err := s.getUserToken(ctx, model.UserTokenResetPassword, req.Token, now)
+	if err != nil {
 		return err
 	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		ok, err := s.tokenRepo.MarkUserTokenUsed(ctx, token.Id, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return v1.ErrInvalidUserToken
+		}
+		if err := s.tokenRepo.InvalidateUserTokens(ctx, user.UserId, model.UserTokenResetPassword, now); err != nil {
+			return err
+		}
+		user.Password = string(hashedPassword)
+		return s.userRepo.Update(ctx, user)
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.loginGuard.ResetAccount(ctx, user.UserId); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeUserSessions(ctx, user.UserId)
+}
 
-	return nil
+// getUserToken 校验一次性令牌，返回令牌及其所属用户；令牌不存在、已使用、已过期或邮箱已变更时返回 ErrInvalidUserToken
+func (s *userService) getUserToken(ctx context.Context, purpose, raw string, now time.Time) (*model.UserToken, *model.User, error) {
+	token, err := s.tokenRepo.GetUserTokenByHash(ctx, purpose, hashToken(raw))
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			return nil, nil, v1.ErrInvalidUserToken
+		}
+		return nil, nil, err
+	}
+	if token.UsedAt != nil || !token.ExpiresAt.After(now) {
+		return nil, nil, v1.ErrInvalidUserToken
+	}
+	user, err := s.userRepo.GetByID(ctx, token.UserId)
+	if err != nil {
+		if errors.Is(err, v1.ErrNotFound) {
+			return nil, nil, v1.ErrInvalidUserToken
+		}
+		return nil, nil, err
+	}
+	if user.Email != token.Email {
+		return nil, nil, v1.ErrInvalidUserToken
+	}
+	return token, user, nil
+}
+
+// sendUserMail 签发一次性令牌并发送对应的邮件，同一用途之前签发的令牌随即作废
+// 距上次发送不足 resendInterval 时返回 ErrMailTooFrequent
+func (s *userService) sendUserMail(ctx context.Context, user *model.User, purpose string) error {
+	var (
+		name, link string
+		ttl        time.Duration
+	)
+	switch purpose {
+	case model.UserTokenVerifyEmail:
+		name, link, ttl = mailVerifyEmail, s.verifyURL, s.verifyTTL
+	case model.UserTokenResetPassword:
+		name, link, ttl = mailResetPassword, s.resetURL, s.resetTTL
+	}
+
+	now := time.Now()
+	latest, err := s.tokenRepo.GetLatestUserToken(ctx, user.UserId, purpose)
+	if err != nil {
+		return err
+	}
+	if latest != nil && now.Sub(latest.CreatedAt) < s.resendInterval {
+		return v1.ErrMailTooFrequent
+	}
+
+	raw, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.tokenRepo.InvalidateUserTokens(ctx, user.UserId, purpose, now); err != nil {
+			return err
+		}
+		return s.tokenRepo.CreateUserToken(ctx, &model.UserToken{
+			UserId:    user.UserId,
+			Purpose:   purpose,
+			TokenHash: hashToken(raw),
+			Email:     user.Email,
+			ExpiresAt: now.Add(ttl),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.templates.render(name, user.Email, map[string]interface{}{
+		"Nickname":  user.Nickname,
+		"Link":      withToken(link, raw),
+		"ExpiresIn": formatTTL(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// withToken 把令牌附加到链接的 token 参数
+func withToken(link, token string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// formatTTL 把有效期格式化为邮件中展示的文字
+func formatTTL(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d 小时", d/time.Hour)
+	}
+	return fmt.Sprintf("%d 分钟", (d+time.Minute-1)/time.Minute)
 }
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	v1 "novel-site-backend/api/v1"
+	"novel-site-backend/internal/model"
+	"novel-site-backend/internal/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// UserBannedError 用户被封禁时返回，errors.Is(err, v1.ErrUserBanned) 成立
+type UserBannedError struct {
+	Reason string     // 封禁原因
+	Until  *time.Time // 封禁截止时间，为空表示永久封禁
+}
+
+func (e *UserBannedError) Error() string {
+	return v1.ErrUserBanned.Error()
+}
+
+func (e *UserBannedError) Unwrap() error {
+	return v1.ErrUserBanned
+}
+
+// checkBanned 用户处于封禁中时返回 *UserBannedError
+func checkBanned(user *model.User, now time.Time) error {
+	if !user.IsBanned(now) {
+		return nil
+	}
+	return &UserBannedError{Reason: user.BanReason, Until: user.BannedUntil}
+}
+
+// UserBanService 封禁和解封用户，封禁后用户无法登录，已签发的令牌立即失效
+// 封禁到期后用户即可正常使用，定时任务再把用户状态恢复为正常并记录解封
+type UserBanService interface {
+	BanUser(ctx context.Context, operatorId, userId string, req *v1.BanUserRequest) error
+	UnbanUser(ctx context.Context, operatorId, userId string, req *v1.UnbanUserRequest) error
+	ListUserBans(ctx context.Context, req *v1.ListUserBansRequest) (*v1.ListUserBansResponse, error)
+	LiftExpiredBans(ctx context.Context) (int, error)
+}
+
+type userBanService struct {
+	userRepo          repository.UserRepository
+	userBanRepo       repository.UserBanRepository
+	tokenService      TokenService
+	permissionService PermissionService
+	*Service
+}
+
+func NewUserBanService(
+	service *Service,
+	userRepo repository.UserRepository,
+	userBanRepo repository.UserBanRepository,
+	tokenService TokenService,
+	permissionService PermissionService,
+) UserBanService {
+	return &userBanService{
+		Service:           service,
+		userRepo:          userRepo,
+		userBanRepo:       userBanRepo,
+		tokenService:      tokenService,
+		permissionService: permissionService,
+	}
+}
+
+// BanUser 封禁用户并吊销其全部会话，已封禁的用户按新的原因和截止时间重新封禁
+// 不能封禁自己和管理员；拥有封禁用户或角色管理权限的用户只有管理员可以封禁，否则返回 ErrCannotBanUser
+func (s *userBanService) BanUser(ctx context.Context, operatorId, userId string, req *v1.BanUserRequest) error {
+	if userId == operatorId {
+		return v1.ErrCannotBanUser
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return v1.ErrBadRequest
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := s.checkBannable(ctx, operatorId, userId); err != nil {
+		return err
+	}
+
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userBanRepo.SetBanStatus(ctx, user.Id, model.UserStatusDisabled, req.Reason, req.ExpiresAt); err != nil {
+			return err
+		}
+		return s.userBanRepo.Create(ctx, &model.UserBan{
+			UserId:     userId,
+			Action:     model.UserBanActionBan,
+			Reason:     req.Reason,
+			ExpiresAt:  req.ExpiresAt,
+			OperatorId: operatorId,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.tokenService.InvalidateUser(userId)
+
+	s.logger.WithContext(ctx).Info("用户被封禁",
+		zap.String("用户ID", userId),
+		zap.String("操作人", operatorId),
+		zap.String("原因", req.Reason))
+	// 会话校验会拒绝被封禁的用户，吊销会话是为了解封后旧令牌也不能再使用
+	return s.tokenService.RevokeUserSessions(ctx, userId)
+}
+
+// UnbanUser 解除用户的封禁，用户未被封禁时返回 ErrUserNotBanned
+func (s *userBanService) UnbanUser(ctx context.Context, operatorId, userId string, req *v1.UnbanUserRequest) error {
+	user, err := s.userRepo.GetByID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user.Status != model.UserStatusDisabled {
+		return v1.ErrUserNotBanned
+	}
+
+	err = s.tm.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userBanRepo.SetBanStatus(ctx, user.Id, model.UserStatusNormal, "", nil); err != nil {
+			return err
+		}
+		return s.userBanRepo.Create(ctx, &model.UserBan{
+			UserId:     userId,
+			Action:     model.UserBanActionUnban,
+			Reason:     req.Reason,
+			OperatorId: operatorId,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.tokenService.InvalidateUser(userId)
+
+	s.logger.WithContext(ctx).Info("用户被解封",
+		zap.String("用户ID", userId),
+		zap.String("操作人", operatorId))
+	return nil
+}
+
+// checkBannable 管理员不能被封禁，拥有封禁用户或角色管理权限的用户只有管理员可以封禁
+func (s *userBanService) checkBannable(ctx context.Context, operatorId, userId string) error {
+	target, err := s.permissionService.GetUserPermissions(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if target.IsAdmin() {
+		return v1.ErrCannotBanUser
+	}
+	if !target.Has(model.PermUserBan) && !target.Has(model.PermRoleManage) {
+		return nil
+	}
+	operator, err := s.permissionService.GetUserPermissions(ctx, operatorId)
+	if err != nil {
+		return err
+	}
+	if !operator.IsAdmin() {
+		return v1.ErrCannotBanUser
+	}
+	return nil
+}
+
+func (s *userBanService) ListUserBans(ctx context.Context, req *v1.ListUserBansRequest) (*v1.ListUserBansResponse, error) {
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	bans, total, err := s.userBanRepo.List(ctx, repository.UserBanFilter{
+		UserId:     req.UserId,
+		OperatorId: req.OperatorId,
+		Action:     req.Action,
+	}, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*v1.UserBanItem, 0, len(bans))
+	for _, b := range bans {
+		items = append(items, &v1.UserBanItem{
+			Id:         b.Id,
+			UserId:     b.UserId,
+			Action:     b.Action,
+			Reason:     b.Reason,
+			ExpiresAt:  b.ExpiresAt,
+			OperatorId: b.OperatorId,
+			CreatedAt:  b.CreatedAt,
+		})
+	}
+	return &v1.ListUserBansResponse{Total: total, Items: items}, nil
+}
+
+// LiftExpiredBans 把封禁已到期的用户恢复为正常状态并记录解封，返回解封的用户数
+func (s *userBanService) LiftExpiredBans(ctx context.Context) (int, error) {
+	const batchSize = 100
+	lifted := 0
+	for {
+		users, err := s.userBanRepo.ListExpiredUsers(ctx, time.Now(), batchSize)
+		if err != nil {
+			return lifted, err
+		}
+		batchLifted := 0
+		for _, user := range users {
+			err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+				ok, err := s.userBanRepo.LiftBan(ctx, user)
+				if err != nil || !ok {
+					// 期间被管理员解封或重新封禁
+					return err
+				}
+				batchLifted++
+				return s.userBanRepo.Create(ctx, &model.UserBan{
+					UserId: user.UserId,
+					Action: model.UserBanActionUnban,
+					Reason: "封禁到期",
+				})
+			})
+			if err != nil {
+				return lifted, err
+			}
+			s.tokenService.InvalidateUser(user.UserId)
+		}
+		lifted += batchLifted
+		if len(users) < batchSize || batchLifted == 0 {
+			return lifted, nil
+		}
+	}
+}
@@ -1,29 +1,169 @@
 package service
 
 import (
-    "context"
+	"context"
+	v1 "novel-site-backend/api/v1"
 	"novel-site-backend/internal/model"
 	"novel-site-backend/internal/repository"
 )
 
 type UserRoleService interface {
-	GetUserRole(ctx context.Context, id int64) (*model.UserRole, error)
+	GetUserRoles(ctx context.Context, userId string) (*v1.UserRolesResponse, error)
+	GrantRoles(ctx context.Context, operatorId string, req *v1.UserRolesRequest) (*v1.BatchUpdateResponse, error)
+	RevokeRoles(ctx context.Context, operatorId string, req *v1.UserRolesRequest) (*v1.BatchUpdateResponse, error)
 }
+
 func NewUserRoleService(
-    service *Service,
-    userRoleRepository repository.UserRoleRepository,
+	service *Service,
+	userRepository repository.UserRepository,
+	roleRepository repository.RoleRepository,
+	userRoleRepository repository.UserRoleRepository,
+	permissionService PermissionService,
 ) UserRoleService {
 	return &userRoleService{
-		Service:        service,
+		Service:            service,
+		userRepository:     userRepository,
+		roleRepository:     roleRepository,
 		userRoleRepository: userRoleRepository,
+		permissionService:  permissionService,
 	}
 }
 
 type userRoleService struct {
 	*Service
+	userRepository     repository.UserRepository
+	roleRepository     repository.RoleRepository
 	userRoleRepository repository.UserRoleRepository
+	permissionService  PermissionService
+}
+
+func (s *userRoleService) GetUserRoles(ctx context.Context, userId string) (*v1.UserRolesResponse, error) {
+	user, err := s.userRepository.GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.roleRepository.ListByUser(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*v1.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		items = append(items, toRoleResponse(role, 0))
+	}
+	return &v1.UserRolesResponse{
+		UserId: user.UserId,
+		Roles:  items,
+	}, nil
+}
+
+// GrantRoles 给每个用户授予每个角色，已有的跳过
+// 只有管理员可以授予管理员角色，其他人只能授予权限都是自己拥有的角色
+func (s *userRoleService) GrantRoles(ctx context.Context, operatorId string, req *v1.UserRolesRequest) (*v1.BatchUpdateResponse, error) {
+	var affected int64
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		userIds, roles, err := s.resolve(ctx, req)
+		if err != nil {
+			return err
+		}
+		roleIds := roleIdsOf(roles)
+		if err := s.permissionService.CheckGrant(ctx, operatorId, roleIds, nil); err != nil {
+			return err
+		}
+		affected, err = s.userRoleRepository.Grant(ctx, userIds, roleIds)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.permissionService.InvalidateCache(req.UserIds...)
+	return &v1.BatchUpdateResponse{Affected: affected}, nil
+}
+
+// RevokeRoles 收回每个用户的每个角色，没有的跳过
+// 只有管理员可以收回管理员角色，收回后没有管理员时返回 ErrLastAdmin
+func (s *userRoleService) RevokeRoles(ctx context.Context, operatorId string, req *v1.UserRolesRequest) (*v1.BatchUpdateResponse, error) {
+	var affected int64
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		userIds, roles, err := s.resolve(ctx, req)
+		if err != nil {
+			return err
+		}
+		var adminId uint
+		for _, role := range roles {
+			if role.Code == model.RoleAdmin {
+				adminId = role.Id
+			}
+		}
+		if adminId != 0 {
+			perms, err := s.permissionService.GetUserPermissions(ctx, operatorId)
+			if err != nil {
+				return err
+			}
+			if !perms.IsAdmin() {
+				return v1.ErrAdminRoleRequired
+			}
+		}
+
+		affected, err = s.userRoleRepository.Revoke(ctx, userIds, roleIdsOf(roles))
+		if err != nil || adminId == 0 {
+			return err
+		}
+		counts, err := s.userRoleRepository.CountByRoles(ctx, []uint{adminId})
+		if err != nil {
+			return err
+		}
+		if counts[adminId] == 0 {
+			return v1.ErrLastAdmin
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.permissionService.InvalidateCache(req.UserIds...)
+	return &v1.BatchUpdateResponse{Affected: affected}, nil
 }
 
-func (s *userRoleService) GetUserRole(ctx context.Context, id int64) (*model.UserRole, error) {
-	return s.userRoleRepository.GetUserRole(ctx, id)
+// resolve 把对外的用户ID转换为用户表主键，并检查用户和角色都存在
+func (s *userRoleService) resolve(ctx context.Context, req *v1.UserRolesRequest) ([]uint, []*model.Role, error) {
+	seen := make(map[string]bool, len(req.UserIds))
+	var userIds []string
+	for _, id := range req.UserIds {
+		if !seen[id] {
+			seen[id] = true
+			userIds = append(userIds, id)
+		}
+	}
+	users, err := s.userRepository.ListByUserIds(ctx, userIds)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(users) != len(userIds) {
+		return nil, nil, v1.ErrBadRequest
+	}
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.Id)
+	}
+
+	roleIds := uniqueIds(req.RoleIds)
+	roles, err := s.roleRepository.ListByIds(ctx, roleIds)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(roles) != len(roleIds) {
+		return nil, nil, v1.ErrBadRequest
+	}
+	return ids, roles, nil
+}
+
+func roleIdsOf(roles []*model.Role) []uint {
+	ids := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	return ids
 }
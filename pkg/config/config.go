@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/spf13/viper"
 	"os"
+	"strings"
 )
 
 func NewConfig(p string) *viper.Viper {
@@ -15,8 +16,13 @@ func NewConfig(p string) *viper.Viper {
 	return getConfig(envConf)
 }
 
+// getConfig 读取配置文件，配置项可用 APP_ 开头的环境变量覆盖
+// 例如 security.visitor.key 对应 APP_SECURITY_VISITOR_KEY，密钥类配置不写入配置文件，只通过环境变量提供
 func getConfig(path string) *viper.Viper {
 	conf := viper.New()
+	conf.SetEnvPrefix("APP")
+	conf.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	conf.AutomaticEnv()
 	conf.SetConfigFile(path)
 	err := conf.ReadInConfig()
 	if err != nil {
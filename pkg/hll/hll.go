@@ -0,0 +1,119 @@
+// Package hll 实现 HyperLogLog 基数估计，用于统计独立访客数
+package hll
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+const (
+	precision = 12             // 寄存器下标位数，标准误差约 1.04/sqrt(4096) ≈ 1.6%
+	registers = 1 << precision // 寄存器数量，序列化后占用 4KB
+)
+
+// Sketch HyperLogLog 草图
+type Sketch struct {
+	reg []uint8
+}
+
+// New 创建空草图
+func New() *Sketch {
+	return &Sketch{reg: make([]uint8, registers)}
+}
+
+// FromBytes 从序列化数据恢复草图，数据无效时返回空草图
+func FromBytes(b []byte) *Sketch {
+	s := New()
+	if len(b) == registers {
+		copy(s.reg, b)
+	}
+	return s
+}
+
+// Hash 将任意字符串（如客户端IP）散列为 64 位值
+func Hash(v string) uint64 {
+	sum := sha256.Sum256([]byte(v))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// Add 加入一个 64 位散列值
+func (s *Sketch) Add(hash uint64) {
+	idx := hash >> (64 - precision)
+	w := hash<<precision | 1<<(precision-1)
+	rho := uint8(bits.LeadingZeros64(w) + 1)
+	if rho > s.reg[idx] {
+		s.reg[idx] = rho
+	}
+}
+
+// Merge 合并另一个草图，结果为两者的并集
+func (s *Sketch) Merge(o *Sketch) {
+	for i, v := range o.reg {
+		if v > s.reg[i] {
+			s.reg[i] = v
+		}
+	}
+}
+
+// Estimate 估计基数，使用 Ertl 改进估计算法，在小基数和大基数区间均无需偏差修正表
+// 参见 Otmar Ertl, "New cardinality estimation algorithms for HyperLogLog sketches"
+func (s *Sketch) Estimate() uint64 {
+	const q = 64 - precision
+	m := float64(registers)
+
+	var counts [q + 2]int
+	for _, v := range s.reg {
+		counts[v]++
+	}
+
+	z := m * tau(1-float64(counts[q+1])/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(counts[k]))
+	}
+	z += m * sigma(float64(counts[0])/m)
+
+	return uint64(m*m/(2*math.Ln2*z) + 0.5)
+}
+
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
+
+// Bytes 序列化草图
+func (s *Sketch) Bytes() []byte {
+	b := make([]byte, registers)
+	copy(b, s.reg)
+	return b
+}
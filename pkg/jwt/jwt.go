@@ -9,8 +9,19 @@ import (
 	"github.com/spf13/viper"
 )
 
+var (
+	ErrNoSigningKey = errors.New("no active jwt signing key")
+	ErrUnknownKey   = errors.New("unknown or retired jwt key")
+)
+
+// JWT 使用 security.jwt.keys 中的密钥签发和校验令牌，令牌头部的 kid 标识签名密钥
+// 签名使用 active_from 已到且最晚生效的密钥，按时间自动轮换；retire_at 之前的旧密钥仍用于验签
+// 未配置 keys 时使用 security.jwt.key 以 HS256 签名；配置 keys 后该密钥只用于校验不带 kid 的旧令牌，
+// 且只在最早的签名密钥生效后一个 access_ttl 内有效，届时旧令牌已全部过期
 type JWT struct {
-	key []byte
+	key         []byte
+	keys        []*key
+	legacyUntil time.Time // 配置 keys 后不带 kid 的令牌的截止时间，未配置 keys 时为零值
 }
 
 type MyCustomClaims struct {
@@ -18,11 +29,63 @@ type MyCustomClaims struct {
 	jwt.RegisteredClaims
 }
 
+// NewJwt 加载签名密钥，security.jwt.key 和带私钥的 security.jwt.keys 都未配置时启动失败
 func NewJwt(conf *viper.Viper) *JWT {
-	return &JWT{key: []byte(conf.GetString("security.jwt.key"))}
+	keys, err := loadKeys(conf)
+	if err != nil {
+		panic(err)
+	}
+	j := &JWT{
+		key:  []byte(conf.GetString("security.jwt.key")),
+		keys: keys,
+	}
+	if len(j.key) == 0 && !hasSigningKey(keys) {
+		panic("security.jwt.key or a security.jwt.keys entry with a private key is required, set the key with APP_SECURITY_JWT_KEY")
+	}
+	if len(keys) > 0 {
+		accessTTL := conf.GetDuration("security.jwt.access_ttl")
+		if accessTTL <= 0 {
+			accessTTL = 15 * time.Minute
+		}
+		j.legacyUntil = legacyCutoff(keys, time.Now()).Add(accessTTL)
+	}
+	return j
 }
 
-func (j *JWT) GenToken(userId string, expiresAt time.Time) (string, error) {
+// hasSigningKey 是否有可用于签名的密钥
+func hasSigningKey(keys []*key) bool {
+	for _, k := range keys {
+		if k.signKey != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyCutoff 最早开始签名的密钥的生效时间，之后不再签发不带 kid 的令牌
+// active_from 留空的密钥立即生效，按启动时间计算
+func legacyCutoff(keys []*key, now time.Time) time.Time {
+	var cutoff time.Time
+	for _, k := range keys {
+		if k.signKey == nil {
+			continue
+		}
+		activeFrom := k.activeFrom
+		if activeFrom.IsZero() {
+			activeFrom = now
+		}
+		if cutoff.IsZero() || activeFrom.Before(cutoff) {
+			cutoff = activeFrom
+		}
+	}
+	if cutoff.IsZero() {
+		cutoff = now
+	}
+	return cutoff
+}
+
+// GenToken 签发访问令牌，jti 写入 ID 声明，用于注销后吊销令牌
+func (j *JWT) GenToken(userId, jti string, expiresAt time.Time) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, MyCustomClaims{
 		UserId: userId,
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -31,13 +94,28 @@ func (j *JWT) GenToken(userId string, expiresAt time.Time) (string, error) {
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "",
 			Subject:   "",
-			ID:        "",
+			ID:        jti,
 			Audience:  []string{},
 		},
 	})
 
+	var signKey interface{} = j.key
+	if len(j.keys) == 0 && len(j.key) == 0 {
+		return "", ErrNoSigningKey
+	}
+	if len(j.keys) > 0 {
+		k := j.signingKey(time.Now())
+		if k == nil {
+			return "", ErrNoSigningKey
+		}
+		token.Method = k.method
+		token.Header["alg"] = k.method.Alg()
+		token.Header["kid"] = k.kid
+		signKey = k.signKey
+	}
+
 	// Sign and get the complete encoded token as a string using the key
-	tokenString, err := token.SignedString(j.key)
+	tokenString, err := token.SignedString(signKey)
 	if err != nil {
 		return "", err
 	}
@@ -49,9 +127,7 @@ func (j *JWT) ParseToken(tokenString string) (*MyCustomClaims, error) {
 	if strings.TrimSpace(tokenString) == "" {
 		return nil, errors.New("token is empty")
 	}
-	token, err := jwt.ParseWithClaims(tokenString, &MyCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return j.key, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &MyCustomClaims{}, j.keyFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -61,3 +137,57 @@ func (j *JWT) ParseToken(tokenString string) (*MyCustomClaims, error) {
 		return nil, err
 	}
 }
+
+// JWKS 返回尚未停止验签的公钥，包括还未开始签名的密钥，便于其他服务提前缓存
+func (j *JWT) JWKS() *JWKSet {
+	now := time.Now()
+	set := &JWKSet{Keys: []JWK{}}
+	for _, k := range j.keys {
+		if k.retired(now) {
+			continue
+		}
+		if jwk, ok := k.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// keyFunc 按 kid 选择验签密钥，并要求令牌的算法与密钥一致，防止算法混淆攻击
+func (j *JWT) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	now := time.Now()
+	if kid == "" {
+		if len(j.key) == 0 || token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, ErrUnknownKey
+		}
+		if len(j.keys) > 0 && !now.Before(j.legacyUntil) {
+			return nil, ErrUnknownKey
+		}
+		return j.key, nil
+	}
+	for _, k := range j.keys {
+		if k.kid != kid {
+			continue
+		}
+		if k.retired(now) || token.Method.Alg() != k.method.Alg() {
+			return nil, ErrUnknownKey
+		}
+		return k.verifyKey, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// signingKey 当前用于签名的密钥，同时生效的多个密钥取 active_from 最晚的
+func (j *JWT) signingKey(now time.Time) *key {
+	var current *key
+	for _, k := range j.keys {
+		if !k.canSign(now) {
+			continue
+		}
+		if current == nil || !k.activeFrom.Before(current.activeFrom) {
+			current = k
+		}
+	}
+	return current
+}
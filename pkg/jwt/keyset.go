@@ -0,0 +1,199 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+// KeyConfig 签名密钥配置，对应 security.jwt.keys 中的一项
+// 非对称密钥配置私钥即可签名和验签，只配置公钥时仅用于验签；HS256 使用 secret
+type KeyConfig struct {
+	Kid            string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"algorithm"` // RS256 / EdDSA / HS256
+	PrivateKey     string `mapstructure:"private_key"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKey      string `mapstructure:"public_key"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+	Secret         string `mapstructure:"secret"`
+	ActiveFrom     string `mapstructure:"active_from"` // 开始用于签名的时间(RFC3339)，留空表示立即生效
+	RetireAt       string `mapstructure:"retire_at"`   // 停止验签的时间(RFC3339)，留空表示一直有效
+}
+
+// key 已加载的密钥
+type key struct {
+	kid        string
+	method     jwt.SigningMethod
+	signKey    interface{} // 签名密钥，只有公钥时为 nil
+	verifyKey  interface{}
+	activeFrom time.Time
+	retireAt   time.Time
+}
+
+// JWK 单个公钥，格式见 RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKSet JWKS 接口返回的公钥集合
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func loadKeys(conf *viper.Viper) ([]*key, error) {
+	var configs []KeyConfig
+	if err := conf.UnmarshalKey("security.jwt.keys", &configs); err != nil {
+		return nil, err
+	}
+	keys := make([]*key, 0, len(configs))
+	seen := make(map[string]struct{})
+	for _, c := range configs {
+		if c.Kid == "" {
+			return nil, fmt.Errorf("jwt key: kid is required")
+		}
+		if _, ok := seen[c.Kid]; ok {
+			return nil, fmt.Errorf("jwt key %s: duplicate kid", c.Kid)
+		}
+		seen[c.Kid] = struct{}{}
+		k, err := loadKey(c)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", c.Kid, err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func loadKey(c KeyConfig) (*key, error) {
+	k := &key{kid: c.Kid}
+	var err error
+	if k.activeFrom, err = parseKeyTime(c.ActiveFrom); err != nil {
+		return nil, err
+	}
+	if k.retireAt, err = parseKeyTime(c.RetireAt); err != nil {
+		return nil, err
+	}
+
+	switch c.Algorithm {
+	case "HS256":
+		if c.Secret == "" {
+			return nil, fmt.Errorf("secret is required")
+		}
+		k.method = jwt.SigningMethodHS256
+		k.signKey = []byte(c.Secret)
+		k.verifyKey = k.signKey
+		return k, nil
+	case "RS256":
+		k.method = jwt.SigningMethodRS256
+	case "EdDSA":
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", c.Algorithm)
+	}
+
+	privatePEM, err := readPEM(c.PrivateKey, c.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if privatePEM != nil {
+		var signer crypto.Signer
+		if c.Algorithm == "RS256" {
+			signer, err = jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		} else {
+			var pk crypto.PrivateKey
+			if pk, err = jwt.ParseEdPrivateKeyFromPEM(privatePEM); err == nil {
+				signer = pk.(crypto.Signer)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		k.signKey = signer
+		k.verifyKey = signer.Public()
+		return k, nil
+	}
+
+	publicPEM, err := readPEM(c.PublicKey, c.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if publicPEM == nil {
+		return nil, fmt.Errorf("private_key or public_key is required")
+	}
+	if c.Algorithm == "RS256" {
+		k.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+	} else {
+		k.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(publicPEM)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func readPEM(inline, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+func parseKeyTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// retired 密钥是否已停止验签
+func (k *key) retired(now time.Time) bool {
+	return !k.retireAt.IsZero() && !now.Before(k.retireAt)
+}
+
+// canSign 密钥当前是否可用于签名
+func (k *key) canSign(now time.Time) bool {
+	return k.signKey != nil && !now.Before(k.activeFrom) && !k.retired(now)
+}
+
+// jwk 公钥的 JWK 表示，对称密钥返回 false
+func (k *key) jwk() (JWK, bool) {
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.kid,
+			Use: "sig",
+			Alg: k.method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.kid,
+			Use: "sig",
+			Alg: k.method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	}
+	return JWK{}, false
+}
@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"novel-site-backend/pkg/log"
+)
+
+type fileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer 把邮件保存为 dir 下的 .eml 文件，用于开发环境查看邮件内容
+func NewFileMailer(dir, from string) Mailer {
+	if dir == "" {
+		dir = "storage/mails"
+	}
+	return &fileMailer{dir: dir, from: from}
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.build(m.from)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), sanitize(msg.To[0]))
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o644)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+type logMailer struct {
+	logger *log.Logger
+	from   string
+}
+
+// NewLogMailer 只把邮件内容写入日志，不实际发送
+func NewLogMailer(logger *log.Logger, from string) Mailer {
+	return &logMailer{logger: logger, from: from}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg *Message) error {
+	if _, err := msg.build(m.from); err != nil {
+		return err
+	}
+	body := msg.Text
+	if body == "" {
+		body = msg.HTML
+	}
+	m.logger.WithContext(ctx).Info("mail",
+		zap.Strings("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", body))
+	return nil
+}
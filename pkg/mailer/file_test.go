@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailerSend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mails")
+	m := NewFileMailer(dir, "noreply@example.com")
+
+	err := m.Send(context.Background(), &Message{
+		To:      []string{"alice@example.com"},
+		Subject: "验证邮箱",
+		Text:    "验证码 123456",
+		HTML:    "<p>验证码 <b>123456</b></p>",
+	})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), "-alice@example.com.eml"))
+
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "noreply@example.com", msg.Header.Get("From"))
+	assert.Equal(t, "alice@example.com", msg.Header.Get("To"))
+
+	// 同时有纯文本和 HTML 时以 multipart/alternative 保存，各部分的内容按 quoted-printable 解码
+	contentType := msg.Header.Get("Content-Type")
+	require.True(t, strings.HasPrefix(contentType, "multipart/alternative; boundary="))
+	reader := multipart.NewReader(msg.Body, strings.TrimPrefix(contentType, "multipart/alternative; boundary="))
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts = append(parts, part.Header.Get("Content-Type")+"|"+string(body))
+	}
+	assert.Equal(t, []string{
+		"text/plain; charset=utf-8|验证码 123456",
+		"text/html; charset=utf-8|<p>验证码 <b>123456</b></p>",
+	}, parts)
+}
+
+func TestFileMailerSendWithoutRecipients(t *testing.T) {
+	dir := t.TempDir()
+	m := NewFileMailer(dir, "noreply@example.com")
+
+	err := m.Send(context.Background(), &Message{Subject: "s", Text: "t"})
+	assert.Error(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,134 @@
+// Package mailer 发送邮件，支持 SMTP 以及开发环境使用的文件、日志实现
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"novel-site-backend/pkg/log"
+)
+
+// Message 待发送的邮件，Text 和 HTML 至少填写一项，同时填写时以 multipart/alternative 发送
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewMailer 按 mail.driver 创建邮件发送实现：smtp / file / log，默认 log
+func NewMailer(conf *viper.Viper, logger *log.Logger) Mailer {
+	from := conf.GetString("mail.from")
+	switch conf.GetString("mail.driver") {
+	case "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			Host:     conf.GetString("mail.smtp.host"),
+			Port:     conf.GetInt("mail.smtp.port"),
+			Username: conf.GetString("mail.smtp.username"),
+			Password: conf.GetString("mail.smtp.password"),
+			TLS:      conf.GetString("mail.smtp.tls"),
+			Timeout:  conf.GetDuration("mail.smtp.timeout"),
+		}, from)
+	case "file":
+		return NewFileMailer(conf.GetString("mail.file.dir"), from)
+	default:
+		return NewLogMailer(logger, from)
+	}
+}
+
+// build 生成完整的 RFC 5322 邮件内容
+func (m *Message) build(from string) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, fmt.Errorf("mailer: no recipients")
+	}
+	if m.Text == "" && m.HTML == "" {
+		return nil, fmt.Errorf("mailer: empty body")
+	}
+
+	var buf bytes.Buffer
+	header := func(k, v string) {
+		buf.WriteString(k + ": " + v + "\r\n")
+	}
+	header("From", from)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageId(from))
+	header("MIME-Version", "1.0")
+
+	if m.Text == "" || m.HTML == "" {
+		body, contentType := m.Text, "text/plain"
+		if body == "" {
+			body, contentType = m.HTML, "text/html"
+		}
+		header("Content-Type", contentType+"; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	header("Content-Type", "multipart/alternative; boundary="+w.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", m.Text},
+		{"text/html", m.HTML},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, s string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// messageId 生成 Message-ID，域名取发件人地址的域名
+func messageId(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
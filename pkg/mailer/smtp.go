@@ -0,0 +1,124 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig SMTP 服务器配置
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // 为空时不认证，适用于本地测试用的 SMTP 服务
+	Password string
+	TLS      string // none：明文；starttls：连接后升级；tls：直接使用 TLS 连接(通常为 465 端口)
+	Timeout  time.Duration
+}
+
+type smtpMailer struct {
+	conf SMTPConfig
+	from string
+}
+
+func NewSMTPMailer(conf SMTPConfig, from string) Mailer {
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+	return &smtpMailer{conf: conf, from: from}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) error {
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid from address: %w", err)
+	}
+	data, err := msg.build(m.from)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.conf.Timeout)
+	defer cancel()
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	// 超时或取消时关闭连接，中断阻塞中的读写
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-stop:
+		}
+	}()
+
+	if m.conf.TLS == "starttls" {
+		if err := client.StartTLS(&tls.Config{ServerName: m.conf.Host}); err != nil {
+			return err
+		}
+	}
+	if m.conf.Username != "" {
+		auth := smtp.PlainAuth("", m.conf.Username, m.conf.Password, m.conf.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(sender.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("mailer: invalid recipient %q: %w", to, err)
+		}
+		if err := client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *smtpMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.conf.Host, strconv.Itoa(m.conf.Port))
+	dialer := &net.Dialer{}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if m.conf.TLS == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: m.conf.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, m.conf.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
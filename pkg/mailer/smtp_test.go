@@ -0,0 +1,151 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpSession 测试用 SMTP 服务收到的一封邮件
+type smtpSession struct {
+	from string
+	to   []string
+	data []byte
+}
+
+// startSMTPStub 启动只接收一封邮件的 SMTP 服务，不支持认证和 STARTTLS
+func startSMTPStub(t *testing.T) (string, int, <-chan *smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	sessions := make(chan *smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		tc := textproto.NewConn(conn)
+		session := new(smtpSession)
+		_ = tc.PrintfLine("220 localhost ESMTP stub")
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				_ = tc.PrintfLine("250-localhost")
+				_ = tc.PrintfLine("250 8BITMIME")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				session.from = envelopeAddress(line[len("MAIL FROM:"):])
+				_ = tc.PrintfLine("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				session.to = append(session.to, envelopeAddress(line[len("RCPT TO:"):]))
+				_ = tc.PrintfLine("250 OK")
+			case cmd == "DATA":
+				_ = tc.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				session.data, err = tc.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				_ = tc.PrintfLine("250 OK")
+			case cmd == "QUIT":
+				_ = tc.PrintfLine("221 Bye")
+				sessions <- session
+				return
+			default:
+				_ = tc.PrintfLine("502 Command not implemented")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return host, p, sessions
+}
+
+// envelopeAddress 取出 MAIL FROM / RCPT TO 参数中尖括号内的地址，忽略 BODY=8BITMIME 等扩展参数
+func envelopeAddress(arg string) string {
+	arg = strings.TrimPrefix(strings.TrimSpace(arg), "<")
+	if i := strings.Index(arg, ">"); i >= 0 {
+		arg = arg[:i]
+	}
+	return arg
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	host, port, sessions := startSMTPStub(t)
+	m := NewSMTPMailer(SMTPConfig{Host: host, Port: port, TLS: "none", Timeout: 5 * time.Second}, "书城 <noreply@example.com>")
+
+	text := "您好，请点击下面的链接重置密码：" + strings.Repeat("https://example.com/reset?token=abc", 3)
+	err := m.Send(context.Background(), &Message{
+		To:      []string{"Alice <alice@example.com>", "bob@example.com"},
+		Subject: "重置密码",
+		Text:    text,
+	})
+	require.NoError(t, err)
+
+	var session *smtpSession
+	select {
+	case session = <-sessions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp stub did not receive the mail")
+	}
+
+	// 信封只包含地址，不包含显示名
+	assert.Equal(t, "noreply@example.com", session.from)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, session.to)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(session.data))
+	require.NoError(t, err)
+	assert.Equal(t, "书城 <noreply@example.com>", msg.Header.Get("From"))
+	assert.Equal(t, "Alice <alice@example.com>, bob@example.com", msg.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "重置密码", subject)
+	assert.Equal(t, "1.0", msg.Header.Get("MIME-Version"))
+	assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+	assert.Equal(t, "quoted-printable", msg.Header.Get("Content-Transfer-Encoding"))
+	assert.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>"))
+	_, err = msg.Header.Date()
+	assert.NoError(t, err)
+
+	// ReadDotBytes 把行尾转为 \n，并在末尾保留 DATA 结束前的换行
+	raw, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	raw = bytes.TrimSuffix(raw, []byte("\n"))
+	for _, line := range strings.Split(string(raw), "\n") {
+		assert.LessOrEqual(t, len(line), 76, "quoted-printable line too long")
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(raw)))
+	require.NoError(t, err)
+	assert.Equal(t, text, string(body))
+}
+
+func TestSMTPMailerSendInvalidRecipient(t *testing.T) {
+	host, port, _ := startSMTPStub(t)
+	m := NewSMTPMailer(SMTPConfig{Host: host, Port: port, TLS: "none", Timeout: 5 * time.Second}, "noreply@example.com")
+
+	err := m.Send(context.Background(), &Message{To: []string{"not an address"}, Subject: "s", Text: "t"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret RFC 6238 附录 B 中 SHA-1 测试用例的密钥 "12345678901234567890"
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// rfcVectors RFC 6238 附录 B 的 SHA-1 测试向量，取 8 位结果的后 6 位
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, v := range rfcVectors {
+		code, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, v.code, code, "unix %d", v.unix)
+	}
+}
+
+func TestCodeAcceptsUnpaddedLowercaseSecret(t *testing.T) {
+	secret := encoding.EncodeToString([]byte("12345678901234567890"))
+	lower := []byte(secret)
+	for i, c := range lower {
+		if c >= 'A' && c <= 'Z' {
+			lower[i] = c + 'a' - 'A'
+		}
+	}
+	code, err := Code(" "+string(lower)+" ", Step(time.Unix(59, 0)))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	_, err := Code("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	for _, v := range rfcVectors {
+		now := time.Unix(v.unix, 0)
+		step, ok := Validate(rfcSecret, v.code, now, 0)
+		assert.True(t, ok, "unix %d", v.unix)
+		assert.Equal(t, Step(now), step)
+	}
+
+	// 允许前后一个时间步的误差，返回实际匹配的时间步
+	now := time.Unix(1111111109, 0)
+	step, ok := Validate(rfcSecret, "081804", now.Add(period*time.Second), 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+	_, ok = Validate(rfcSecret, "081804", now.Add(2*period*time.Second), 1)
+	assert.False(t, ok)
+
+	_, ok = Validate(rfcSecret, "000000", now, 1)
+	assert.False(t, ok)
+	_, ok = Validate(rfcSecret, "81804", now, 1)
+	assert.False(t, ok)
+}
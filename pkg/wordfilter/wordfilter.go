@@ -0,0 +1,135 @@
+// Package wordfilter 基于 Aho-Corasick 自动机实现敏感词匹配
+// 匹配时忽略大小写，并跳过夹在敏感词中间的空白和标点，如 "敏 感-词"
+package wordfilter
+
+import (
+	"unicode"
+)
+
+// Match 一次命中，Start 和 End 为原文中的字符（rune）下标，End 不含
+type Match struct {
+	Index int // 命中的敏感词在构造时传入列表中的下标
+	Start int
+	End   int
+}
+
+type node struct {
+	next map[rune]int
+	fail int
+	out  []int // 在该节点结束的敏感词下标，包含经失败指针可达的后缀词
+}
+
+// Filter 敏感词过滤器，构造后只读，可在多个 goroutine 间共享
+type Filter struct {
+	nodes []node
+	lens  []int // 各敏感词规范化后的字符数
+}
+
+// New 用敏感词列表构造过滤器，规范化后为空的词会被忽略
+func New(words []string) *Filter {
+	f := &Filter{
+		nodes: []node{{next: map[rune]int{}}},
+		lens:  make([]int, len(words)),
+	}
+
+	for i, word := range words {
+		cur := 0
+		for _, r := range word {
+			if skippable(r) {
+				continue
+			}
+			r = unicode.ToLower(r)
+			nxt, ok := f.nodes[cur].next[r]
+			if !ok {
+				nxt = len(f.nodes)
+				f.nodes = append(f.nodes, node{next: map[rune]int{}})
+				f.nodes[cur].next[r] = nxt
+			}
+			cur = nxt
+			f.lens[i]++
+		}
+		if cur != 0 {
+			f.nodes[cur].out = append(f.nodes[cur].out, i)
+		}
+	}
+
+	// 按层序构建失败指针
+	queue := make([]int, 0, len(f.nodes))
+	for _, child := range f.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range f.nodes[cur].next {
+			fail := f.nodes[cur].fail
+			for fail != 0 {
+				if _, ok := f.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = f.nodes[fail].fail
+			}
+			if nxt, ok := f.nodes[fail].next[r]; ok && nxt != child {
+				f.nodes[child].fail = nxt
+			}
+			f.nodes[child].out = append(f.nodes[child].out, f.nodes[f.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+	return f
+}
+
+// FindAll 返回文本中的全部命中，按结束位置排序
+func (f *Filter) FindAll(text string) []Match {
+	if f == nil || len(f.nodes) == 1 {
+		return nil
+	}
+
+	runes := []rune(text)
+	// positions 记录参与匹配的字符在原文中的下标，用于把命中映射回原文
+	positions := make([]int, 0, len(runes))
+	var matches []Match
+	cur := 0
+	for i, r := range runes {
+		if skippable(r) {
+			continue
+		}
+		positions = append(positions, i)
+		r = unicode.ToLower(r)
+		for cur != 0 {
+			if _, ok := f.nodes[cur].next[r]; ok {
+				break
+			}
+			cur = f.nodes[cur].fail
+		}
+		if nxt, ok := f.nodes[cur].next[r]; ok {
+			cur = nxt
+		}
+		for _, idx := range f.nodes[cur].out {
+			matches = append(matches, Match{
+				Index: idx,
+				Start: positions[len(positions)-f.lens[idx]],
+				End:   i + 1,
+			})
+		}
+	}
+	return matches
+}
+
+// Mask 把命中的字符替换为 mask，跳过的空白和标点一并替换
+func Mask(text string, matches []Match, mask rune) string {
+	if len(matches) == 0 {
+		return text
+	}
+	runes := []rune(text)
+	for _, m := range matches {
+		for i := m.Start; i < m.End && i < len(runes); i++ {
+			runes[i] = mask
+		}
+	}
+	return string(runes)
+}
+
+func skippable(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}